@@ -0,0 +1,59 @@
+// Command backfill-guards writes the uniqueness guard items (EMAIL#..., USERNAME#...) for users
+// and clients created before guards existed. Run it once before deploying a server that relies
+// on guards; it is safe to run again. Duplicate emails or usernames already in the tables are
+// listed and the command exits with status 1, so they can be resolved by hand.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/jmason/john_ai_project/internal/config"
+	"github.com/jmason/john_ai_project/internal/db"
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+func main() {
+	ctx := context.Background()
+
+	cfg, err := config.Read(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	dbClient, err := db.NewClient(ctx, cfg.AWS)
+	if err != nil {
+		log.Fatalf("Failed to create DB client: %v", err)
+	}
+	if err := dbClient.Ping(ctx); err != nil {
+		log.Fatalf("Failed to ping DynamoDB: %v", err)
+	}
+	log.Printf("Connected to DynamoDB at %s (region: %s)", dbClient.Endpoint, dbClient.Region)
+
+	tables := []struct {
+		name     string
+		backfill func(context.Context) (*repository.GuardBackfill, error)
+	}{
+		{cfg.Tables.Users, repository.NewUserRepository(dbClient.DynamoDB, cfg.Tables.Users).BackfillGuards},
+		{cfg.Tables.Clients, repository.NewClientRepository(dbClient.DynamoDB, cfg.Tables.Clients).BackfillGuards},
+	}
+
+	conflicts := 0
+	for _, table := range tables {
+		result, err := table.backfill(ctx)
+		if err != nil {
+			log.Fatalf("Failed to backfill guards in %s: %v", table.name, err)
+		}
+		log.Printf("✓ %s: %d records, %d guards created", table.name, result.Records, result.Created)
+		for _, c := range result.Conflicts {
+			log.Printf("  ✗ %s: %s is already held by %s", c.OwnerID, c.GuardID, c.HeldBy)
+		}
+		conflicts += len(result.Conflicts)
+	}
+
+	if conflicts > 0 {
+		log.Fatalf("Found %d duplicate values; resolve them and run backfill-guards again", conflicts)
+	}
+	log.Println("✓ Every email and username is guarded")
+}
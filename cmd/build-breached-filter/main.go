@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jmason/john_ai_project/internal/bloom"
+	"github.com/jmason/john_ai_project/internal/breached"
+)
+
+// Builds the breached-password Bloom filter embedded by internal/breached from a plain text list
+// (one password per line, # for comments). Larger lists, such as an export of the most common
+// breached passwords, can be passed with -in.
+func main() {
+	in := flag.String("in", "internal/breached/common-passwords.txt", "password list, one per line")
+	out := flag.String("out", "internal/breached/common-passwords.bloom", "filter output path")
+	fpRate := flag.Float64("fp", 0.001, "target false-positive rate")
+	flag.Parse()
+
+	file, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("Failed to open password list: %v", err)
+	}
+	defer file.Close()
+
+	var passwords []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		password := breached.Normalize(line)
+		if !seen[password] {
+			seen[password] = true
+			passwords = append(passwords, password)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Failed to read password list: %v", err)
+	}
+
+	filter := bloom.New(len(passwords), *fpRate)
+	for _, password := range passwords {
+		filter.Add(password)
+	}
+	data, err := filter.MarshalBinary()
+	if err != nil {
+		log.Fatalf("Failed to encode filter: %v", err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("Failed to write filter: %v", err)
+	}
+	log.Printf("✓ Wrote %s: %d passwords, %d bytes", *out, len(passwords), len(data))
+}
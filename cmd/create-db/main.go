@@ -12,14 +12,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	appconfig "github.com/jmason/john_ai_project/internal/config"
 )
 
 func main() {
-	// Get configuration from environment
-	endpoint := getEnv("DYNAMODB_ENDPOINT", "")
-	region := getEnv("AWS_REGION", "us-east-1")
-	accessKey := getEnv("AWS_ACCESS_KEY_ID", "")
-	secretKey := getEnv("AWS_SECRET_ACCESS_KEY", "")
+	// Get configuration from CONFIG_FILE and the environment
+	appCfg, err := appconfig.Read(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	endpoint := appCfg.AWS.DynamoDBEndpoint
+	region := appCfg.AWS.Region
+	accessKey := appCfg.AWS.AccessKeyID
+	secretKey := appCfg.AWS.SecretAccessKey
 
 	// Determine connection target
 	if endpoint != "" {
@@ -75,7 +80,7 @@ func main() {
 	log.Println("Connected to DynamoDB successfully")
 
 	// Create tables
-	if err := createTables(ctx, client); err != nil {
+	if err := createTables(ctx, client, appCfg.Tables); err != nil {
 		log.Fatalf("Failed to create tables: %v", err)
 	}
 
@@ -97,26 +102,55 @@ func waitForDynamoDB(ctx context.Context, client *dynamodb.Client) error {
 	return fmt.Errorf("DynamoDB did not become ready after %d attempts", maxRetries)
 }
 
-func createTables(ctx context.Context, client *dynamodb.Client) error {
+func createTables(ctx context.Context, client *dynamodb.Client, tables appconfig.Tables) error {
 	log.Println("Creating tables...")
 
 	// Create clients table
-	if err := createClientsTable(ctx, client); err != nil {
+	if err := createClientsTable(ctx, client, tables.Clients); err != nil {
 		return fmt.Errorf("failed to create clients table: %w", err)
 	}
 
 	// Create users table
-	if err := createUsersTable(ctx, client); err != nil {
+	if err := createUsersTable(ctx, client, tables.Users); err != nil {
 		return fmt.Errorf("failed to create users table: %w", err)
 	}
 
+	// Create invitations table
+	if err := createInvitationsTable(ctx, client, tables.Invitations); err != nil {
+		return fmt.Errorf("failed to create invitations table: %w", err)
+	}
+
+	// Create API keys table
+	if err := createAPIKeysTable(ctx, client, tables.APIKeys); err != nil {
+		return fmt.Errorf("failed to create api_keys table: %w", err)
+	}
+
+	// Create sessions table
+	if err := createSessionsTable(ctx, client, tables.Sessions); err != nil {
+		return fmt.Errorf("failed to create sessions table: %w", err)
+	}
+
+	// Create security events table
+	if err := createSecurityEventsTable(ctx, client, tables.SecurityEvents); err != nil {
+		return fmt.Errorf("failed to create security_events table: %w", err)
+	}
+
+	// Create idempotency keys table
+	if err := createIdempotencyKeysTable(ctx, client, tables.IdempotencyKeys); err != nil {
+		return fmt.Errorf("failed to create idempotency_keys table: %w", err)
+	}
+
+	// Create rate limits table
+	if err := createRateLimitsTable(ctx, client, tables.RateLimits); err != nil {
+		return fmt.Errorf("failed to create rate_limits table: %w", err)
+	}
+
 	return nil
 }
 
-func createClientsTable(ctx context.Context, client *dynamodb.Client) error {
+func createClientsTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
 	log.Println("Creating clients table...")
 
-	tableName := "clients"
 	input := &dynamodb.CreateTableInput{
 		TableName: aws.String(tableName),
 		AttributeDefinitions: []types.AttributeDefinition{
@@ -205,10 +239,9 @@ func createClientsTable(ctx context.Context, client *dynamodb.Client) error {
 	return nil
 }
 
-func createUsersTable(ctx context.Context, client *dynamodb.Client) error {
+func createUsersTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
 	log.Println("Creating users table...")
 
-	tableName := "users"
 	input := &dynamodb.CreateTableInput{
 		TableName: aws.String(tableName),
 		AttributeDefinitions: []types.AttributeDefinition{
@@ -309,11 +342,374 @@ func createUsersTable(ctx context.Context, client *dynamodb.Client) error {
 	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func createInvitationsTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	log.Println("Creating invitations table...")
+
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+	}
+
+	_, err := client.CreateTable(ctx, input)
+	if err != nil {
+		// Check if table already exists
+		_, describeErr := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if describeErr == nil {
+			log.Printf("  ✓ Invitations table already exists")
+			return nil
+		}
+		return err
+	}
+
+	log.Println("  ✓ Created invitations table")
+	return nil
+}
+
+func createAPIKeysTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	log.Println("Creating api_keys table...")
+
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+	}
+
+	_, err := client.CreateTable(ctx, input)
+	if err != nil {
+		// Check if table already exists
+		_, describeErr := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if describeErr == nil {
+			log.Printf("  ✓ API keys table already exists")
+			return nil
+		}
+		return err
+	}
+
+	log.Println("  ✓ Created api_keys table")
+	return nil
+}
+
+func createSessionsTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	log.Println("Creating sessions table...")
+
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("user_id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("user-index"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("user_id"),
+						KeyType:       types.KeyTypeHash,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType: types.ProjectionTypeAll,
+				},
+				ProvisionedThroughput: &types.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(5),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+			},
+		},
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+	}
+
+	_, err := client.CreateTable(ctx, input)
+	if err != nil {
+		// Check if table already exists
+		_, describeErr := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if describeErr == nil {
+			log.Printf("  ✓ Sessions table already exists")
+			return nil
+		}
+		return err
 	}
-	return defaultValue
+
+	// Expired sessions are removed by DynamoDB TTL
+	_, err = client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String("ttl"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		log.Printf("  ! Could not enable TTL on sessions table: %v", err)
+	}
+
+	log.Println("  ✓ Created sessions table")
+	return nil
+}
+
+func createSecurityEventsTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	log.Println("Creating security_events table...")
+
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("user_id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("type"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("created_at"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("user-index"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("user_id"),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String("created_at"),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType: types.ProjectionTypeAll,
+				},
+				ProvisionedThroughput: &types.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(5),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+			},
+			{
+				IndexName: aws.String("type-index"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("type"),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String("created_at"),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType: types.ProjectionTypeAll,
+				},
+				ProvisionedThroughput: &types.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(5),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+			},
+		},
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+	}
+
+	_, err := client.CreateTable(ctx, input)
+	if err != nil {
+		// Check if table already exists
+		_, describeErr := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if describeErr == nil {
+			log.Printf("  ✓ Security events table already exists")
+			return nil
+		}
+		return err
+	}
+
+	// Events past retention are removed by DynamoDB TTL
+	_, err = client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String("ttl"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		log.Printf("  ! Could not enable TTL on security_events table: %v", err)
+	}
+
+	log.Println("  ✓ Created security_events table")
+	return nil
+}
+
+func createIdempotencyKeysTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	log.Println("Creating idempotency_keys table...")
+
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+	}
+
+	_, err := client.CreateTable(ctx, input)
+	if err != nil {
+		// Check if table already exists
+		_, describeErr := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if describeErr == nil {
+			log.Printf("  ✓ Idempotency keys table already exists")
+			return nil
+		}
+		return err
+	}
+
+	// Stored responses expire through DynamoDB TTL
+	_, err = client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String("ttl"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		log.Printf("  ! Could not enable TTL on idempotency_keys table: %v", err)
+	}
+
+	log.Println("  ✓ Created idempotency_keys table")
+	return nil
+}
+
+func createRateLimitsTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	log.Println("Creating rate_limits table...")
+
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+	}
+
+	_, err := client.CreateTable(ctx, input)
+	if err != nil {
+		// Check if table already exists
+		_, describeErr := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if describeErr == nil {
+			log.Printf("  ✓ Rate limits table already exists")
+			return nil
+		}
+		return err
+	}
+
+	// Idle buckets expire through DynamoDB TTL
+	_, err = client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String("ttl"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		log.Printf("  ! Could not enable TTL on rate_limits table: %v", err)
+	}
+
+	log.Println("  ✓ Created rate_limits table")
+	return nil
 }
 
 func maskString(s string) string {
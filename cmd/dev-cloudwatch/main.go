@@ -0,0 +1,38 @@
+// Command dev-cloudwatch runs a stand-in CloudWatch Logs API for trying log shipping locally.
+// Every batch the API server ships is printed to stdout.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/jmason/john_ai_project/internal/cloudwatchtest"
+)
+
+func main() {
+	port := getEnv("DEV_CLOUDWATCH_PORT", "9500")
+
+	server := cloudwatchtest.NewServer()
+	server.OnEvents = func(group, stream string, events []cloudwatchtest.Event) {
+		for _, e := range events {
+			fmt.Printf("%s/%s %s\n", group, stream, e.Message)
+		}
+	}
+
+	fmt.Printf("Stand-in CloudWatch Logs API running at http://localhost:%s\n", port)
+	fmt.Printf("Start the API server with:\n")
+	fmt.Printf("  CLOUDWATCH_LOGS_ENABLED=true CLOUDWATCH_LOGS_ENDPOINT=http://localhost:%s make run-server\n", port)
+
+	if err := http.ListenAndServe(":"+port, server); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,51 @@
+// Command dev-idp runs a stand-in OpenID Connect provider for trying SSO login locally. Every
+// login is signed in as the identity configured through DEV_IDP_* environment variables.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jmason/john_ai_project/internal/oidctest"
+)
+
+func main() {
+	port := getEnv("DEV_IDP_PORT", "9400")
+	clientID := getEnv("OIDC_CLIENT_ID", "john-ai-local")
+
+	provider, err := oidctest.NewProvider(clientID, oidctest.Identity{
+		Subject:       getEnv("DEV_IDP_SUBJECT", "dev-idp-user-1"),
+		Email:         getEnv("DEV_IDP_EMAIL", "counsellor@example.com"),
+		EmailVerified: true,
+		Username:      getEnv("DEV_IDP_USERNAME", "counsellor"),
+		GivenName:     getEnv("DEV_IDP_GIVEN_NAME", "Casey"),
+		FamilyName:    getEnv("DEV_IDP_FAMILY_NAME", "Counsellor"),
+		Groups:        strings.Split(getEnv("DEV_IDP_GROUPS", "counsellors"), ","),
+	})
+	if err != nil {
+		log.Fatalf("Failed to create provider: %v", err)
+	}
+	provider.Issuer = getEnv("DEV_IDP_ISSUER", "http://localhost:"+port)
+	provider.ClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+
+	fmt.Printf("Stand-in identity provider running at %s\n", provider.Issuer)
+	fmt.Printf("Signs in %s with groups %v\n", provider.Identity.Email, provider.Identity.Groups)
+	fmt.Printf("Start the API server with:\n")
+	fmt.Printf("  OIDC_ISSUER_URL=%s OIDC_CLIENT_ID=%s \\\n", provider.Issuer, clientID)
+	fmt.Printf("  OIDC_REDIRECT_URL=http://localhost:8081/api/auth/oidc/callback \\\n")
+	fmt.Printf("  OIDC_GROUP_ROLES=counsellors=counsellor,admins=admin make run-server\n")
+
+	if err := http.ListenAndServe(":"+port, provider); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/jmason/john_ai_project/internal/config"
 	"github.com/jmason/john_ai_project/internal/db"
 	"github.com/jmason/john_ai_project/internal/repository"
 	"github.com/jmason/john_ai_project/internal/service"
@@ -15,9 +16,14 @@ import (
 func main() {
 	ctx := context.Background()
 
+	cfg, err := config.Read(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Create DynamoDB connection
 	fmt.Println("Connecting to DynamoDB...")
-	dbClient, err := db.NewClient(ctx)
+	dbClient, err := db.NewClient(ctx, cfg.AWS)
 	if err != nil {
 		log.Fatalf("Failed to create DB client: %v", err)
 	}
@@ -29,7 +35,7 @@ func main() {
 	fmt.Printf("✓ Connected to DynamoDB at %s (region: %s)\n", dbClient.Endpoint, dbClient.Region)
 
 	// Create repository
-	clientRepo := repository.NewClientRepository(dbClient.DynamoDB)
+	clientRepo := repository.NewClientRepository(dbClient.DynamoDB, cfg.Tables.Clients)
 
 	// Create service
 	clientService := service.NewClientService(clientRepo)
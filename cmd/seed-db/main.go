@@ -12,14 +12,21 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	appconfig "github.com/jmason/john_ai_project/internal/config"
+	"github.com/jmason/john_ai_project/internal/repository"
 )
 
 func main() {
-	// Get configuration from environment
-	endpoint := getEnv("DYNAMODB_ENDPOINT", "")
-	region := getEnv("AWS_REGION", "us-east-1")
-	accessKey := getEnv("AWS_ACCESS_KEY_ID", "")
-	secretKey := getEnv("AWS_SECRET_ACCESS_KEY", "")
+	// Get configuration from CONFIG_FILE and the environment
+	appCfg, err := appconfig.Read(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	endpoint := appCfg.AWS.DynamoDBEndpoint
+	region := appCfg.AWS.Region
+	accessKey := appCfg.AWS.AccessKeyID
+	secretKey := appCfg.AWS.SecretAccessKey
 
 	// Determine connection target
 	if endpoint != "" {
@@ -70,19 +77,19 @@ func main() {
 	log.Println("Connected successfully")
 
 	// Seed clients
-	if err := seedClients(ctx, client); err != nil {
+	if err := seedClients(ctx, client, appCfg.Tables.Clients); err != nil {
 		log.Fatalf("Failed to seed clients: %v", err)
 	}
 
 	// Seed users
-	if err := seedUsers(ctx, client); err != nil {
+	if err := seedUsers(ctx, client, appCfg.Tables.Users); err != nil {
 		log.Fatalf("Failed to seed users: %v", err)
 	}
 
 	log.Println("Database seeding completed successfully!")
 }
 
-func seedClients(ctx context.Context, client *dynamodb.Client) error {
+func seedClients(ctx context.Context, client *dynamodb.Client, tableName string) error {
 	log.Println("Seeding clients table...")
 
 	clients := []map[string]interface{}{
@@ -165,12 +172,15 @@ func seedClients(ctx context.Context, client *dynamodb.Client) error {
 		}
 
 		_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
-			TableName: aws.String("clients"),
+			TableName: aws.String(tableName),
 			Item:      item,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to put client item: %w", err)
 		}
+		if err := putGuard(ctx, client, tableName, repository.EmailGuardID(clientData["email"].(string)), clientData["id"].(string)); err != nil {
+			return err
+		}
 
 		log.Printf("  ✓ Seeded client: %s %s (%s)", clientData["first_name"], clientData["last_name"], clientData["email"])
 	}
@@ -179,7 +189,7 @@ func seedClients(ctx context.Context, client *dynamodb.Client) error {
 	return nil
 }
 
-func seedUsers(ctx context.Context, client *dynamodb.Client) error {
+func seedUsers(ctx context.Context, client *dynamodb.Client, tableName string) error {
 	log.Println("Seeding users table...")
 
 	// Note: In production, passwords should be properly hashed using bcrypt or similar
@@ -242,12 +252,18 @@ func seedUsers(ctx context.Context, client *dynamodb.Client) error {
 		}
 
 		_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
-			TableName: aws.String("users"),
+			TableName: aws.String(tableName),
 			Item:      item,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to put user item: %w", err)
 		}
+		if err := putGuard(ctx, client, tableName, repository.EmailGuardID(userData["email"].(string)), userData["id"].(string)); err != nil {
+			return err
+		}
+		if err := putGuard(ctx, client, tableName, repository.UsernameGuardID(userData["username"].(string)), userData["id"].(string)); err != nil {
+			return err
+		}
 
 		log.Printf("  ✓ Seeded user: %s (%s) - %s", userData["username"], userData["email"], userData["role"])
 	}
@@ -256,11 +272,21 @@ func seedUsers(ctx context.Context, client *dynamodb.Client) error {
 	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// putGuard writes the uniqueness guard item the repositories keep for each email and username
+// (see internal/repository/uniqueness.go), so seeded values cannot be registered again. id comes
+// from repository.EmailGuardID or repository.UsernameGuardID.
+func putGuard(ctx context.Context, client *dynamodb.Client, table, id, ownerID string) error {
+	_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item: map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: id},
+			"owner_id": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put guard item %s: %w", id, err)
 	}
-	return defaultValue
+	return nil
 }
 
 func maskString(s string) string {
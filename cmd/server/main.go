@@ -2,16 +2,35 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"os"
 
+	"github.com/jmason/john_ai_project/internal/config"
 	"github.com/jmason/john_ai_project/internal/router"
 )
 
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file; environment variables override it")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets masked and exit")
+	flag.Parse()
+
+	// Fail fast on missing or unsafe settings before touching AWS
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if *printConfig {
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 
 	// Create router
-	r, err := router.NewRouter(ctx)
+	r, err := router.NewRouter(ctx, cfg)
 	if err != nil {
 		log.Fatalf("Failed to create router: %v", err)
 	}
@@ -21,4 +40,3 @@ func main() {
 		log.Fatalf("Server error: %v", err)
 	}
 }
-
@@ -56,3 +56,169 @@ resource "aws_dynamodb_table" "users" {
   }
 }
 
+
+# DynamoDB Table - Invitations
+resource "aws_dynamodb_table" "invitations" {
+  name           = "invitations"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "invitations"
+    Project     = var.stack_name
+    Environment = var.environment
+  }
+}
+
+# DynamoDB Table - API Keys
+resource "aws_dynamodb_table" "api_keys" {
+  name           = "api_keys"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "api_keys"
+    Project     = var.stack_name
+    Environment = var.environment
+  }
+}
+
+# DynamoDB Table - Sessions (one item per issued login token)
+resource "aws_dynamodb_table" "sessions" {
+  name           = "sessions"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  attribute {
+    name = "user_id"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name            = "user-index"
+    hash_key        = "user_id"
+    projection_type = "ALL"
+  }
+
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "sessions"
+    Project     = var.stack_name
+    Environment = var.environment
+  }
+}
+
+resource "aws_dynamodb_table" "security_events" {
+  name           = "security_events"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  attribute {
+    name = "user_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "type"
+    type = "S"
+  }
+
+  attribute {
+    name = "created_at"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name            = "user-index"
+    hash_key        = "user_id"
+    range_key       = "created_at"
+    projection_type = "ALL"
+  }
+
+  global_secondary_index {
+    name            = "type-index"
+    hash_key        = "type"
+    range_key       = "created_at"
+    projection_type = "ALL"
+  }
+
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "security_events"
+    Project     = var.stack_name
+    Environment = var.environment
+  }
+}
+
+# DynamoDB Table - Idempotency Keys (first response to each Idempotency-Key, per caller)
+resource "aws_dynamodb_table" "idempotency_keys" {
+  name           = "idempotency_keys"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "idempotency_keys"
+    Project     = var.stack_name
+    Environment = var.environment
+  }
+}
+
+resource "aws_dynamodb_table" "rate_limits" {
+  name           = "rate_limits"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "rate_limits"
+    Project     = var.stack_name
+    Environment = var.environment
+  }
+}
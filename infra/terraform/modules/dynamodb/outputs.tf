@@ -18,3 +18,32 @@ output "users_table_arn" {
   value       = aws_dynamodb_table.users.arn
 }
 
+output "invitations_table_name" {
+  description = "Name of the Invitations DynamoDB table"
+  value       = aws_dynamodb_table.invitations.name
+}
+
+output "api_keys_table_name" {
+  description = "Name of the API Keys DynamoDB table"
+  value       = aws_dynamodb_table.api_keys.name
+}
+
+output "sessions_table_name" {
+  description = "Name of the Sessions DynamoDB table"
+  value       = aws_dynamodb_table.sessions.name
+}
+
+output "security_events_table_name" {
+  description = "Name of the Security Events DynamoDB table"
+  value       = aws_dynamodb_table.security_events.name
+}
+
+output "idempotency_keys_table_name" {
+  description = "Name of the Idempotency Keys DynamoDB table"
+  value       = aws_dynamodb_table.idempotency_keys.name
+}
+
+output "rate_limits_table_name" {
+  description = "Name of the Rate Limits DynamoDB table"
+  value       = aws_dynamodb_table.rate_limits.name
+}
@@ -29,6 +29,8 @@ RestartSec=10
 Environment="AWS_REGION=${aws_region}"
 Environment="HTTP_PORT=8080"
 Environment="DYNAMODB_ENDPOINT="
+# API Gateway appends the caller's address to X-Forwarded-For
+Environment="RATE_LIMIT_TRUSTED_PROXY_HOPS=1"
 StandardOutput=journal
 StandardError=journal
 SyslogIdentifier=john-ai-backend
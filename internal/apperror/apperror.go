@@ -0,0 +1,103 @@
+// Package apperror defines the typed errors the service and repository layers return, so the
+// HTTP layer can map them to a status and a stable machine-readable code in one place instead
+// of matching error text.
+package apperror
+
+import "errors"
+
+// Kind classifies an error by what the caller can do about it.
+type Kind int
+
+const (
+	// KindInternal is anything unexpected; its details are not shown to API clients.
+	KindInternal Kind = iota
+	// KindValidation means the request was malformed or failed validation.
+	KindValidation
+	// KindUnauthorized means the caller is not authenticated.
+	KindUnauthorized
+	// KindForbidden means the caller is authenticated but not allowed.
+	KindForbidden
+	// KindNotFound means the resource does not exist.
+	KindNotFound
+	// KindConflict means the request clashes with the current state, e.g. a duplicate email.
+	KindConflict
+	// KindUnprocessable means the request is well-formed but cannot be applied, e.g. an
+	// idempotency key reused for a different request.
+	KindUnprocessable
+	// KindNotImplemented means the feature is switched off in this deployment.
+	KindNotImplemented
+	// KindUpstream means a service this one depends on failed, e.g. the identity provider.
+	KindUpstream
+)
+
+// Error is a domain error with a stable Code (e.g. "client_not_found") that API clients can
+// branch on. Fields carries per-field messages for validation errors.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  map[string]string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is an *Error with the same code, so a copy carrying field details
+// still matches its sentinel with errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithFields returns a copy of e carrying per-field messages.
+func (e *Error) WithFields(fields map[string]string) *Error {
+	out := *e
+	out.Fields = fields
+	return &out
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func Unprocessable(code, message string) *Error {
+	return New(KindUnprocessable, code, message)
+}
+
+func NotImplemented(code, message string) *Error {
+	return New(KindNotImplemented, code, message)
+}
+
+func Upstream(code, message string) *Error {
+	return New(KindUpstream, code, message)
+}
+
+// As returns the first *Error in err's chain.
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
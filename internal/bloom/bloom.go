@@ -0,0 +1,104 @@
+// Package bloom implements a compact, serialisable Bloom filter for set membership checks
+// where false positives are acceptable and false negatives are not.
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+)
+
+// ErrInvalidFilter is returned when decoding malformed filter data.
+var ErrInvalidFilter = errors.New("invalid bloom filter data")
+
+// magic prefixes the binary encoding so unrelated files are rejected.
+const magic = "BLM1"
+
+// Filter is a Bloom filter of m bits probed with k hash functions.
+type Filter struct {
+	m    uint64
+	k    uint32
+	bits []uint64
+}
+
+// New sizes a filter for n items at the given false-positive rate.
+func New(n int, falsePositiveRate float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{m: m, k: k, bits: make([]uint64, (m+63)/64)}
+}
+
+// Add inserts s into the filter.
+func (f *Filter) Add(s string) {
+	h1, h2 := hashes(s)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether s may have been added. False positives are possible; false
+// negatives are not.
+func (f *Filter) Contains(s string) bool {
+	h1, h2 := hashes(s)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes derives the two base hashes for double hashing from a 128-bit FNV-1a digest.
+func hashes(s string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(s))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1 // odd, so probes never repeat early
+	return h1, h2
+}
+
+// MarshalBinary encodes the filter as magic, m, k and the bit array, all big-endian.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	out := make([]byte, 0, len(magic)+12+8*len(f.bits))
+	out = append(out, magic...)
+	out = binary.BigEndian.AppendUint64(out, f.m)
+	out = binary.BigEndian.AppendUint32(out, f.k)
+	for _, word := range f.bits {
+		out = binary.BigEndian.AppendUint64(out, word)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary decodes data written by MarshalBinary.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	header := len(magic) + 12
+	if len(data) < header || string(data[:len(magic)]) != magic {
+		return ErrInvalidFilter
+	}
+	m := binary.BigEndian.Uint64(data[len(magic):])
+	k := binary.BigEndian.Uint32(data[len(magic)+8:])
+	words := (m + 63) / 64
+	if m == 0 || k == 0 || uint64(len(data)-header) != words*8 {
+		return ErrInvalidFilter
+	}
+
+	bits := make([]uint64, words)
+	for i := range bits {
+		bits[i] = binary.BigEndian.Uint64(data[header+8*i:])
+	}
+	f.m, f.k, f.bits = m, k, bits
+	return nil
+}
@@ -0,0 +1,37 @@
+// Package breached bundles a Bloom filter of common and breached passwords so password checks
+// work offline. The filter is built from common-passwords.txt by cmd/build-breached-filter.
+package breached
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+
+	"github.com/jmason/john_ai_project/internal/bloom"
+)
+
+//go:embed common-passwords.bloom
+var filterData []byte
+
+var (
+	loadOnce sync.Once
+	filter   *bloom.Filter
+	loadErr  error
+)
+
+// Filter returns the bundled filter, decoding it on first use.
+func Filter() (*bloom.Filter, error) {
+	loadOnce.Do(func() {
+		f := &bloom.Filter{}
+		if loadErr = f.UnmarshalBinary(filterData); loadErr == nil {
+			filter = f
+		}
+	})
+	return filter, loadErr
+}
+
+// Normalize is applied to passwords both when building the filter and when checking, so
+// case variants of a listed password also match.
+func Normalize(password string) string {
+	return strings.ToLower(password)
+}
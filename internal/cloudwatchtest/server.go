@@ -0,0 +1,198 @@
+// Package cloudwatchtest is an in-memory stand-in for the CloudWatch Logs API, used by tests and
+// by cmd/dev-cloudwatch to exercise log shipping without AWS. It implements CreateLogGroup,
+// CreateLogStream and PutLogEvents with sequence tokens, and can be told to fail requests.
+package cloudwatchtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Event is a log event accepted by the server.
+type Event struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// Failure is an error response returned instead of handling a request.
+type Failure struct {
+	Status int
+	Type   string
+}
+
+// Server implements the CloudWatch Logs JSON API. The zero value is not usable; call NewServer.
+type Server struct {
+	// OnEvents, when set, is called with each accepted batch.
+	OnEvents func(group, stream string, events []Event)
+
+	mu       sync.Mutex
+	groups   map[string]map[string]*logStream
+	calls    map[string]int
+	failures map[string][]Failure
+}
+
+type logStream struct {
+	events   []Event
+	sequence int
+}
+
+// NewServer returns a server with no log groups.
+func NewServer() *Server {
+	return &Server{groups: map[string]map[string]*logStream{}, calls: map[string]int{}, failures: map[string][]Failure{}}
+}
+
+// CreateLogGroup creates a log group, as if it had been provisioned ahead of time.
+func (s *Server) CreateLogGroup(group string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.groups[group] == nil {
+		s.groups[group] = map[string]*logStream{}
+	}
+}
+
+// FailNext makes the next requests for operation fail, one per Failure, before any are handled.
+func (s *Server) FailNext(operation string, failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[operation] = append(s.failures[operation], failures...)
+}
+
+// Calls returns how many requests for operation have been received, including failed ones.
+func (s *Server) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[operation]
+}
+
+// Events returns the events accepted for a stream, in the order they were put.
+func (s *Server) Events(group, stream string) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ls := s.groups[group][stream]
+	if ls == nil {
+		return nil
+	}
+	return append([]Event(nil), ls.events...)
+}
+
+// ExpireSequenceToken advances a stream's sequence token, as another writer to the stream would.
+func (s *Server) ExpireSequenceToken(group, stream string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ls := s.groups[group][stream]; ls != nil {
+		ls.sequence++
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "UnknownOperationException", "only POST is supported")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		writeError(w, http.StatusBadRequest, "MissingAuthenticationTokenException", "request is not signed")
+		return
+	}
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+
+	var input struct {
+		LogGroupName  string  `json:"logGroupName"`
+		LogStreamName string  `json:"logStreamName"`
+		LogEvents     []Event `json:"logEvents"`
+		SequenceToken string  `json:"sequenceToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[operation]++
+	if failures := s.failures[operation]; len(failures) > 0 {
+		f := failures[0]
+		s.failures[operation] = failures[1:]
+		writeError(w, f.Status, f.Type, "injected failure")
+		return
+	}
+
+	switch operation {
+	case "CreateLogGroup":
+		if s.groups[input.LogGroupName] != nil {
+			writeError(w, http.StatusBadRequest, "ResourceAlreadyExistsException", "The specified log group already exists")
+			return
+		}
+		s.groups[input.LogGroupName] = map[string]*logStream{}
+		writeJSON(w, struct{}{})
+	case "CreateLogStream":
+		streams := s.groups[input.LogGroupName]
+		if streams == nil {
+			writeError(w, http.StatusBadRequest, "ResourceNotFoundException", "The specified log group does not exist.")
+			return
+		}
+		if streams[input.LogStreamName] != nil {
+			writeError(w, http.StatusBadRequest, "ResourceAlreadyExistsException", "The specified log stream already exists")
+			return
+		}
+		streams[input.LogStreamName] = &logStream{}
+		writeJSON(w, struct{}{})
+	case "PutLogEvents":
+		s.putLogEvents(w, input.LogGroupName, input.LogStreamName, input.SequenceToken, input.LogEvents)
+	default:
+		writeError(w, http.StatusBadRequest, "UnknownOperationException", "unsupported operation "+operation)
+	}
+}
+
+func (s *Server) putLogEvents(w http.ResponseWriter, group, stream, token string, events []Event) {
+	ls := s.groups[group][stream]
+	if ls == nil {
+		writeError(w, http.StatusBadRequest, "ResourceNotFoundException", "The specified log stream does not exist.")
+		return
+	}
+	expected := ""
+	if ls.sequence > 0 {
+		expected = strconv.Itoa(ls.sequence)
+	}
+	if token != expected {
+		writeSequenceError(w, "InvalidSequenceTokenException", "The given sequenceToken is invalid.", expected)
+		return
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Timestamp < events[i-1].Timestamp {
+			writeError(w, http.StatusBadRequest, "InvalidParameterException", "Log events in a single PutLogEvents request must be in chronological order.")
+			return
+		}
+	}
+
+	ls.events = append(ls.events, events...)
+	ls.sequence++
+	if s.OnEvents != nil {
+		s.OnEvents(group, stream, events)
+	}
+	writeJSON(w, map[string]string{"nextSequenceToken": strconv.Itoa(ls.sequence)})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"__type": errType, "message": message})
+}
+
+func writeSequenceError(w http.ResponseWriter, errType, message, expected string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(http.StatusBadRequest)
+	body := map[string]string{"__type": errType, "message": fmt.Sprintf("%s The next expected sequenceToken is: %s", message, expected)}
+	if expected != "" {
+		body["expectedSequenceToken"] = expected
+	}
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,415 @@
+// Package config loads the server configuration into a typed struct: defaults, then an optional
+// YAML or JSON file, then environment variables, which always win. Each setting's file key and
+// environment variable are given by its yaml and env tags; settings tagged secret are masked
+// whenever the configuration is printed or logged.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environments accepted in Config.Env.
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Config is everything the API server reads at startup.
+type Config struct {
+	// Env is development or production. Development relaxes the checks in Validate and allows
+	// local conveniences such as turning off log redaction.
+	Env string `yaml:"env" env:"APP_ENV"`
+
+	HTTP        HTTP        `yaml:"http"`
+	AWS         AWS         `yaml:"aws"`
+	Tables      Tables      `yaml:"tables"`
+	JWT         JWT         `yaml:"jwt"`
+	Auth        Auth        `yaml:"auth"`
+	Invitations Invitations `yaml:"invitations"`
+	Passwords   Passwords   `yaml:"passwords"`
+	OIDC        OIDC        `yaml:"oidc"`
+	CORS        CORS        `yaml:"cors"`
+	Log         Log         `yaml:"log"`
+	CloudWatch  CloudWatch  `yaml:"cloudwatch"`
+	Tracing     Tracing     `yaml:"tracing"`
+	Metrics     Metrics     `yaml:"metrics"`
+	Readiness   Readiness   `yaml:"readiness"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	RateLimit   RateLimit   `yaml:"rate_limit"`
+}
+
+// HTTP configures the listener.
+type HTTP struct {
+	Port            string        `yaml:"port" env:"HTTP_PORT"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+}
+
+// AWS configures the AWS SDK. Without static keys the SDK's default chain (e.g. the EC2
+// instance role) is used.
+type AWS struct {
+	Region string `yaml:"region" env:"AWS_REGION"`
+	// DynamoDBEndpoint points the DynamoDB client at DynamoDB Local.
+	DynamoDBEndpoint string `yaml:"dynamodb_endpoint" env:"DYNAMODB_ENDPOINT"`
+	AccessKeyID      string `yaml:"access_key_id" env:"AWS_ACCESS_KEY_ID" secret:"true"`
+	SecretAccessKey  string `yaml:"secret_access_key" env:"AWS_SECRET_ACCESS_KEY" secret:"true"`
+}
+
+// Tables names the DynamoDB tables.
+type Tables struct {
+	Clients         string `yaml:"clients" env:"TABLE_CLIENTS"`
+	Users           string `yaml:"users" env:"TABLE_USERS"`
+	Invitations     string `yaml:"invitations" env:"TABLE_INVITATIONS"`
+	APIKeys         string `yaml:"api_keys" env:"TABLE_API_KEYS"`
+	Sessions        string `yaml:"sessions" env:"TABLE_SESSIONS"`
+	SecurityEvents  string `yaml:"security_events" env:"TABLE_SECURITY_EVENTS"`
+	IdempotencyKeys string `yaml:"idempotency_keys" env:"TABLE_IDEMPOTENCY_KEYS"`
+	RateLimits      string `yaml:"rate_limits" env:"TABLE_RATE_LIMITS"`
+}
+
+// JWT configures token signing. With KeysDir set, tokens are signed with the RS256/ES256 key
+// ActiveKID and Secret only verifies tokens issued before the switch.
+type JWT struct {
+	Secret    string `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	KeysDir   string `yaml:"keys_dir" env:"JWT_KEYS_DIR"`
+	ActiveKID string `yaml:"active_kid" env:"JWT_ACTIVE_KID"`
+}
+
+// Auth configures registration and account features.
+type Auth struct {
+	OpenRegistration         bool   `yaml:"open_registration" env:"OPEN_REGISTRATION"`
+	EmailVerifyBaseURL       string `yaml:"email_verify_base_url" env:"EMAIL_VERIFY_BASE_URL"`
+	ImpersonationAllowWrites bool   `yaml:"impersonation_allow_writes" env:"IMPERSONATION_ALLOW_WRITES"`
+}
+
+// Invitations configures invitation links.
+type Invitations struct {
+	TTL time.Duration `yaml:"ttl" env:"INVITE_TTL"`
+	// Secret signs invitation tokens; empty means JWT.Secret.
+	Secret  string `yaml:"secret" env:"INVITE_SECRET" secret:"true"`
+	BaseURL string `yaml:"base_url" env:"INVITE_BASE_URL"`
+}
+
+// Passwords configures hashing and the password policy. A zero MinLength or MinEntropyBits
+// disables that rule.
+type Passwords struct {
+	HashAlgorithm      string  `yaml:"hash_algorithm" env:"PASSWORD_HASH_ALGORITHM"`
+	BcryptCost         int     `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+	Argon2MemoryKiB    uint32  `yaml:"argon2_memory_kib" env:"ARGON2_MEMORY_KIB"`
+	Argon2Iterations   uint32  `yaml:"argon2_iterations" env:"ARGON2_ITERATIONS"`
+	Argon2Parallelism  uint8   `yaml:"argon2_parallelism" env:"ARGON2_PARALLELISM"`
+	MinLength          int     `yaml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	MinEntropyBits     float64 `yaml:"min_entropy_bits" env:"PASSWORD_MIN_ENTROPY_BITS"`
+	RejectPersonalInfo bool    `yaml:"reject_personal_info" env:"PASSWORD_REJECT_PERSONAL_INFO"`
+	CheckBreached      bool    `yaml:"check_breached" env:"PASSWORD_CHECK_BREACHED"`
+}
+
+// OIDC configures SSO, which is enabled when IssuerURL is set.
+type OIDC struct {
+	IssuerURL    string `yaml:"issuer_url" env:"OIDC_ISSUER_URL"`
+	ClientID     string `yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string `yaml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	// FrontendURL is the page the callback redirects to with #token=... or #error=<code>.
+	// Empty returns the token as JSON, which suits API clients and local testing.
+	FrontendURL string `yaml:"frontend_url" env:"OIDC_FRONTEND_URL"`
+	// Scopes replaces the default scopes when set.
+	Scopes      []string `yaml:"scopes" env:"OIDC_SCOPES"`
+	GroupsClaim string   `yaml:"groups_claim" env:"OIDC_GROUPS_CLAIM"`
+	// GroupRoles maps provider groups to roles, e.g. "staff-admins=admin,counsellors=counsellor".
+	GroupRoles  string `yaml:"group_roles" env:"OIDC_GROUP_ROLES"`
+	DefaultRole string `yaml:"default_role" env:"OIDC_DEFAULT_ROLE"`
+	// StateSecret signs the login state cookie; empty means JWT.Secret.
+	StateSecret string `yaml:"state_secret" env:"OIDC_STATE_SECRET" secret:"true"`
+}
+
+// CORS configures cross-origin access for browser clients. No allowed origins disables CORS.
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// Log configures logging.
+type Log struct {
+	// Level is a default level plus optional per-component levels, e.g. "info,auth=debug".
+	Level        string   `yaml:"level" env:"LOG_LEVEL"`
+	RedactFields []string `yaml:"redact_fields" env:"LOG_REDACT_FIELDS"`
+	// Redaction can only be turned off in development.
+	Redaction bool `yaml:"redaction" env:"LOG_REDACTION"`
+}
+
+// CloudWatch configures shipping logs to CloudWatch Logs.
+type CloudWatch struct {
+	Enabled       bool          `yaml:"enabled" env:"CLOUDWATCH_LOGS_ENABLED"`
+	Endpoint      string        `yaml:"endpoint" env:"CLOUDWATCH_LOGS_ENDPOINT"`
+	LogGroup      string        `yaml:"log_group" env:"CLOUDWATCH_LOG_GROUP"`
+	LogStream     string        `yaml:"log_stream" env:"CLOUDWATCH_LOG_STREAM"`
+	BufferSize    int           `yaml:"buffer_size" env:"CLOUDWATCH_LOGS_BUFFER_SIZE"`
+	BatchSize     int           `yaml:"batch_size" env:"CLOUDWATCH_LOGS_BATCH_SIZE"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"CLOUDWATCH_LOGS_FLUSH_INTERVAL"`
+}
+
+// Tracing configures OpenTelemetry. The OTLP exporter reads its endpoint and headers from the
+// standard OTEL_EXPORTER_OTLP_* variables.
+type Tracing struct {
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+// Metrics configures GET /metrics.
+type Metrics struct {
+	Token string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
+	// RefreshInterval is how often the client gauges are recomputed; 0 disables them.
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"METRICS_REFRESH_INTERVAL"`
+}
+
+// Readiness configures GET /readyz.
+type Readiness struct {
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"READINESS_CACHE_TTL"`
+}
+
+// Idempotency configures Idempotency-Key handling.
+type Idempotency struct {
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL"`
+}
+
+// RateLimit configures per-caller token buckets for each route group. A limit is
+// "<requests>/<period>", e.g. "120/1m": a caller may burst up to requests and is refilled at
+// requests per period. An empty limit leaves the group unlimited.
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	// Store is memory, for a single server, or dynamodb to share buckets across a fleet.
+	Store string `yaml:"store" env:"RATE_LIMIT_STORE"`
+	// TrustedProxyHops is how many proxies in front of the server append the caller's address
+	// to X-Forwarded-For. The default, 0, uses the connection's address; API Gateway is 1 hop.
+	TrustedProxyHops int `yaml:"trusted_proxy_hops" env:"RATE_LIMIT_TRUSTED_PROXY_HOPS"`
+	// Public limits unauthenticated routes such as login and registration, by client IP.
+	Public  string `yaml:"public" env:"RATE_LIMIT_PUBLIC"`
+	Account string `yaml:"account" env:"RATE_LIMIT_ACCOUNT"`
+	Clients string `yaml:"clients" env:"RATE_LIMIT_CLIENTS"`
+	Admin   string `yaml:"admin" env:"RATE_LIMIT_ADMIN"`
+}
+
+// Default returns the configuration used for anything not set in the file or environment. The
+// password defaults match service.DefaultArgon2idParams and service.DefaultPasswordPolicy.
+func Default() Config {
+	return Config{
+		Env: EnvProduction,
+		HTTP: HTTP{
+			Port:            "8081",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		AWS: AWS{Region: "us-east-1"},
+		Tables: Tables{
+			Clients:         "clients",
+			Users:           "users",
+			Invitations:     "invitations",
+			APIKeys:         "api_keys",
+			Sessions:        "sessions",
+			SecurityEvents:  "security_events",
+			IdempotencyKeys: "idempotency_keys",
+			RateLimits:      "rate_limits",
+		},
+		Auth:        Auth{OpenRegistration: true},
+		Invitations: Invitations{TTL: 72 * time.Hour},
+		Passwords: Passwords{
+			HashAlgorithm:      "argon2id",
+			BcryptCost:         10,
+			Argon2MemoryKiB:    19 * 1024,
+			Argon2Iterations:   2,
+			Argon2Parallelism:  1,
+			MinLength:          8,
+			MinEntropyBits:     40,
+			RejectPersonalInfo: true,
+			CheckBreached:      true,
+		},
+		OIDC: OIDC{GroupsClaim: "groups"},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Idempotency-Key", "X-API-Key", "X-Request-ID"},
+			ExposedHeaders: []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
+			MaxAge:         10 * time.Minute,
+		},
+		Log: Log{Level: "info", Redaction: true},
+		CloudWatch: CloudWatch{
+			LogGroup:      "/aws/ec2/john-ai-backend",
+			LogStream:     "api-server",
+			BufferSize:    10000,
+			BatchSize:     500,
+			FlushInterval: 5 * time.Second,
+		},
+		Tracing:     Tracing{Exporter: "none", ServiceName: "john-ai-backend"},
+		Metrics:     Metrics{RefreshInterval: time.Minute},
+		Readiness:   Readiness{CacheTTL: 5 * time.Second},
+		Idempotency: Idempotency{TTL: 24 * time.Hour},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Store:   "memory",
+			Public:  "20/1m",
+			Account: "120/1m",
+			Clients: "120/1m",
+			Admin:   "60/1m",
+		},
+	}
+}
+
+// Load reads the configuration like Read and validates it, so the server fails fast on a bad
+// or unsafe setting.
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read builds the configuration from Default, the file at path if path is not empty, and the
+// environment, without validating it. Tools that only talk to DynamoDB use it directly.
+func Read(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		// YAML is a superset of JSON, so one decoder reads both.
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return nil, err
+	}
+	cfg.applyDerivedDefaults()
+	return &cfg, nil
+}
+
+// Development reports whether Env is development.
+func (c *Config) Development() bool {
+	return c.Env == EnvDevelopment
+}
+
+// devJWTSecret signs tokens in development when no JWT secret is configured.
+const devJWTSecret = "dev-secret-key-change-in-production"
+
+// applyDerivedDefaults fills settings whose default depends on other settings.
+func (c *Config) applyDerivedDefaults() {
+	if c.JWT.Secret == "" && c.Development() {
+		c.JWT.Secret = devJWTSecret
+	}
+	if c.Invitations.Secret == "" {
+		c.Invitations.Secret = c.JWT.Secret
+	}
+	if c.OIDC.StateSecret == "" {
+		c.OIDC.StateSecret = c.JWT.Secret
+	}
+}
+
+// applyEnv overrides the fields of v that have an env tag with the variables that are set and
+// not empty.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value); err != nil {
+				return err
+			}
+			continue
+		}
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		if err := setFromString(value, raw); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setFromString(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := parseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.ParseInt(raw, 10, 0)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint8, reflect.Uint32:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		// Lists are comma or space separated, e.g. "openid,email" or "openid email".
+		v.Set(reflect.ValueOf(strings.Fields(strings.ReplaceAll(raw, ",", " "))))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// parseBool accepts on/off and yes/no besides the strconv forms.
+func parseBool(raw string) (bool, error) {
+	switch strings.ToLower(raw) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
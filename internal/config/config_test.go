@@ -0,0 +1,216 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef-test"
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Development(t *testing.T) {
+	t.Setenv("APP_ENV", EnvDevelopment)
+	t.Setenv("JWT_SECRET", "")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Expected the defaults to be valid in development, got %v", err)
+	}
+	if cfg.JWT.Secret != devJWTSecret {
+		t.Errorf("Expected the development JWT secret, got %q", cfg.JWT.Secret)
+	}
+	if cfg.Invitations.Secret != cfg.JWT.Secret || cfg.OIDC.StateSecret != cfg.JWT.Secret {
+		t.Error("Expected the invitation and OIDC state secrets to default to the JWT secret")
+	}
+}
+
+func TestLoad_ProductionSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr string
+	}{
+		{name: "Missing", secret: "", wantErr: "JWT_SECRET is required"},
+		{name: "Placeholder", secret: "your-secret-key-CHANGE-IN-PRODUCTION-via-env-var", wantErr: "JWT_SECRET is a placeholder"},
+		{name: "Too short", secret: "hunter2", wantErr: "at least 32 characters"},
+		{name: "Strong", secret: testSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", "")
+			t.Setenv("JWT_SECRET", tt.secret)
+
+			_, err := Load("")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_FileAndEnv(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+env: development
+http:
+  port: "9000"
+  read_timeout: 30s
+tables:
+  clients: staging-clients
+cors:
+  allowed_origins: [https://app.example.com]
+`,
+		"config.json": `{
+  "env": "development",
+  "http": {"port": "9000", "read_timeout": "30s"},
+  "tables": {"clients": "staging-clients"},
+  "cors": {"allowed_origins": ["https://app.example.com"]}
+}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("APP_ENV", "")
+			t.Setenv("JWT_SECRET", "")
+			t.Setenv("HTTP_PORT", "9100")
+			t.Setenv("LOG_REDACT_FIELDS", "ssn, notes")
+
+			cfg, err := Load(writeFile(t, name, content))
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Env != EnvDevelopment {
+				t.Errorf("Expected env from the file, got %q", cfg.Env)
+			}
+			if cfg.HTTP.Port != "9100" {
+				t.Errorf("Expected HTTP_PORT to override the file, got %q", cfg.HTTP.Port)
+			}
+			if cfg.HTTP.ReadTimeout != 30*time.Second {
+				t.Errorf("Expected read timeout 30s, got %v", cfg.HTTP.ReadTimeout)
+			}
+			if cfg.HTTP.WriteTimeout != 15*time.Second {
+				t.Errorf("Expected the default write timeout, got %v", cfg.HTTP.WriteTimeout)
+			}
+			if cfg.Tables.Clients != "staging-clients" || cfg.Tables.Users != "users" {
+				t.Errorf("Unexpected tables %+v", cfg.Tables)
+			}
+			if got := cfg.CORS.AllowedOrigins; len(got) != 1 || got[0] != "https://app.example.com" {
+				t.Errorf("Unexpected allowed origins %v", got)
+			}
+			if got := cfg.Log.RedactFields; len(got) != 2 || got[0] != "ssn" || got[1] != "notes" {
+				t.Errorf("Unexpected redact fields %v", got)
+			}
+		})
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	t.Run("Unknown file key", func(t *testing.T) {
+		_, err := Read(writeFile(t, "config.yaml", "htp:\n  port: \"9000\"\n"))
+		if err == nil || !strings.Contains(err.Error(), "htp") {
+			t.Errorf("Expected an error naming the unknown key, got %v", err)
+		}
+	})
+	t.Run("Invalid env value", func(t *testing.T) {
+		t.Setenv("INVITE_TTL", "3 days")
+		_, err := Read("")
+		if err == nil || !strings.Contains(err.Error(), "INVITE_TTL") {
+			t.Errorf("Expected an error naming INVITE_TTL, got %v", err)
+		}
+	})
+	t.Run("Empty file", func(t *testing.T) {
+		if _, err := Read(writeFile(t, "config.yaml", "")); err != nil {
+			t.Errorf("Expected an empty file to be ignored, got %v", err)
+		}
+	})
+}
+
+func TestValidate_CollectsErrors(t *testing.T) {
+	cfg := Default()
+	cfg.JWT.Secret = testSecret
+	cfg.HTTP.Port = "http"
+	cfg.Tables.Sessions = ""
+	cfg.CORS.AllowedOrigins = []string{"*"}
+	cfg.CORS.AllowCredentials = true
+	cfg.OIDC.FrontendURL = "https://app.example.com/sso#done"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+	for _, want := range []string{"HTTP_PORT", "TABLE_SESSIONS", "CORS_ALLOW_CREDENTIALS", "OIDC_FRONTEND_URL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestValidateOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		valid  bool
+	}{
+		{origin: "*", valid: true},
+		{origin: "https://app.example.com", valid: true},
+		{origin: "http://localhost:3000", valid: true},
+		{origin: "https://*.example.com", valid: true},
+		{origin: "app.example.com", valid: false},
+		{origin: "https://app.*.example.com", valid: false},
+		{origin: "ftp://example.com", valid: false},
+		{origin: "https://example.com/app", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if err := validateOrigin(tt.origin); (err == nil) != tt.valid {
+				t.Errorf("validateOrigin(%q) = %v, want valid %v", tt.origin, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestConfig_MasksSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.JWT.Secret = testSecret
+	cfg.Metrics.Token = "metrics-token"
+	cfg.applyDerivedDefaults()
+
+	var yamlOut bytes.Buffer
+	if err := cfg.WriteYAML(&yamlOut); err != nil {
+		t.Fatalf("WriteYAML failed: %v", err)
+	}
+	var logOut bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logOut, nil)).Info("effective configuration", "config", cfg)
+
+	for name, out := range map[string]string{"yaml": yamlOut.String(), "log": logOut.String()} {
+		if strings.Contains(out, testSecret) || strings.Contains(out, "metrics-token") {
+			t.Errorf("Expected secrets to be masked in the %s output:\n%s", name, out)
+		}
+		if !strings.Contains(out, masked) || !strings.Contains(out, "15s") {
+			t.Errorf("Expected masked secrets and readable durations in the %s output:\n%s", name, out)
+		}
+	}
+	if cfg.JWT.Secret != testSecret {
+		t.Error("Expected masking not to change the configuration")
+	}
+}
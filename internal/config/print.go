@@ -0,0 +1,67 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// masked replaces the value of every non-empty secret setting.
+const masked = "[REDACTED]"
+
+// Masked returns a copy of c with its secrets replaced, for printing.
+func (c Config) Masked() Config {
+	maskSecrets(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func maskSecrets(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			maskSecrets(value)
+		case field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "":
+			value.SetString(masked)
+		}
+	}
+}
+
+// WriteYAML writes the configuration with secrets masked, in the format Load reads.
+func (c Config) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Masked()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// LogValue logs the configuration as nested groups keyed like the config file, with secrets
+// masked.
+func (c Config) LogValue() slog.Value {
+	return structValue(reflect.ValueOf(c.Masked()))
+}
+
+func structValue(v reflect.Value) slog.Value {
+	t := v.Type()
+	attrs := make([]slog.Attr, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch {
+		case value.Type() == durationType:
+			attrs = append(attrs, slog.String(key, time.Duration(value.Int()).String()))
+		case field.Type.Kind() == reflect.Struct:
+			attrs = append(attrs, slog.Attr{Key: key, Value: structValue(value)})
+		default:
+			attrs = append(attrs, slog.Any(key, value.Interface()))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/logger"
+)
+
+// minProductionSecretLength is the shortest JWT secret accepted in production: 256 bits for
+// HS256.
+const minProductionSecretLength = 32
+
+// placeholderSecrets are JWT secrets from old defaults and sample configuration that must never
+// sign production tokens.
+var placeholderSecrets = []string{
+	devJWTSecret,
+	"your-secret-key-CHANGE-IN-PRODUCTION-via-env-var",
+	"local-dev-secret-key-change-in-production",
+}
+
+// Validate reports every invalid setting at once, naming each by its environment variable.
+// Outside development it also rejects settings that are only safe locally, such as a missing or
+// placeholder JWT secret.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		fail("APP_ENV must be %s or %s, got %q", EnvDevelopment, EnvProduction, c.Env)
+	}
+	production := !c.Development()
+
+	if port, err := strconv.Atoi(c.HTTP.Port); err != nil || port < 1 || port > 65535 {
+		fail("HTTP_PORT must be a port number, got %q", c.HTTP.Port)
+	}
+	for _, d := range []struct {
+		env   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"INVITE_TTL", c.Invitations.TTL},
+		{"IDEMPOTENCY_TTL", c.Idempotency.TTL},
+	} {
+		if d.value <= 0 {
+			fail("%s must be positive", d.env)
+		}
+	}
+
+	if c.AWS.Region == "" {
+		fail("AWS_REGION is required")
+	}
+	if (c.AWS.AccessKeyID == "") != (c.AWS.SecretAccessKey == "") {
+		fail("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set together")
+	}
+	tables := reflect.ValueOf(c.Tables)
+	for i := 0; i < tables.NumField(); i++ {
+		if tables.Field(i).String() == "" {
+			fail("%s must not be empty", tables.Type().Field(i).Tag.Get("env"))
+		}
+	}
+
+	switch {
+	case c.JWT.Secret == "":
+		fail("JWT_SECRET is required when APP_ENV is not %s", EnvDevelopment)
+	case production && isPlaceholderSecret(c.JWT.Secret):
+		fail("JWT_SECRET is a placeholder; generate one with: openssl rand -base64 48")
+	case production && len(c.JWT.Secret) < minProductionSecretLength:
+		fail("JWT_SECRET must be at least %d characters", minProductionSecretLength)
+	}
+	if c.JWT.KeysDir != "" && c.JWT.ActiveKID == "" {
+		fail("JWT_ACTIVE_KID is required with JWT_KEYS_DIR")
+	}
+
+	p := c.Passwords
+	if p.HashAlgorithm != "argon2id" && p.HashAlgorithm != "bcrypt" {
+		fail("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got %q", p.HashAlgorithm)
+	}
+	// bcrypt.MinCost and bcrypt.MaxCost
+	if p.BcryptCost < 4 || p.BcryptCost > 31 {
+		fail("BCRYPT_COST must be between 4 and 31")
+	}
+	if p.Argon2MemoryKiB == 0 || p.Argon2Iterations == 0 || p.Argon2Parallelism == 0 {
+		fail("ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM must be positive")
+	}
+	if p.MinLength < 0 || p.MinEntropyBits < 0 {
+		fail("PASSWORD_MIN_LENGTH and PASSWORD_MIN_ENTROPY_BITS must not be negative")
+	}
+
+	if c.OIDC.IssuerURL != "" && (c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "") {
+		fail("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER_URL")
+	}
+	if c.OIDC.RedirectURL != "" && !isPageURL(c.OIDC.RedirectURL) {
+		fail("OIDC_REDIRECT_URL must be an absolute http or https URL without a fragment")
+	}
+	if c.OIDC.FrontendURL != "" && !isPageURL(c.OIDC.FrontendURL) {
+		fail("OIDC_FRONTEND_URL must be an absolute http or https URL without a fragment")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			fail("CORS_ALLOWED_ORIGINS: %v", err)
+		}
+		if origin == "*" && c.CORS.AllowCredentials {
+			fail("CORS_ALLOWED_ORIGINS cannot be * with CORS_ALLOW_CREDENTIALS")
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		fail("CORS_MAX_AGE must not be negative")
+	}
+
+	if _, err := logger.ParseLevels(c.Log.Level); err != nil {
+		fail("LOG_LEVEL: %v", err)
+	}
+	if production && !c.Log.Redaction {
+		fail("LOG_REDACTION can only be turned off when APP_ENV is %s", EnvDevelopment)
+	}
+
+	if cw := c.CloudWatch; cw.Enabled {
+		if cw.LogGroup == "" || cw.LogStream == "" {
+			fail("CLOUDWATCH_LOG_GROUP and CLOUDWATCH_LOG_STREAM are required with CLOUDWATCH_LOGS_ENABLED")
+		}
+		// PutLogEvents accepts at most 10,000 events.
+		if cw.BatchSize < 1 || cw.BatchSize > 10000 || cw.BufferSize < 1 || cw.FlushInterval <= 0 {
+			fail("CLOUDWATCH_LOGS_BUFFER_SIZE and CLOUDWATCH_LOGS_FLUSH_INTERVAL must be positive and CLOUDWATCH_LOGS_BATCH_SIZE between 1 and 10000")
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case "otlp", "stdout", "none":
+	default:
+		fail("OTEL_TRACES_EXPORTER must be otlp, stdout or none, got %q", c.Tracing.Exporter)
+	}
+	if c.Metrics.RefreshInterval < 0 || c.Readiness.CacheTTL < 0 {
+		fail("METRICS_REFRESH_INTERVAL and READINESS_CACHE_TTL must not be negative")
+	}
+
+	if rl := c.RateLimit; rl.Enabled {
+		if rl.Store != "memory" && rl.Store != "dynamodb" {
+			fail("RATE_LIMIT_STORE must be memory or dynamodb, got %q", rl.Store)
+		}
+		if rl.TrustedProxyHops < 0 {
+			fail("RATE_LIMIT_TRUSTED_PROXY_HOPS must not be negative")
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func isPlaceholderSecret(secret string) bool {
+	for _, p := range placeholderSecrets {
+		if secret == p {
+			return true
+		}
+	}
+	return false
+}
+
+// isPageURL reports whether s is an absolute http(s) URL the server can redirect to or append a
+// fragment to.
+func isPageURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Fragment == "" && !strings.Contains(s, "#")
+}
+
+// validateOrigin accepts "*", or a scheme and host with an optional port, where the host may
+// start with "*." to match any subdomain, e.g. "https://*.example.com".
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	host := strings.Replace(origin, "://*.", "://wildcard.", 1)
+	u, err := url.Parse(host)
+	if err != nil || strings.Contains(host, "*") || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return fmt.Errorf("%q is not an origin such as https://app.example.com or https://*.example.com", origin)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package cors lets browser frontends on other origins call the API: it answers CORS preflight
+// requests and adds the Access-Control-* headers to responses for allowed origins.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures which cross-origin requests are allowed.
+type Options struct {
+	// AllowedOrigins are exact origins such as https://app.example.com, patterns such as
+	// https://*.example.com that match any subdomain, or "*" for any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders are the request headers browsers may send; "*" allows any.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers browser code may read.
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response; 0 leaves it to the browser.
+	MaxAge time.Duration
+}
+
+// Policy decides whether an origin may call the API.
+type Policy struct {
+	opts      Options
+	anyOrigin bool
+	origins   map[string]bool
+	wildcards []wildcard
+	methods   string
+	headers   string
+	anyHeader bool
+	exposed   string
+	maxAge    string
+}
+
+// wildcard is an allowed origin with a "*." subdomain, split around the "*".
+type wildcard struct {
+	prefix, suffix string
+}
+
+// New returns the policy for opts. Origins are compared case-insensitively.
+func New(opts Options) *Policy {
+	p := &Policy{
+		opts:    opts,
+		origins: make(map[string]bool),
+		methods: strings.Join(opts.AllowedMethods, ", "),
+		headers: strings.Join(opts.AllowedHeaders, ", "),
+		exposed: strings.Join(opts.ExposedHeaders, ", "),
+	}
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			p.wildcards = append(p.wildcards, wildcard{prefix: prefix, suffix: suffix})
+		default:
+			p.origins[origin] = true
+		}
+	}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			p.anyHeader = true
+		}
+	}
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return p
+}
+
+// Allowed reports whether requests from origin may read responses.
+func (p *Policy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if len(origin) > len(w.prefix)+len(w.suffix) && strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) {
+			// The subdomain part must not smuggle in a path or port.
+			sub := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+			if !strings.ContainsAny(sub, "/:@") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Middleware adds CORS headers to responses for allowed origins. Preflight requests from an
+// allowed origin get the allowed methods and headers and are then passed on, so the mux answers
+// them with 204 and an Allow header for known routes and 404 otherwise. Requests from other
+// origins are served without CORS headers, which makes browsers refuse them. A policy with no
+// allowed origins returns next unchanged.
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	if !p.anyOrigin && len(p.origins) == 0 && len(p.wildcards) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		h := w.Header()
+		// Responses differ by origin unless every origin gets "*", so caches must key on it.
+		if !p.anyOrigin || p.opts.AllowCredentials {
+			h.Add("Vary", "Origin")
+		}
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if !p.Allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if p.anyOrigin && !p.opts.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.opts.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if p.exposed != "" {
+				h.Set("Access-Control-Expose-Headers", p.exposed)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if p.methods != "" {
+			h.Set("Access-Control-Allow-Methods", p.methods)
+		}
+		if p.anyHeader {
+			if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+		} else if p.headers != "" {
+			h.Set("Access-Control-Allow-Headers", p.headers)
+		}
+		if p.maxAge != "" {
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,162 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/route"
+)
+
+func TestPolicy_Allowed(t *testing.T) {
+	p := New(Options{AllowedOrigins: []string{"https://app.example.com", "https://*.staging.example.com", "http://localhost:3000/"}})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "HTTPS://APP.EXAMPLE.COM", want: true},
+		{origin: "http://localhost:3000", want: true},
+		{origin: "https://pr-42.staging.example.com", want: true},
+		{origin: "https://a.b.staging.example.com", want: true},
+		{origin: "https://staging.example.com", want: false},
+		{origin: "https://.staging.example.com", want: false},
+		{origin: "http://pr-42.staging.example.com", want: false},
+		{origin: "https://evil.com/.staging.example.com", want: false},
+		{origin: "https://app.example.com.evil.com", want: false},
+		{origin: "http://localhost:3001", want: false},
+		{origin: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := p.Allowed(tt.origin); got != tt.want {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestHandler(opts Options) http.Handler {
+	mux := route.New()
+	mux.Handle(http.MethodGet, "/api/clients", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	mux.Handle(http.MethodPost, "/api/clients", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	return New(opts).Middleware(mux)
+}
+
+var testOptions = Options{
+	AllowedOrigins:   []string{"https://app.example.com"},
+	AllowedMethods:   []string{"GET", "POST"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type"},
+	ExposedHeaders:   []string{"X-Request-ID"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func TestMiddleware_Preflight(t *testing.T) {
+	tests := []struct {
+		name       string
+		origin     string
+		path       string
+		wantStatus int
+		wantCORS   bool
+	}{
+		{name: "Allowed origin", origin: "https://app.example.com", path: "/api/clients", wantStatus: http.StatusNoContent, wantCORS: true},
+		{name: "Other origin", origin: "https://evil.com", path: "/api/clients", wantStatus: http.StatusNoContent, wantCORS: false},
+		{name: "Unknown route", origin: "https://app.example.com", path: "/api/nope", wantStatus: http.StatusNotFound, wantCORS: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+			rec := httptest.NewRecorder()
+
+			newTestHandler(testOptions).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); (got == tt.origin) != tt.wantCORS {
+				t.Fatalf("Unexpected Access-Control-Allow-Origin %q", got)
+			}
+			if !tt.wantCORS {
+				return
+			}
+			for header, want := range map[string]string{
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			} {
+				if got := h.Get(header); got != want {
+					t.Errorf("Expected %s %q, got %q", header, want, got)
+				}
+			}
+			if h.Get("Access-Control-Expose-Headers") != "" {
+				t.Error("Expected no Access-Control-Expose-Headers on a preflight response")
+			}
+		})
+	}
+}
+
+func TestMiddleware_ActualRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/clients", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+
+	newTestHandler(testOptions).ServeHTTP(rec, req)
+
+	h := rec.Header()
+	if rec.Code != http.StatusOK || rec.Body.String() != "[]" {
+		t.Errorf("Expected the route to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := h.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected the origin to be reflected, got %q", got)
+	}
+	if got := h.Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+		t.Errorf("Expected exposed headers, got %q", got)
+	}
+	if got := h.Values("Vary"); len(got) != 1 || got[0] != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %v", got)
+	}
+}
+
+func TestMiddleware_AnyOrigin(t *testing.T) {
+	opts := Options{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowedHeaders: []string{"*"}}
+	req := httptest.NewRequest(http.MethodOptions, "/api/clients", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "x-custom")
+	rec := httptest.NewRecorder()
+
+	newTestHandler(opts).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected Access-Control-Allow-Origin *, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "x-custom" {
+		t.Errorf("Expected the requested headers to be allowed, got %q", got)
+	}
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/clients", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+
+	newTestHandler(Options{}).ServeHTTP(rec, req)
+
+	if len(rec.Header().Values("Vary")) != 0 || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers without allowed origins, got %v", rec.Header())
+	}
+}
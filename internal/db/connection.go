@@ -3,12 +3,12 @@ package db
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jmason/john_ai_project/internal/config"
 )
 
 // Client wraps the DynamoDB client and provides connection management
@@ -16,33 +16,32 @@ type Client struct {
 	DynamoDB *dynamodb.Client
 	Region   string
 	Endpoint string
+	// AWSConfig is the loaded SDK configuration, for other AWS clients sharing its credentials
+	AWSConfig aws.Config
 }
 
-// NewClient creates a new DynamoDB client connection
-// It reads configuration from environment variables:
-//   - DYNAMODB_ENDPOINT: DynamoDB endpoint (if empty, uses AWS DynamoDB)
-//   - AWS_REGION: AWS region (default: us-east-1)
-//   - AWS_ACCESS_KEY_ID: AWS access key (optional, uses IAM role if not set)
-//   - AWS_SECRET_ACCESS_KEY: AWS secret key (optional, uses IAM role if not set)
-func NewClient(ctx context.Context) (*Client, error) {
-	endpoint := getEnv("DYNAMODB_ENDPOINT", "")
-	region := getEnv("AWS_REGION", "us-east-1")
-	accessKey := getEnv("AWS_ACCESS_KEY_ID", "")
-	secretKey := getEnv("AWS_SECRET_ACCESS_KEY", "")
+// NewClient creates a new DynamoDB client connection from cfg. Without an endpoint it connects
+// to AWS DynamoDB, and without static keys it uses the SDK's default credentials (e.g. the EC2
+// instance role).
+func NewClient(ctx context.Context, cfg config.AWS) (*Client, error) {
+	endpoint := cfg.DynamoDBEndpoint
+	region := cfg.Region
+	accessKey := cfg.AccessKeyID
+	secretKey := cfg.SecretAccessKey
 
 	// Build config options
-	cfgOpts := []func(*config.LoadOptions) error{
-		config.WithRegion(region),
+	cfgOpts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(region),
 	}
 
 	// Only use static credentials if both access key and secret are provided (for local dev)
 	if accessKey != "" && secretKey != "" {
-		cfgOpts = append(cfgOpts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")))
+		cfgOpts = append(cfgOpts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")))
 	}
 	// Otherwise, LoadDefaultConfig will automatically use IAM role credentials on EC2
 
 	// Add custom endpoint resolver for local DynamoDB
-	cfgOpts = append(cfgOpts, config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
+	cfgOpts = append(cfgOpts, awsconfig.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
 		func(service, reg string, options ...interface{}) (aws.Endpoint, error) {
 			if service == dynamodb.ServiceID && endpoint != "" {
 				return aws.Endpoint{
@@ -55,34 +54,28 @@ func NewClient(ctx context.Context) (*Client, error) {
 	)))
 
 	// Load AWS config
-	cfg, err := config.LoadDefaultConfig(ctx, cfgOpts...)
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, cfgOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	client := dynamodb.NewFromConfig(cfg)
+	client := dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
+		o.APIOptions = append(o.APIOptions, instrument, traceCalls)
+	})
 
 	return &Client{
-		DynamoDB: client,
-		Region:   region,
-		Endpoint: endpoint,
+		DynamoDB:  client,
+		Region:    region,
+		Endpoint:  endpoint,
+		AWSConfig: awsCfg,
 	}, nil
 }
 
 // Ping checks if the DynamoDB connection is working by listing tables
 func (c *Client) Ping(ctx context.Context) error {
-	_, err := c.DynamoDB.ListTables(ctx, &dynamodb.ListTablesInput{})
+	_, err := c.DynamoDB.ListTables(WithCaller(ctx, "Client", "Ping"), &dynamodb.ListTablesInput{})
 	if err != nil {
 		return fmt.Errorf("failed to ping DynamoDB: %w", err)
 	}
 	return nil
-}
-
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
+}
\ No newline at end of file
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/jmason/john_ai_project/internal/metrics"
+)
+
+// DynamoDB metrics, labelled with the repository method that made the call (see WithCaller) and
+// the DynamoDB operation.
+var (
+	dynamoDBDuration = metrics.NewHistogram("dynamodb_request_duration_seconds",
+		"DynamoDB call latency in seconds, including SDK retries.", nil, "repository", "method", "operation")
+	dynamoDBErrors = metrics.NewCounter("dynamodb_errors_total",
+		"DynamoDB calls that failed after SDK retries, by error code.", "repository", "method", "operation", "code")
+	dynamoDBThrottles = metrics.NewCounter("dynamodb_throttles_total",
+		"DynamoDB attempts rejected by throttling, including ones that succeeded on retry.", "repository", "method", "operation")
+)
+
+type callerKey struct{}
+
+type caller struct {
+	repository, method string
+}
+
+// WithCaller labels the DynamoDB calls made with ctx in metrics as coming from method of
+// repository, e.g. WithCaller(ctx, "ClientRepository", "GetClientByID").
+func WithCaller(ctx context.Context, repository, method string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller{repository: repository, method: method})
+}
+
+func callerFromContext(ctx context.Context) caller {
+	if c, ok := ctx.Value(callerKey{}).(caller); ok {
+		return c
+	}
+	return caller{repository: "unknown", method: "unknown"}
+}
+
+// instrument records latency and errors for each DynamoDB operation, and throttling for each
+// attempt the SDK makes.
+func instrument(stack *middleware.Stack) error {
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RecordMetrics",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			c := callerFromContext(ctx)
+			operation := awsmiddleware.GetOperationName(ctx)
+			start := time.Now()
+			out, md, err := next.HandleInitialize(ctx, in)
+			dynamoDBDuration.Observe(time.Since(start).Seconds(), c.repository, c.method, operation)
+			if err != nil {
+				dynamoDBErrors.Inc(c.repository, c.method, operation, errorCode(err))
+			}
+			return out, md, err
+		}), middleware.After)
+	if err != nil {
+		return err
+	}
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("RecordThrottles",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			out, md, err := next.HandleFinalize(ctx, in)
+			if _, throttled := retry.DefaultThrottleErrorCodes[errorCode(err)]; throttled {
+				c := callerFromContext(ctx)
+				dynamoDBThrottles.Inc(c.repository, c.method, awsmiddleware.GetOperationName(ctx))
+			}
+			return out, md, err
+		}), middleware.After)
+}
+
+// errorCode is the DynamoDB error code, such as ConditionalCheckFailedException, or "client" for
+// failures that never got a response.
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return "client"
+}
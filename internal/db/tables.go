@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Table is a table the server needs and the global secondary indexes it queries.
+type Table struct {
+	Name    string
+	Indexes []string
+}
+
+// ErrTableNotReady is returned by CheckTable for a table or index that is missing or still
+// being created.
+var ErrTableNotReady = errors.New("table not ready")
+
+// CheckTable verifies that t exists and that each of its indexes exists and can be queried.
+func (c *Client) CheckTable(ctx context.Context, t Table) error {
+	out, err := c.DynamoDB.DescribeTable(WithCaller(ctx, "Client", "CheckTable"), &dynamodb.DescribeTableInput{
+		TableName: aws.String(t.Name),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("%w: table %s does not exist", ErrTableNotReady, t.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe table %s: %w", t.Name, err)
+	}
+
+	// UPDATING tables and indexes keep serving requests.
+	if status := out.Table.TableStatus; status != types.TableStatusActive && status != types.TableStatusUpdating {
+		return fmt.Errorf("%w: table %s is %s", ErrTableNotReady, t.Name, status)
+	}
+	indexes := map[string]types.IndexStatus{}
+	for _, gsi := range out.Table.GlobalSecondaryIndexes {
+		indexes[aws.ToString(gsi.IndexName)] = gsi.IndexStatus
+	}
+	for _, name := range t.Indexes {
+		status, ok := indexes[name]
+		if !ok {
+			return fmt.Errorf("%w: index %s on table %s does not exist", ErrTableNotReady, name, t.Name)
+		}
+		if status != types.IndexStatusActive && status != types.IndexStatusUpdating {
+			return fmt.Errorf("%w: index %s on table %s is %s", ErrTableNotReady, name, t.Name, status)
+		}
+	}
+	return nil
+}
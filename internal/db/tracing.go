@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/jmason/john_ai_project/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceCalls wraps each DynamoDB operation, retries included, in a client span named like
+// "DynamoDB.UpdateItem" and attributed to the repository method that made it (see WithCaller).
+func traceCalls(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("TraceCall",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			c := callerFromContext(ctx)
+			operation := awsmiddleware.GetOperationName(ctx)
+			attrs := []attribute.KeyValue{
+				semconv.RPCSystemKey.String("aws-api"),
+				semconv.RPCService(dynamodb.ServiceID),
+				semconv.RPCMethod(operation),
+				semconv.CodeNamespace(c.repository),
+				semconv.CodeFunction(c.method),
+			}
+			if tables := tableNames(in.Parameters); len(tables) > 0 {
+				attrs = append(attrs, semconv.AWSDynamoDBTableNames(tables...))
+			}
+			ctx, span := tracing.Start(ctx, dynamodb.ServiceID+"."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			out, md, err := next.HandleInitialize(ctx, in)
+			if id, ok := awsmiddleware.GetRequestIDMetadata(md); ok {
+				span.SetAttributes(semconv.AWSRequestID(id))
+			}
+			if attempts, ok := retry.GetAttemptResults(md); ok {
+				span.SetAttributes(attribute.Int("aws.attempts", len(attempts.Results)))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, errorCode(err))
+			}
+			return out, md, err
+		}), middleware.After)
+}
+
+// tableNames lists the tables an operation touches, for the operations the repositories use.
+func tableNames(params interface{}) []string {
+	var names []*string
+	switch in := params.(type) {
+	case *dynamodb.DescribeTableInput:
+		names = append(names, in.TableName)
+	case *dynamodb.GetItemInput:
+		names = append(names, in.TableName)
+	case *dynamodb.PutItemInput:
+		names = append(names, in.TableName)
+	case *dynamodb.UpdateItemInput:
+		names = append(names, in.TableName)
+	case *dynamodb.DeleteItemInput:
+		names = append(names, in.TableName)
+	case *dynamodb.QueryInput:
+		names = append(names, in.TableName)
+	case *dynamodb.ScanInput:
+		names = append(names, in.TableName)
+	case *dynamodb.TransactWriteItemsInput:
+		for _, item := range in.TransactItems {
+			switch {
+			case item.Put != nil:
+				names = append(names, item.Put.TableName)
+			case item.Update != nil:
+				names = append(names, item.Update.TableName)
+			case item.Delete != nil:
+				names = append(names, item.Delete.TableName)
+			case item.ConditionCheck != nil:
+				names = append(names, item.ConditionCheck.TableName)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	var tables []string
+	for _, name := range names {
+		if n := aws.ToString(name); n != "" && !seen[n] {
+			seen[n] = true
+			tables = append(tables, n)
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
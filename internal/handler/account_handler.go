@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// AccountService interface for dependency injection
+type AccountService interface {
+	UpdateProfile(ctx context.Context, userID string, update service.ProfileUpdate) (*repository.User, string, error)
+	ConfirmEmailChange(ctx context.Context, token string) (*repository.User, error)
+	ChangePassword(ctx context.Context, userID, currentSessionID, currentPassword, newPassword string) error
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]service.ActiveSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) (int, error)
+}
+
+// AccountHandler serves the signed-in user's own profile, password and sessions.
+type AccountHandler struct {
+	service AccountService
+}
+
+func NewAccountHandler(service AccountService) *AccountHandler {
+	return &AccountHandler{
+		service: service,
+	}
+}
+
+// UpdateProfileRequest is a partial update; omitted fields are left unchanged.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Email     *string `json:"email,omitempty"`
+}
+
+type UpdateProfileResponse struct {
+	User *repository.User `json:"user"`
+	// PendingEmail is set when a verification link was sent to a new address.
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+func (h *AccountHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, r, invalidBody(err))
+		return
+	}
+
+	user, pendingEmail, err := h.service.UpdateProfile(r.Context(), p.ID, service.ProfileUpdate{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+	})
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, UpdateProfileResponse{
+		User:         user,
+		PendingEmail: pendingEmail,
+	})
+}
+
+// VerifyEmail confirms an email change with the token from the verification link. The token
+// identifies the user, so this endpoint does not require a login.
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, r, invalidBody(err))
+		return
+	}
+
+	user, err := h.service.ConfirmEmailChange(r.Context(), req.Token)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, user)
+}
+
+func (h *AccountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, r, invalidBody(err))
+		return
+	}
+
+	if err := h.service.ChangePassword(r.Context(), p.ID, p.SessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AccountHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.service.ListSessions(r.Context(), p.ID, p.SessionID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession signs out the session in the URL path. Revoking the current session logs the
+// caller out.
+func (h *AccountHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	id := route.Param(r, "id")
+	if id == "" {
+		RespondError(w, r, missingPathParam("id"))
+		return
+	}
+
+	if err := h.service.RevokeSession(r.Context(), p.ID, id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs out every session except the one making the request.
+func (h *AccountHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.service.RevokeOtherSessions(r.Context(), p.ID, p.SessionID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, RevokeSessionsResponse{Revoked: revoked})
+}
+
+// accountPrincipal returns the signed-in user. API keys have no account to manage.
+func accountPrincipal(w http.ResponseWriter, r *http.Request) (*Principal, bool) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		RespondError(w, r, errNotAuthenticated)
+		return nil, false
+	}
+	if p.Type != PrincipalUser {
+		RespondError(w, r, errUserLoginRequired)
+		return nil, false
+	}
+	return p, true
+}
+
+// sessionMetadata describes the client making the request. Behind API Gateway the caller's
+// address is the first X-Forwarded-For entry.
+func sessionMetadata(r *http.Request) service.SessionMetadata {
+	ip := r.RemoteAddr
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	return service.SessionMetadata{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// Mock AccountService
+type MockAccountService struct {
+	UpdateProfileFunc       func(ctx context.Context, userID string, update service.ProfileUpdate) (*repository.User, string, error)
+	ConfirmEmailChangeFunc  func(ctx context.Context, token string) (*repository.User, error)
+	ChangePasswordFunc      func(ctx context.Context, userID, currentSessionID, currentPassword, newPassword string) error
+	ListSessionsFunc        func(ctx context.Context, userID, currentSessionID string) ([]service.ActiveSession, error)
+	RevokeSessionFunc       func(ctx context.Context, userID, sessionID string) error
+	RevokeOtherSessionsFunc func(ctx context.Context, userID, keepSessionID string) (int, error)
+}
+
+func (m *MockAccountService) UpdateProfile(ctx context.Context, userID string, update service.ProfileUpdate) (*repository.User, string, error) {
+	if m.UpdateProfileFunc != nil {
+		return m.UpdateProfileFunc(ctx, userID, update)
+	}
+	return nil, "", nil
+}
+
+func (m *MockAccountService) ConfirmEmailChange(ctx context.Context, token string) (*repository.User, error) {
+	if m.ConfirmEmailChangeFunc != nil {
+		return m.ConfirmEmailChangeFunc(ctx, token)
+	}
+	return nil, nil
+}
+
+func (m *MockAccountService) ChangePassword(ctx context.Context, userID, currentSessionID, currentPassword, newPassword string) error {
+	if m.ChangePasswordFunc != nil {
+		return m.ChangePasswordFunc(ctx, userID, currentSessionID, currentPassword, newPassword)
+	}
+	return nil
+}
+
+func (m *MockAccountService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]service.ActiveSession, error) {
+	if m.ListSessionsFunc != nil {
+		return m.ListSessionsFunc(ctx, userID, currentSessionID)
+	}
+	return nil, nil
+}
+
+func (m *MockAccountService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if m.RevokeSessionFunc != nil {
+		return m.RevokeSessionFunc(ctx, userID, sessionID)
+	}
+	return nil
+}
+
+func (m *MockAccountService) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) (int, error) {
+	if m.RevokeOtherSessionsFunc != nil {
+		return m.RevokeOtherSessionsFunc(ctx, userID, keepSessionID)
+	}
+	return 0, nil
+}
+
+func withTestPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(withPrincipal(r.Context(), p, ""))
+}
+
+func TestAccountHandler_UpdateProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		principal      *Principal
+		requestBody    string
+		updateErr      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{name: "Success - Email change pending", principal: &Principal{Type: PrincipalUser, ID: "user-1"}, requestBody: `{"email":"new@example.com"}`, expectedStatus: http.StatusOK},
+		{name: "Failure - Invalid JSON", principal: &Principal{Type: PrincipalUser, ID: "user-1"}, requestBody: `{invalid`, expectedStatus: http.StatusBadRequest, expectedError: "invalid_body"},
+		{name: "Failure - Email taken", principal: &Principal{Type: PrincipalUser, ID: "user-1"}, requestBody: `{"email":"taken@example.com"}`, updateErr: service.ErrUserExists, expectedStatus: http.StatusConflict, expectedError: "user_exists"},
+		{name: "Failure - SSO managed email", principal: &Principal{Type: PrincipalUser, ID: "user-1"}, requestBody: `{"email":"new@example.com"}`, updateErr: service.ErrEmailManagedByProvider, expectedStatus: http.StatusForbidden, expectedError: "email_managed_by_provider"},
+		{name: "Failure - API key caller", principal: &Principal{Type: PrincipalService, ID: "key-1"}, requestBody: `{}`, expectedStatus: http.StatusForbidden, expectedError: "user_login_required"},
+		{name: "Failure - Not authenticated", requestBody: `{}`, expectedStatus: http.StatusUnauthorized, expectedError: "not_authenticated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockAccountService{
+				UpdateProfileFunc: func(ctx context.Context, userID string, update service.ProfileUpdate) (*repository.User, string, error) {
+					if tt.updateErr != nil {
+						return nil, "", tt.updateErr
+					}
+					if update.Email == nil || update.FirstName != nil {
+						t.Errorf("Expected only email in update, got %+v", update)
+					}
+					return &repository.User{ID: userID, Email: "old@example.com"}, *update.Email, nil
+				},
+			}
+			handler := NewAccountHandler(mockSvc)
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/auth/me", bytes.NewBufferString(tt.requestBody))
+			if tt.principal != nil {
+				req = withTestPrincipal(req, tt.principal)
+			}
+			w := httptest.NewRecorder()
+
+			handler.UpdateProfile(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedError != "" {
+				var resp Problem
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.expectedError {
+					t.Errorf("Expected error code %q, got %q", tt.expectedError, resp.Code)
+				}
+				return
+			}
+			var resp UpdateProfileResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.PendingEmail != "new@example.com" || resp.User.Email != "old@example.com" {
+				t.Errorf("Unexpected response: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestAccountHandler_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		changeErr      error
+		expectedStatus int
+	}{
+		{name: "Success - Password changed", expectedStatus: http.StatusNoContent},
+		{name: "Failure - Wrong current password", changeErr: service.ErrCurrentPasswordIncorrect, expectedStatus: http.StatusForbidden},
+		{name: "Failure - Weak new password", changeErr: service.ErrAuthInvalidPassword, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockAccountService{
+				ChangePasswordFunc: func(ctx context.Context, userID, currentSessionID, currentPassword, newPassword string) error {
+					if currentSessionID != "sess-1" {
+						t.Errorf("Expected current session sess-1, got %q", currentSessionID)
+					}
+					return tt.changeErr
+				},
+			}
+			handler := NewAccountHandler(mockSvc)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/password", bytes.NewBufferString(`{"current_password":"old","new_password":"new-password"}`))
+			req = withTestPrincipal(req, &Principal{Type: PrincipalUser, ID: "user-1", SessionID: "sess-1"})
+			w := httptest.NewRecorder()
+
+			handler.ChangePassword(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAccountHandler_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		revokeErr      error
+		expectedStatus int
+	}{
+		{name: "Success - Revoked", id: "sess-2", expectedStatus: http.StatusNoContent},
+		{name: "Failure - Missing id", id: "", expectedStatus: http.StatusBadRequest},
+		{name: "Failure - Not found", id: "missing", revokeErr: service.ErrSessionNotFound, expectedStatus: http.StatusNotFound},
+		{name: "Failure - Tracking disabled", id: "sess-2", revokeErr: service.ErrSessionsDisabled, expectedStatus: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockAccountService{
+				RevokeSessionFunc: func(ctx context.Context, userID, sessionID string) error {
+					return tt.revokeErr
+				},
+			}
+			handler := NewAccountHandler(mockSvc)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/auth/sessions/"+tt.id, nil)
+			req = withTestPrincipal(req, &Principal{Type: PrincipalUser, ID: "user-1"})
+			if tt.id != "" {
+				req = route.WithParams(req, map[string]string{"id": tt.id})
+			}
+			w := httptest.NewRecorder()
+
+			handler.RevokeSession(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestSessionMetadata(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+
+	meta := sessionMetadata(req)
+	if meta.IPAddress != "203.0.113.7" || meta.UserAgent != "test-agent" {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+)
+
+// APIKeyService interface for dependency injection
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, createdBy, name string, scopes []string) (*repository.APIKey, string, error)
+	ListAPIKeys(ctx context.Context) ([]repository.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+type APIKeyHandler struct {
+	service APIKeyService
+}
+
+func NewAPIKeyHandler(service APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateAPIKeyResponse carries the plaintext key. It is only returned once, at creation.
+type CreateAPIKeyResponse struct {
+	APIKey *repository.APIKey `json:"api_key"`
+	Key    string             `json:"key"`
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, r, invalidBody(err))
+		return
+	}
+
+	createdBy, _ := r.Context().Value("user_id").(string)
+	key, raw, err := h.service.CreateAPIKey(r.Context(), createdBy, req.Name, req.Scopes)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	// no-store keeps the raw key out of caches and the idempotency store.
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusCreated, CreateAPIKeyResponse{
+		APIKey: key,
+		Key:    raw,
+	})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.ListAPIKeys(r.Context())
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(r, "id")
+	if id == "" {
+		RespondError(w, r, missingPathParam("id"))
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
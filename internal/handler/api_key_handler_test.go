@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// Mock APIKeyService
+type MockAPIKeyService struct {
+	CreateAPIKeyFunc func(ctx context.Context, createdBy, name string, scopes []string) (*repository.APIKey, string, error)
+	ListAPIKeysFunc  func(ctx context.Context) ([]repository.APIKey, error)
+	RevokeAPIKeyFunc func(ctx context.Context, id string) error
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, createdBy, name string, scopes []string) (*repository.APIKey, string, error) {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(ctx, createdBy, name, scopes)
+	}
+	return nil, "", nil
+}
+
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context) ([]repository.APIKey, error) {
+	if m.ListAPIKeysFunc != nil {
+		return m.ListAPIKeysFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	if m.RevokeAPIKeyFunc != nil {
+		return m.RevokeAPIKeyFunc(ctx, id)
+	}
+	return nil
+}
+
+func TestAPIKeyHandler_CreateAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		createErr      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{name: "Success - Key returned once", requestBody: `{"name":"nightly","scopes":["clients:read"]}`, expectedStatus: http.StatusCreated},
+		{name: "Failure - Invalid JSON", requestBody: `{invalid`, expectedStatus: http.StatusBadRequest, expectedError: "invalid_body"},
+		{name: "Failure - Invalid scopes", requestBody: `{"name":"nightly","scopes":["admin"]}`, createErr: service.ErrAPIKeyInvalidScopes, expectedStatus: http.StatusBadRequest, expectedError: "invalid_scopes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockAPIKeyService{
+				CreateAPIKeyFunc: func(ctx context.Context, createdBy, name string, scopes []string) (*repository.APIKey, string, error) {
+					if tt.createErr != nil {
+						return nil, "", tt.createErr
+					}
+					if createdBy != "admin-1" {
+						t.Errorf("Expected createdBy admin-1, got %q", createdBy)
+					}
+					return &repository.APIKey{ID: "abc", Name: name, SecretHash: "hash", Scopes: scopes}, "jak_abc_secret", nil
+				},
+			}
+			handler := NewAPIKeyHandler(mockSvc)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/api-keys", bytes.NewBufferString(tt.requestBody))
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", "admin-1"))
+			w := httptest.NewRecorder()
+
+			handler.CreateAPIKey(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedError != "" {
+				var resp Problem
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Code != tt.expectedError {
+					t.Errorf("Expected error code %q, got %q", tt.expectedError, resp.Code)
+				}
+				return
+			}
+			if strings.Contains(w.Body.String(), "hash") {
+				t.Error("Response must not expose the secret hash")
+			}
+			var resp CreateAPIKeyResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Key != "jak_abc_secret" {
+				t.Errorf("Expected plaintext key in response, got %q", resp.Key)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHandler_RevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		revokeErr      error
+		expectedStatus int
+	}{
+		{name: "Success - Revoked", id: "abc", expectedStatus: http.StatusNoContent},
+		{name: "Failure - Missing id", id: "", expectedStatus: http.StatusBadRequest},
+		{name: "Failure - Not found", id: "missing", revokeErr: service.ErrAPIKeyNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockAPIKeyService{
+				RevokeAPIKeyFunc: func(ctx context.Context, id string) error {
+					return tt.revokeErr
+				},
+			}
+			handler := NewAPIKeyHandler(mockSvc)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/api-keys/"+tt.id, nil)
+			if tt.id != "" {
+				req = route.WithParams(req, map[string]string{"id": tt.id})
+			}
+			w := httptest.NewRecorder()
+
+			handler.RevokeAPIKey(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -3,10 +3,11 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/jmason/john_ai_project/internal/apperror"
 	"github.com/jmason/john_ai_project/internal/repository"
 	"github.com/jmason/john_ai_project/internal/service"
 )
@@ -14,28 +15,75 @@ import (
 // AuthService interface for dependency injection
 type AuthService interface {
 	Register(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error)
+	RegisterWithInvite(ctx context.Context, inviteToken, username, email, password, firstName, lastName string) (*repository.User, error)
 	Login(ctx context.Context, usernameOrEmail, password string) (string, *repository.User, error)
 	GetUserByID(ctx context.Context, userID string) (*repository.User, error)
-	GenerateToken(user *repository.User) (string, error)
+	IssueToken(ctx context.Context, user *repository.User) (string, error)
 	ValidateToken(tokenString string) (*service.Claims, error)
+	CheckSession(ctx context.Context, claims *service.Claims) error
+	Impersonate(ctx context.Context, actorID, targetUserID string) (string, *repository.User, time.Time, error)
+	JWKS() service.JWKS
+}
+
+// APIKeyAuthenticator verifies API keys for AuthMiddleware. APIKeyService implements it.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*repository.APIKey, error)
 }
 
 type AuthHandler struct {
 	authService AuthService
+	apiKeys     APIKeyAuthenticator
+	// impersonationWrites lets impersonation tokens make changes (tagged in the audit log)
+	// instead of being read-only.
+	impersonationWrites bool
+}
+
+// AuthHandlerOption configures optional AuthHandler behaviour.
+type AuthHandlerOption func(*AuthHandler)
+
+// WithAPIKeys lets AuthMiddleware accept API keys alongside Bearer JWTs.
+func WithAPIKeys(apiKeys APIKeyAuthenticator) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.apiKeys = apiKeys
+	}
 }
 
-func NewAuthHandler(authService AuthService) *AuthHandler {
-	return &AuthHandler{
+// WithImpersonationWrites allows requests other than GET, HEAD and OPTIONS while an admin is
+// impersonating a user. They are logged with both identities either way.
+func WithImpersonationWrites(allow bool) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.impersonationWrites = allow
+	}
+}
+
+func NewAuthHandler(authService AuthService, opts ...AuthHandlerOption) *AuthHandler {
+	h := &AuthHandler{
 		authService: authService,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
+// Errors raised while authenticating and authorising requests
+var (
+	errAuthorizationRequired = apperror.Unauthorized("authorization_required", "authorization header required")
+	errAuthorizationFormat   = apperror.Unauthorized("invalid_authorization_header", "invalid authorization format. Use: Bearer <token>")
+	errInvalidToken          = apperror.Unauthorized("invalid_token", "invalid or expired token")
+	errNotAuthenticated      = apperror.Unauthorized("not_authenticated", "no authenticated user for this request")
+	errInsufficientRole      = apperror.Forbidden("insufficient_role", "insufficient role for this operation")
+	errUserLoginRequired     = apperror.Forbidden("user_login_required", "this endpoint requires a user login, not an API key")
+)
+
 type RegisterRequest struct {
 	Username  string `json:"username"`
 	Email     string `json:"email"`
 	Password  string `json:"password"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	// InviteToken is required when open registration is disabled; the invited role is applied.
+	InviteToken string `json:"invite_token,omitempty"`
 }
 
 type LoginRequest struct {
@@ -44,35 +92,33 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  interface{} `json:"user"`
+	Token string           `json:"token"`
+	User  *repository.User `json:"user"`
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		RespondError(w, r, invalidBody(err))
 		return
 	}
 
-	user, err := h.authService.Register(r.Context(), req.Username, req.Email, req.Password, req.FirstName, req.LastName)
+	ctx := service.WithSessionMetadata(r.Context(), sessionMetadata(r))
+	var user *repository.User
+	var err error
+	if req.InviteToken != "" {
+		user, err = h.authService.RegisterWithInvite(ctx, req.InviteToken, req.Username, req.Email, req.Password, req.FirstName, req.LastName)
+	} else {
+		user, err = h.authService.Register(ctx, req.Username, req.Email, req.Password, req.FirstName, req.LastName)
+	}
 	if err != nil {
-		RespondJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "Registration failed",
-			Message: err.Error(),
-		})
+		RespondError(w, r, err)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(user)
+	token, err := h.authService.IssueToken(ctx, user)
 	if err != nil {
-		RespondJSON(w, http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to generate token",
-			Message: err.Error(),
-		})
+		RespondError(w, r, err)
 		return
 	}
 
@@ -85,23 +131,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		RespondError(w, r, invalidBody(err))
 		return
 	}
 
-	token, user, err := h.authService.Login(r.Context(), req.Login, req.Password)
+	ctx := service.WithSessionMetadata(r.Context(), sessionMetadata(r))
+	token, user, err := h.authService.Login(ctx, req.Login, req.Password)
 	if err != nil {
-		statusCode := http.StatusUnauthorized
-		if errors.Is(err, service.ErrAuthLoginMissingFields) {
-			statusCode = http.StatusBadRequest
-		}
-		RespondJSON(w, statusCode, ErrorResponse{
-			Error:   "Login failed",
-			Message: err.Error(),
-		})
+		RespondError(w, r, err)
 		return
 	}
 
@@ -114,62 +151,126 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("user_id").(string)
 	if !ok {
-		RespondJSON(w, http.StatusUnauthorized, ErrorResponse{
-			Error:   "Unauthorized",
-			Message: "User not found in context",
-		})
+		RespondError(w, r, errNotAuthenticated)
 		return
 	}
 
 	user, err := h.authService.GetUserByID(r.Context(), userID)
 	if err != nil {
-		RespondJSON(w, http.StatusNotFound, ErrorResponse{
-			Error:   "User not found",
-			Message: err.Error(),
-		})
+		RespondError(w, r, err)
 		return
 	}
 
 	RespondJSON(w, http.StatusOK, user)
 }
 
+// JWKS publishes the public signing keys so the frontend and the API Gateway authorizer can
+// verify tokens without sharing a secret.
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	RespondJSON(w, http.StatusOK, h.authService.JWKS())
+}
+
+// AuthMiddleware authenticates the request with either a Bearer JWT or, when API keys are
+// enabled, an API key sent as "Bearer jak_..." or in the X-API-Key header.
 func (h *AuthHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-API-Key"); key != "" && h.apiKeys != nil {
+			h.serveWithAPIKey(w, r, key, next)
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			RespondJSON(w, http.StatusUnauthorized, ErrorResponse{
-				Error:   "Unauthorized",
-				Message: "Authorization header required",
-			})
+			RespondError(w, r, errAuthorizationRequired)
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			RespondJSON(w, http.StatusUnauthorized, ErrorResponse{
-				Error:   "Unauthorized",
-				Message: "Invalid authorization format. Use: Bearer <token>",
-			})
+			RespondError(w, r, errAuthorizationFormat)
 			return
 		}
 
 		token := parts[1]
 
+		if service.IsAPIKey(token) && h.apiKeys != nil {
+			h.serveWithAPIKey(w, r, token, next)
+			return
+		}
+
 		claims, err := h.authService.ValidateToken(token)
 		if err != nil {
-			RespondJSON(w, http.StatusUnauthorized, ErrorResponse{
-				Error:   "Unauthorized",
-				Message: "Invalid or expired token",
-			})
+			RespondError(w, r, errInvalidToken)
 			return
 		}
 
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, "user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "user_email", claims.Email)
-		ctx = context.WithValue(ctx, "user_username", claims.Username)
-		ctx = context.WithValue(ctx, "user_role", claims.Role)
+		if err := h.authService.CheckSession(r.Context(), claims); err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		p := &Principal{
+			Type:      PrincipalUser,
+			ID:        claims.UserID,
+			Name:      claims.Username,
+			Role:      claims.Role,
+			SessionID: claims.ID,
+		}
+		if claims.Actor != nil {
+			p.ActorID, p.ActorName = claims.Actor.UserID, claims.Actor.Username
+			if !h.allowImpersonated(w, r, p) {
+				return
+			}
+		}
+		ctx := service.WithSessionMetadata(withPrincipal(r.Context(), p, claims.Email), sessionMetadata(r))
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+func (h *AuthHandler) serveWithAPIKey(w http.ResponseWriter, r *http.Request, rawKey string, next http.HandlerFunc) {
+	key, err := h.apiKeys.Authenticate(r.Context(), rawKey)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	ctx := withPrincipal(r.Context(), &Principal{
+		Type:   PrincipalService,
+		ID:     "apikey:" + key.ID,
+		Name:   key.Name,
+		Role:   PrincipalService,
+		Scopes: key.Scopes,
+	}, "")
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireRole wraps a handler that has already passed AuthMiddleware and rejects callers whose
+// role is not one of roles.
+func (h *AuthHandler) RequireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value("user_role").(string)
+		for _, allowed := range roles {
+			if role == allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		RespondError(w, r, errInsufficientRole)
+	}
+}
+
+// RequireScope wraps a handler that has already passed AuthMiddleware and rejects API keys
+// that were not granted scope. Users are authorised by role and always pass.
+func (h *AuthHandler) RequireScope(next http.HandlerFunc, scope string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFromContext(r.Context())
+		if !ok || !p.HasScope(scope) {
+			RespondError(w, r, apperror.Forbidden("missing_scope", "API key is missing required scope: "+scope))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
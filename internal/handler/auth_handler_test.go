@@ -5,22 +5,29 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/jmason/john_ai_project/internal/repository"
 	"github.com/jmason/john_ai_project/internal/service"
 )
 
 // Mock AuthService
 type MockAuthService struct {
-	RegisterFunc      func(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error)
-	LoginFunc         func(ctx context.Context, usernameOrEmail, password string) (string, *repository.User, error)
-	GetUserByIDFunc   func(ctx context.Context, userID string) (*repository.User, error)
-	GenerateTokenFunc func(user *repository.User) (string, error)
-	ValidateTokenFunc func(tokenString string) (*service.Claims, error)
+	RegisterFunc           func(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error)
+	RegisterWithInviteFunc func(ctx context.Context, inviteToken, username, email, password, firstName, lastName string) (*repository.User, error)
+	LoginFunc              func(ctx context.Context, usernameOrEmail, password string) (string, *repository.User, error)
+	GetUserByIDFunc        func(ctx context.Context, userID string) (*repository.User, error)
+	IssueTokenFunc         func(ctx context.Context, user *repository.User) (string, error)
+	ValidateTokenFunc      func(tokenString string) (*service.Claims, error)
+	CheckSessionFunc       func(ctx context.Context, claims *service.Claims) error
+	ImpersonateFunc        func(ctx context.Context, actorID, targetUserID string) (string, *repository.User, time.Time, error)
+	JWKSFunc               func() service.JWKS
 }
 
 func (m *MockAuthService) Register(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error) {
@@ -30,6 +37,13 @@ func (m *MockAuthService) Register(ctx context.Context, username, email, passwor
 	return nil, nil
 }
 
+func (m *MockAuthService) RegisterWithInvite(ctx context.Context, inviteToken, username, email, password, firstName, lastName string) (*repository.User, error) {
+	if m.RegisterWithInviteFunc != nil {
+		return m.RegisterWithInviteFunc(ctx, inviteToken, username, email, password, firstName, lastName)
+	}
+	return nil, nil
+}
+
 func (m *MockAuthService) Login(ctx context.Context, usernameOrEmail, password string) (string, *repository.User, error) {
 	if m.LoginFunc != nil {
 		return m.LoginFunc(ctx, usernameOrEmail, password)
@@ -44,9 +58,9 @@ func (m *MockAuthService) GetUserByID(ctx context.Context, userID string) (*repo
 	return nil, nil
 }
 
-func (m *MockAuthService) GenerateToken(user *repository.User) (string, error) {
-	if m.GenerateTokenFunc != nil {
-		return m.GenerateTokenFunc(user)
+func (m *MockAuthService) IssueToken(ctx context.Context, user *repository.User) (string, error) {
+	if m.IssueTokenFunc != nil {
+		return m.IssueTokenFunc(ctx, user)
 	}
 	return "", nil
 }
@@ -58,6 +72,27 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*service.Claims, er
 	return nil, nil
 }
 
+func (m *MockAuthService) CheckSession(ctx context.Context, claims *service.Claims) error {
+	if m.CheckSessionFunc != nil {
+		return m.CheckSessionFunc(ctx, claims)
+	}
+	return nil
+}
+
+func (m *MockAuthService) Impersonate(ctx context.Context, actorID, targetUserID string) (string, *repository.User, time.Time, error) {
+	if m.ImpersonateFunc != nil {
+		return m.ImpersonateFunc(ctx, actorID, targetUserID)
+	}
+	return "", nil, time.Time{}, nil
+}
+
+func (m *MockAuthService) JWKS() service.JWKS {
+	if m.JWKSFunc != nil {
+		return m.JWKSFunc()
+	}
+	return service.JWKS{Keys: []service.JWK{}}
+}
+
 func TestAuthHandler_Register(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -86,7 +121,7 @@ func TestAuthHandler_Register(t *testing.T) {
 						LastName:  lastName,
 					}, nil
 				}
-				m.GenerateTokenFunc = func(user *repository.User) (string, error) {
+				m.IssueTokenFunc = func(ctx context.Context, user *repository.User) (string, error) {
 					return "jwt-token-123", nil
 				}
 			},
@@ -106,7 +141,7 @@ func TestAuthHandler_Register(t *testing.T) {
 			requestBody:    `{"username": "test`,
 			mockSetup:      func(m *MockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid request body",
+			expectedError:  "invalid_body",
 		},
 		{
 			name: "Failure - Service validation (missing fields)",
@@ -140,6 +175,34 @@ func TestAuthHandler_Register(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedError:  "password must be at least 8 characters long",
 		},
+		{
+			name: "Failure - Password policy lists every violation",
+			requestBody: RegisterRequest{
+				Username:  "johndoe",
+				Email:     "john@example.com",
+				Password:  "johndoe",
+				FirstName: "John",
+				LastName:  "Doe",
+			},
+			mockSetup: func(m *MockAuthService) {
+				m.RegisterFunc = func(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error) {
+					return nil, &service.PasswordPolicyError{Violations: []service.PasswordViolation{
+						{Rule: service.PasswordRuleMinLength, Message: "password must be at least 8 characters long"},
+						{Rule: service.PasswordRuleContainsUsername, Message: "password must not contain your username"},
+					}}
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			validateResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var resp Problem
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.Code != "invalid_password" || len(resp.Violations) != 2 || resp.Violations[1].Rule != service.PasswordRuleContainsUsername {
+					t.Errorf("Unexpected response: %+v", resp)
+				}
+			},
+		},
 		{
 			name: "Failure - Service validation (email exists)",
 			requestBody: RegisterRequest{
@@ -154,11 +217,11 @@ func TestAuthHandler_Register(t *testing.T) {
 					return nil, service.ErrUserExists
 				}
 			},
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusConflict,
 			expectedError:  "user with this email already exists",
 		},
 		{
-			name: "Failure - GenerateToken error",
+			name: "Failure - IssueToken error",
 			requestBody: RegisterRequest{
 				Username:  "johndoe",
 				Email:     "john@example.com",
@@ -170,12 +233,84 @@ func TestAuthHandler_Register(t *testing.T) {
 				m.RegisterFunc = func(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error) {
 					return &repository.User{ID: "user-123"}, nil
 				}
-				m.GenerateTokenFunc = func(user *repository.User) (string, error) {
+				m.IssueTokenFunc = func(ctx context.Context, user *repository.User) (string, error) {
 					return "", context.DeadlineExceeded
 				}
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedError:  "Failed to generate token",
+			expectedError:  "internal_error",
+		},
+		{
+			name: "Failure - Open registration disabled",
+			requestBody: RegisterRequest{
+				Username:  "johndoe",
+				Email:     "john@example.com",
+				Password:  "password123",
+				FirstName: "John",
+				LastName:  "Doe",
+			},
+			mockSetup: func(m *MockAuthService) {
+				m.RegisterFunc = func(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error) {
+					return nil, service.ErrRegistrationClosed
+				}
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "registration requires an invitation",
+		},
+		{
+			name: "Success - Invite token uses invited registration",
+			requestBody: RegisterRequest{
+				Username:    "counsellor",
+				Email:       "counsellor@example.com",
+				Password:    "password123",
+				FirstName:   "Alice",
+				LastName:    "Counsellor",
+				InviteToken: "invite-token",
+			},
+			mockSetup: func(m *MockAuthService) {
+				m.RegisterFunc = func(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error) {
+					return nil, errors.New("Register must not be called when an invite token is sent")
+				}
+				m.RegisterWithInviteFunc = func(ctx context.Context, inviteToken, username, email, password, firstName, lastName string) (*repository.User, error) {
+					if inviteToken != "invite-token" {
+						return nil, service.ErrInvitationInvalid
+					}
+					return &repository.User{ID: "user-456", Username: username, Email: email, Role: "counsellor"}, nil
+				}
+				m.IssueTokenFunc = func(ctx context.Context, user *repository.User) (string, error) {
+					return "jwt-token-456", nil
+				}
+			},
+			expectedStatus: http.StatusCreated,
+			validateResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var resp struct {
+					User repository.User `json:"user"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.User.Role != "counsellor" {
+					t.Errorf("Expected role 'counsellor', got %q", resp.User.Role)
+				}
+			},
+		},
+		{
+			name: "Failure - Invitation already used",
+			requestBody: RegisterRequest{
+				Username:    "counsellor",
+				Email:       "counsellor@example.com",
+				Password:    "password123",
+				FirstName:   "Alice",
+				LastName:    "Counsellor",
+				InviteToken: "used-token",
+			},
+			mockSetup: func(m *MockAuthService) {
+				m.RegisterWithInviteFunc = func(ctx context.Context, inviteToken, username, email, password, firstName, lastName string) (*repository.User, error) {
+					return nil, service.ErrInvitationUsed
+				}
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  "invitation has already been used or revoked",
 		},
 	}
 
@@ -204,16 +339,16 @@ func TestAuthHandler_Register(t *testing.T) {
 			}
 
 			if tt.expectedError != "" {
-				var errResp ErrorResponse
+				var errResp Problem
 				if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
 					bodyStr := w.Body.String()
 					if !strings.Contains(bodyStr, tt.expectedError) {
 						t.Errorf("Expected error containing '%s', got body: %s", tt.expectedError, bodyStr)
 					}
 				} else {
-					if !strings.Contains(errResp.Error, tt.expectedError) && !strings.Contains(errResp.Message, tt.expectedError) {
-						t.Errorf("Expected error containing '%s', got Error='%s', Message='%s'",
-							tt.expectedError, errResp.Error, errResp.Message)
+					if errResp.Code != tt.expectedError && !strings.Contains(errResp.Detail, tt.expectedError) {
+						t.Errorf("Expected error containing '%s', got Code='%s', Detail='%s'",
+							tt.expectedError, errResp.Code, errResp.Detail)
 					}
 				}
 			}
@@ -251,7 +386,7 @@ func TestAuthHandler_Login(t *testing.T) {
 			requestBody:    `{"login": "test`,
 			mockSetup:      func(m *MockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid request body",
+			expectedError:  "invalid_body",
 		},
 		{
 			name: "Failure - Missing login/password (400)",
@@ -292,7 +427,7 @@ func TestAuthHandler_Login(t *testing.T) {
 					return "", nil, service.ErrAccountDisabled
 				}
 			},
-			expectedStatus: http.StatusUnauthorized,
+			expectedStatus: http.StatusForbidden,
 			expectedError:  "account is disabled",
 		},
 	}
@@ -322,16 +457,16 @@ func TestAuthHandler_Login(t *testing.T) {
 			}
 
 			if tt.expectedError != "" {
-				var errResp ErrorResponse
+				var errResp Problem
 				if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
 					bodyStr := w.Body.String()
 					if !strings.Contains(bodyStr, tt.expectedError) {
 						t.Errorf("Expected error containing '%s', got body: %s", tt.expectedError, bodyStr)
 					}
 				} else {
-					if !strings.Contains(errResp.Error, tt.expectedError) && !strings.Contains(errResp.Message, tt.expectedError) {
-						t.Errorf("Expected error containing '%s', got Error='%s', Message='%s'",
-							tt.expectedError, errResp.Error, errResp.Message)
+					if errResp.Code != tt.expectedError && !strings.Contains(errResp.Detail, tt.expectedError) {
+						t.Errorf("Expected error containing '%s', got Code='%s', Detail='%s'",
+							tt.expectedError, errResp.Code, errResp.Detail)
 					}
 				}
 			}
@@ -366,18 +501,18 @@ func TestAuthHandler_Me(t *testing.T) {
 			contextUserID:  nil,
 			mockSetup:      func(m *MockAuthService) {},
 			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "User not found in context",
+			expectedError:  "not_authenticated",
 		},
 		{
 			name:          "Failure - User not found",
 			contextUserID: "nonexistent",
 			mockSetup: func(m *MockAuthService) {
 				m.GetUserByIDFunc = func(ctx context.Context, userID string) (*repository.User, error) {
-					return nil, errors.New("user not found")
+					return nil, fmt.Errorf("failed to get user: %w", repository.ErrUserNotFound)
 				}
 			},
 			expectedStatus: http.StatusNotFound,
-			expectedError:  "user not found",
+			expectedError:  "user_not_found",
 		},
 	}
 
@@ -402,15 +537,15 @@ func TestAuthHandler_Me(t *testing.T) {
 			}
 
 			if tt.expectedError != "" {
-				var errResp ErrorResponse
+				var errResp Problem
 				if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
 					bodyStr := w.Body.String()
 					if !strings.Contains(bodyStr, tt.expectedError) {
 						t.Errorf("Expected error containing '%s', got body: %s", tt.expectedError, bodyStr)
 					}
 				} else {
-					if !strings.Contains(errResp.Error, tt.expectedError) && !strings.Contains(errResp.Message, tt.expectedError) {
-						t.Errorf("Expected error containing '%s', got Error='%s' Message='%s'", tt.expectedError, errResp.Error, errResp.Message)
+					if errResp.Code != tt.expectedError && !strings.Contains(errResp.Detail, tt.expectedError) {
+						t.Errorf("Expected error containing '%s', got Code='%s' Detail='%s'", tt.expectedError, errResp.Code, errResp.Detail)
 					}
 				}
 			}
@@ -459,6 +594,22 @@ func TestAuthHandler_AuthMiddleware(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedCalled: true,
 		},
+		{
+			name:       "Failure - Revoked session",
+			authHeader: "Bearer valid-token",
+			mockSetup: func(m *MockAuthService) {
+				m.ValidateTokenFunc = func(tokenString string) (*service.Claims, error) {
+					return &service.Claims{UserID: "user-123", RegisteredClaims: jwt.RegisteredClaims{ID: "session-1"}}, nil
+				}
+				m.CheckSessionFunc = func(ctx context.Context, claims *service.Claims) error {
+					if claims.ID != "session-1" {
+						t.Errorf("Expected session-1, got %q", claims.ID)
+					}
+					return service.ErrSessionRevoked
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
 	}
 
 	for _, tt := range tests {
@@ -491,3 +642,168 @@ func TestAuthHandler_AuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthHandler_RequireRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           interface{}
+		expectedStatus int
+	}{
+		{name: "Success - Admin allowed", role: "admin", expectedStatus: http.StatusOK},
+		{name: "Failure - User forbidden", role: "user", expectedStatus: http.StatusForbidden},
+		{name: "Failure - No role in context", role: nil, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{})
+			next := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/invitations", nil)
+			if tt.role != nil {
+				req = req.WithContext(context.WithValue(req.Context(), "user_role", tt.role))
+			}
+			w := httptest.NewRecorder()
+
+			handler.RequireRole(next, "admin")(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_JWKS(t *testing.T) {
+	mockSvc := &MockAuthService{
+		JWKSFunc: func() service.JWKS {
+			return service.JWKS{Keys: []service.JWK{{Kty: "RSA", Kid: "key-1", Use: "sig", Alg: "RS256", N: "abc", E: "AQAB"}}}
+		},
+	}
+	handler := NewAuthHandler(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.JWKS(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age") {
+		t.Errorf("Expected Cache-Control with max-age, got %q", cc)
+	}
+	var got service.JWKS
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(got.Keys) != 1 || got.Keys[0].Kid != "key-1" {
+		t.Errorf("Unexpected JWKS: %+v", got)
+	}
+}
+
+// Mock APIKeyAuthenticator
+type MockAPIKeyAuthenticator struct {
+	AuthenticateFunc func(ctx context.Context, rawKey string) (*repository.APIKey, error)
+}
+
+func (m *MockAPIKeyAuthenticator) Authenticate(ctx context.Context, rawKey string) (*repository.APIKey, error) {
+	if m.AuthenticateFunc != nil {
+		return m.AuthenticateFunc(ctx, rawKey)
+	}
+	return nil, service.ErrAPIKeyInvalid
+}
+
+func TestAuthHandler_AuthMiddleware_APIKey(t *testing.T) {
+	apiKeys := &MockAPIKeyAuthenticator{
+		AuthenticateFunc: func(ctx context.Context, rawKey string) (*repository.APIKey, error) {
+			switch rawKey {
+			case "jak_abc_good":
+				return &repository.APIKey{ID: "abc", Name: "nightly", Scopes: []string{service.ScopeClientsRead}}, nil
+			case "jak_abc_revoked":
+				return nil, service.ErrAPIKeyRevoked
+			default:
+				return nil, service.ErrAPIKeyInvalid
+			}
+		},
+	}
+
+	tests := []struct {
+		name            string
+		headers         map[string]string
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{name: "Success - X-API-Key header", headers: map[string]string{"X-API-Key": "jak_abc_good"}, expectedStatus: http.StatusOK},
+		{name: "Success - Bearer API key", headers: map[string]string{"Authorization": "Bearer jak_abc_good"}, expectedStatus: http.StatusOK},
+		{name: "Failure - Unknown key", headers: map[string]string{"X-API-Key": "jak_abc_bad"}, expectedStatus: http.StatusUnauthorized, expectedMessage: "invalid_api_key"},
+		{name: "Failure - Revoked key", headers: map[string]string{"Authorization": "Bearer jak_abc_revoked"}, expectedStatus: http.StatusUnauthorized, expectedMessage: "revoked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{}, WithAPIKeys(apiKeys))
+			var got *Principal
+			next := func(w http.ResponseWriter, r *http.Request) {
+				got, _ = PrincipalFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/clients", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			handler.AuthMiddleware(next)(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedMessage != "" && !strings.Contains(w.Body.String(), tt.expectedMessage) {
+				t.Errorf("Expected message containing %q, got %s", tt.expectedMessage, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK {
+				if got == nil || got.Type != PrincipalService || got.ID != "apikey:abc" {
+					t.Errorf("Unexpected principal: %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_RequireScope(t *testing.T) {
+	tests := []struct {
+		name           string
+		principal      *Principal
+		expectedStatus int
+	}{
+		{name: "Success - User passes", principal: &Principal{Type: PrincipalUser, ID: "user-1"}, expectedStatus: http.StatusOK},
+		{name: "Success - Key with scope", principal: &Principal{Type: PrincipalService, Scopes: []string{service.ScopeClientsWrite}}, expectedStatus: http.StatusOK},
+		{name: "Failure - Key without scope", principal: &Principal{Type: PrincipalService, Scopes: []string{service.ScopeClientsRead}}, expectedStatus: http.StatusForbidden},
+		{name: "Failure - No principal", principal: nil, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&MockAuthService{})
+			next := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/clients", nil)
+			if tt.principal != nil {
+				req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			handler.RequireScope(next, service.ScopeClientsWrite)(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
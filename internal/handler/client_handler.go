@@ -9,13 +9,12 @@ import (
 	"strings"
 
 	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
 	"github.com/jmason/john_ai_project/internal/service"
 )
 
 type ContextKey string
 
-const ClientIDKey ContextKey = "client_id"
-
 // ClientService interface for dependency injection
 type ClientService interface {
 	GetClientList(ctx context.Context) ([]repository.Client, error)
@@ -38,14 +37,9 @@ func NewClientHandler(service ClientService) *ClientHandler {
 }
 
 func (h *ClientHandler) GetClientList(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	clients, err := h.service.GetClientList(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -53,29 +47,15 @@ func (h *ClientHandler) GetClientList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ClientHandler) GetClientByID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var id string
-	if ctxID := r.Context().Value(ClientIDKey); ctxID != nil {
-		id = ctxID.(string)
-	} else {
-		path := r.URL.Path
-		if len(path) > len("/api/clients/") {
-			id = path[len("/api/clients/"):]
-		}
-	}
-
+	id := route.Param(r, "id")
 	if id == "" {
-		http.Error(w, "Client ID is required", http.StatusBadRequest)
+		RespondError(w, r, missingPathParam("id"))
 		return
 	}
 
 	client, err := h.service.GetClientByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -83,43 +63,20 @@ func (h *ClientHandler) GetClientByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ClientHandler) GetClientByEmail(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	email := strings.TrimSpace(r.URL.Query().Get("email"))
 	client, err := h.service.GetClientByEmail(r.Context(), email)
 	if err != nil {
-		switch err {
-		case service.ErrMissingEmail, service.ErrInvalidEmail:
-			RespondJSON(w, http.StatusBadRequest, ErrorResponse{
-				Error:   "Invalid email",
-				Message: err.Error(),
-			})
-			return
-		default:
-			if strings.Contains(err.Error(), "not found") {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+		RespondError(w, r, err)
+		return
 	}
 
 	RespondJSON(w, http.StatusOK, client)
 }
 
 func (h *ClientHandler) GetActiveClients(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	clients, err := h.service.GetActiveClients(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -127,14 +84,9 @@ func (h *ClientHandler) GetActiveClients(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *ClientHandler) GetInactiveClients(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	clients, err := h.service.GetInactiveClients(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -202,17 +154,9 @@ func (r *CreateClientRequest) UnmarshalJSON(data []byte) error {
 }
 
 func (h *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req CreateClientRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		RespondError(w, r, invalidBody(err))
 		return
 	}
 
@@ -234,33 +178,13 @@ func (h *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.CreateClient(r.Context(), client); err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == service.ErrMissingRequiredFields || err == service.ErrInvalidEmail {
-			statusCode = http.StatusBadRequest
-		}
-		if err == service.ErrEmailAlreadyExists {
-			statusCode = http.StatusConflict
-		}
-		RespondJSON(w, statusCode, ErrorResponse{
-			Error:   "Failed to create client",
-			Message: err.Error(),
-		})
+		RespondError(w, r, err)
 		return
 	}
 
 	RespondJSON(w, http.StatusCreated, client)
 }
 
-// ReservedClientPathID reports whether id is a fixed route segment under /api/clients/, not a client id.
-func ReservedClientPathID(id string) bool {
-	switch id {
-	case "active", "inactive", "add", "by-email":
-		return true
-	default:
-		return false
-	}
-}
-
 // UpdateClientRequest is a partial update: include only fields to change.
 // Notes is a single note object; it updates the first entry in the client's notes list (same as initial_note).
 // NotesList replaces the entire notes array (use [] to clear). If set, it takes precedence over initial_note/notes.
@@ -433,35 +357,15 @@ func extractCounsellorDisplay(raw json.RawMessage) string {
 }
 
 func (h *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut && r.Method != http.MethodPatch {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var id string
-	if ctxID := r.Context().Value(ClientIDKey); ctxID != nil {
-		id = ctxID.(string)
-	} else {
-		path := r.URL.Path
-		if len(path) > len("/api/clients/") {
-			id = path[len("/api/clients/"):]
-		}
-	}
-
-	if id == "" || ReservedClientPathID(id) {
-		RespondJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid client ID",
-			Message: "A valid client id is required in the URL path",
-		})
+	id := route.Param(r, "id")
+	if id == "" {
+		RespondError(w, r, missingPathParam("id"))
 		return
 	}
 
 	var req UpdateClientRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		RespondError(w, r, invalidBody(err))
 		return
 	}
 
@@ -485,27 +389,13 @@ func (h *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.UpdateClient(r.Context(), id, in); err != nil {
-		statusCode := http.StatusInternalServerError
-		switch err {
-		case service.ErrMissingClientID, service.ErrMissingRequiredFields, service.ErrInvalidEmail, service.ErrNoFieldsToUpdate:
-			statusCode = http.StatusBadRequest
-		}
-		if strings.Contains(err.Error(), "failed to load client") {
-			statusCode = http.StatusNotFound
-		}
-		RespondJSON(w, statusCode, ErrorResponse{
-			Error:   "Failed to update client",
-			Message: err.Error(),
-		})
+		RespondError(w, r, err)
 		return
 	}
 
 	updated, err := h.service.GetClientByID(r.Context(), id)
 	if err != nil {
-		RespondJSON(w, http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to load updated client",
-			Message: err.Error(),
-		})
+		RespondError(w, r, err)
 		return
 	}
 
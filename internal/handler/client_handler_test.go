@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
 	"github.com/jmason/john_ai_project/internal/service"
 )
 
@@ -165,11 +167,11 @@ func TestClientHandler_CreateClient(t *testing.T) {
 			},
 			mockSetup: func(m *MockClientService) {
 				m.CreateClientFunc = func(ctx context.Context, client *repository.Client) error {
-					return service.ErrMissingRequiredFields
+					return service.ErrClientInvalid.WithFields(map[string]string{"first_name": "is required"})
 				}
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "first_name, last_name, and email are required",
+			expectedError:  "invalid_client_fields",
 		},
 		{
 			name:   "Failure - Missing last_name (service validation)",
@@ -180,11 +182,11 @@ func TestClientHandler_CreateClient(t *testing.T) {
 			},
 			mockSetup: func(m *MockClientService) {
 				m.CreateClientFunc = func(ctx context.Context, client *repository.Client) error {
-					return service.ErrMissingRequiredFields
+					return service.ErrClientInvalid.WithFields(map[string]string{"last_name": "is required"})
 				}
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "first_name, last_name, and email are required",
+			expectedError:  "invalid_client_fields",
 		},
 		{
 			name:   "Failure - Missing email (service validation)",
@@ -195,11 +197,11 @@ func TestClientHandler_CreateClient(t *testing.T) {
 			},
 			mockSetup: func(m *MockClientService) {
 				m.CreateClientFunc = func(ctx context.Context, client *repository.Client) error {
-					return service.ErrMissingRequiredFields
+					return service.ErrClientInvalid.WithFields(map[string]string{"email": "is required"})
 				}
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "first_name, last_name, and email are required",
+			expectedError:  "invalid_client_fields",
 		},
 		{
 			name:   "Failure - Missing all required fields (service validation)",
@@ -209,11 +211,11 @@ func TestClientHandler_CreateClient(t *testing.T) {
 			},
 			mockSetup: func(m *MockClientService) {
 				m.CreateClientFunc = func(ctx context.Context, client *repository.Client) error {
-					return service.ErrMissingRequiredFields
+					return service.ErrClientInvalid.WithFields(map[string]string{"first_name": "is required", "last_name": "is required", "email": "is required"})
 				}
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "first_name, last_name, and email are required",
+			expectedError:  "invalid_client_fields",
 		},
 		{
 			name:           "Failure - Invalid JSON",
@@ -221,7 +223,7 @@ func TestClientHandler_CreateClient(t *testing.T) {
 			requestBody:    `{"invalid json`,
 			mockSetup:      func(m *MockClientService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid request body",
+			expectedError:  "invalid_body",
 		},
 		{
 			name:   "Failure - Service error",
@@ -237,18 +239,7 @@ func TestClientHandler_CreateClient(t *testing.T) {
 				}
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedError:  "Failed to create client",
-		},
-		{
-			name:   "Failure - Wrong HTTP method (GET)",
-			method: http.MethodGet,
-			requestBody: CreateClientRequest{
-				FirstName: "John",
-				LastName:  "Doe",
-				Email:     "john@example.com",
-			},
-			mockSetup:      func(m *MockClientService) {},
-			expectedStatus: http.StatusMethodNotAllowed,
+			expectedError:  "internal_error",
 		},
 		{
 			name:   "Success - Minimal required fields only",
@@ -348,17 +339,17 @@ func TestClientHandler_CreateClient(t *testing.T) {
 
 			// Assert error message if expected
 			if tt.expectedError != "" {
-				var errResp ErrorResponse
+				var errResp Problem
 				if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
-					// If we can't decode as ErrorResponse, check the body directly
+					// If we can't decode as a Problem, check the body directly
 					bodyStr := w.Body.String()
 					if !strings.Contains(bodyStr, tt.expectedError) {
 						t.Errorf("Expected error containing '%s', got body: %s", tt.expectedError, bodyStr)
 					}
 				} else {
-					if !strings.Contains(errResp.Message, tt.expectedError) && !strings.Contains(errResp.Error, tt.expectedError) {
-						t.Errorf("Expected error containing '%s', got Error='%s', Message='%s'",
-							tt.expectedError, errResp.Error, errResp.Message)
+					if errResp.Code != tt.expectedError && !strings.Contains(errResp.Detail, tt.expectedError) {
+						t.Errorf("Expected error containing '%s', got Code='%s', Detail='%s'",
+							tt.expectedError, errResp.Code, errResp.Detail)
 					}
 				}
 			}
@@ -403,7 +394,7 @@ func TestClientHandler_GetClientByEmail(t *testing.T) {
 			rawQuery: "email=no%40one.com",
 			mockSetup: func(m *MockClientService) {
 				m.GetClientByEmailFunc = func(ctx context.Context, email string) (*repository.Client, error) {
-					return nil, errors.New("failed to get client by email: client not found for email: no@one.com")
+					return nil, fmt.Errorf("failed to get client by email: %w for email: no@one.com", repository.ErrClientNotFound)
 				}
 			},
 			expectedStatus: http.StatusNotFound,
@@ -431,6 +422,56 @@ func TestClientHandler_GetClientByEmail(t *testing.T) {
 	}
 }
 
+// The mux, not the handlers, decides which methods a route accepts: HEAD is answered by the GET
+// handler, and other methods get 405 with Allow.
+func TestClientHandler_MethodsThroughMux(t *testing.T) {
+	mock := &MockClientService{
+		GetClientListFunc: func(ctx context.Context) ([]repository.Client, error) {
+			return []repository.Client{{ID: "c1", FirstName: "Ada"}}, nil
+		},
+		GetClientByIDFunc: func(ctx context.Context, id string) (*repository.Client, error) {
+			return &repository.Client{ID: id, FirstName: "Ada"}, nil
+		},
+	}
+	h := NewClientHandler(mock)
+	mux := route.New()
+	mux.MethodNotAllowed = MethodNotAllowed
+	clients := mux.Group("/api/clients")
+	clients.Get("", h.GetClientList)
+	clients.Post("/add", h.CreateClient)
+	clients.Get("/{id}", h.GetClientByID)
+	clients.Put("/{id}", h.UpdateClient)
+
+	tests := []struct {
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{method: http.MethodHead, path: "/api/clients", expectedStatus: http.StatusOK},
+		{method: http.MethodHead, path: "/api/clients/c1", expectedStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/api/clients/add", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "OPTIONS, POST"},
+		{method: http.MethodPatch, path: "/api/clients/c1", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "GET, HEAD, OPTIONS, PUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.expectedStatus)
+			}
+			if got := w.Header().Get("Allow"); got != tt.expectedAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.expectedAllow)
+			}
+			if tt.method == http.MethodHead && w.Body.Len() != 0 {
+				t.Errorf("Expected no body for HEAD, got %q", w.Body.String())
+			}
+		})
+	}
+}
+
 func TestClientJSON_responseIncludesExtendedFields(t *testing.T) {
 	c := repository.Client{
 		ID:                  "c1",
@@ -551,7 +592,7 @@ func TestClientHandler_UpdateClient(t *testing.T) {
 		h := NewClientHandler(mock)
 		req := httptest.NewRequest(http.MethodPut, "/api/clients/client-99", bytes.NewReader(bodyJSON))
 		req.Header.Set("Content-Type", "application/json")
-		req = req.WithContext(context.WithValue(req.Context(), ClientIDKey, "client-99"))
+		req = route.WithParams(req, map[string]string{"id": "client-99"})
 		w := httptest.NewRecorder()
 		h.UpdateClient(w, req)
 
@@ -575,7 +616,7 @@ func TestClientHandler_UpdateClient(t *testing.T) {
 		h := NewClientHandler(mock)
 		req := httptest.NewRequest(http.MethodPatch, "/api/clients/client-99", bytes.NewReader(bodyJSON))
 		req.Header.Set("Content-Type", "application/json")
-		req = req.WithContext(context.WithValue(req.Context(), ClientIDKey, "client-99"))
+		req = route.WithParams(req, map[string]string{"id": "client-99"})
 		w := httptest.NewRecorder()
 		h.UpdateClient(w, req)
 		if w.Code != http.StatusOK {
@@ -583,11 +624,10 @@ func TestClientHandler_UpdateClient(t *testing.T) {
 		}
 	})
 
-	t.Run("reserved path id", func(t *testing.T) {
+	t.Run("missing path id", func(t *testing.T) {
 		mock := &MockClientService{}
 		h := NewClientHandler(mock)
-		req := httptest.NewRequest(http.MethodPut, "/api/clients/active", bytes.NewReader(bodyJSON))
-		req = req.WithContext(context.WithValue(req.Context(), ClientIDKey, "active"))
+		req := httptest.NewRequest(http.MethodPut, "/api/clients/", bytes.NewReader(bodyJSON))
 		w := httptest.NewRecorder()
 		h.UpdateClient(w, req)
 		if w.Code != http.StatusBadRequest {
@@ -598,16 +638,67 @@ func TestClientHandler_UpdateClient(t *testing.T) {
 	t.Run("validation error", func(t *testing.T) {
 		mock := &MockClientService{
 			UpdateClientFunc: func(ctx context.Context, clientID string, in service.ClientUpdateInput) error {
-				return service.ErrInvalidEmail
+				return service.ErrClientInvalid.WithFields(map[string]string{"email": "must be a valid email address"})
 			},
 		}
 		h := NewClientHandler(mock)
 		req := httptest.NewRequest(http.MethodPut, "/api/clients/c1", bytes.NewReader(bodyJSON))
-		req = req.WithContext(context.WithValue(req.Context(), ClientIDKey, "c1"))
+		req = route.WithParams(req, map[string]string{"id": "c1"})
 		w := httptest.NewRecorder()
 		h.UpdateClient(w, req)
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("status = %d, want 400", w.Code)
 		}
 	})
+
+	t.Run("email already in use", func(t *testing.T) {
+		mock := &MockClientService{
+			UpdateClientFunc: func(ctx context.Context, clientID string, in service.ClientUpdateInput) error {
+				return service.ErrEmailAlreadyExists
+			},
+		}
+		h := NewClientHandler(mock)
+		req := httptest.NewRequest(http.MethodPut, "/api/clients/c1", bytes.NewReader([]byte(`{"email":"taken@example.com"}`)))
+		req = route.WithParams(req, map[string]string{"id": "c1"})
+		w := httptest.NewRecorder()
+		h.UpdateClient(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want 409", w.Code)
+		}
+	})
+}
+
+// stubClientRepository fails the test run with a nil dereference if validation lets a request
+// reach storage.
+type stubClientRepository struct {
+	service.ClientRepository
+}
+
+func TestCreateClient_reportsEveryInvalidField(t *testing.T) {
+	// The real service, so the response shows what the onboarding form receives.
+	h := NewClientHandler(service.NewClientService(&stubClientRepository{}))
+	body := `{"first_name":"","last_name":"Doe","email":"nope","phone":"12345","urgency":"soon"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/clients/add", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.CreateClient(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if p.Code != "invalid_client_fields" {
+		t.Errorf("code = %q, want invalid_client_fields", p.Code)
+	}
+	for _, field := range []string{"first_name", "email", "phone", "urgency"} {
+		if p.Fields[field] == "" {
+			t.Errorf("fields[%q] missing from %v", field, p.Fields)
+		}
+	}
+	if len(p.Fields) != 4 {
+		t.Errorf("fields = %v, want exactly 4 entries", p.Fields)
+	}
 }
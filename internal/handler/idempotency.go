@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// IdempotencyKeyHeader carries a client-chosen key that makes a POST, PUT or PATCH safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayHeader marks a response that was replayed from an earlier request.
+const idempotentReplayHeader = "Idempotent-Replayed"
+
+// maxIdempotentBodyBytes caps the request body read to fingerprint a keyed request.
+const maxIdempotentBodyBytes = 1 << 20
+
+// IdempotencyService interface for dependency injection
+type IdempotencyService interface {
+	Begin(ctx context.Context, req service.IdempotentRequest) (*repository.IdempotencyRecord, error)
+	Complete(ctx context.Context, callerID, key string, status int, header map[string][]string, body []byte) error
+	Release(ctx context.Context, callerID, key string) error
+}
+
+type IdempotencyHandler struct {
+	idempotencyService IdempotencyService
+}
+
+func NewIdempotencyHandler(idempotencyService IdempotencyService) *IdempotencyHandler {
+	return &IdempotencyHandler{
+		idempotencyService: idempotencyService,
+	}
+}
+
+// Middleware honours the Idempotency-Key header on authenticated writes. The first response to
+// a key is stored for the caller; a retry with the same key and payload gets that response back
+// unchanged, and reusing the key for a different payload is refused with 422. Server errors are
+// not stored, so the client can retry them, and neither are responses marked Cache-Control:
+// no-store, which carry credentials. It must run after AuthMiddleware.
+func (h *IdempotencyHandler) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !idempotentMethod(r.Method) {
+			next(w, r)
+			return
+		}
+
+		p, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			RespondError(w, r, errNotAuthenticated)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			RespondError(w, r, invalidBody(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		callerID := p.Type + ":" + p.ID
+		record, err := h.idempotencyService.Begin(r.Context(), service.IdempotentRequest{
+			CallerID: callerID,
+			Key:      key,
+			Method:   r.Method,
+			Path:     r.URL.RequestURI(),
+			Body:     body,
+		})
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+		if record != nil {
+			replayResponse(w, record)
+			return
+		}
+
+		// The outcome is stored even if the client has gone away, so its retry can be answered.
+		storeCtx := context.WithoutCancel(r.Context())
+		before := w.Header().Clone()
+		rec := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if !completed {
+				if err := h.idempotencyService.Release(storeCtx, callerID, key); err != nil {
+					httpLog.ErrorContext(storeCtx, "failed to release idempotency key", "caller", callerID, logger.Error(err))
+				}
+			}
+		}()
+
+		next(rec, r)
+
+		if rec.status >= http.StatusInternalServerError || noStore(rec.Header()) {
+			return
+		}
+		header := handlerHeader(before, rec.Header())
+		if err := h.idempotencyService.Complete(storeCtx, callerID, key, rec.status, header, rec.body.Bytes()); err != nil {
+			if !errors.Is(err, repository.ErrIdempotencyRecordNotFound) {
+				httpLog.ErrorContext(storeCtx, "failed to store idempotent response", "caller", callerID, logger.Error(err))
+			}
+			return
+		}
+		completed = true
+	}
+}
+
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// unstoredHeaders are not replayed: hop-by-hop headers belong to the original connection and
+// Content-Length is recomputed.
+var unstoredHeaders = map[string]bool{
+	"Connection":          true,
+	"Content-Length":      true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// handlerHeader returns the headers the handler set or changed. Headers already present before
+// it ran, such as X-Request-ID and CORS, come from outer middleware and are set afresh on a
+// replay.
+func handlerHeader(before, after http.Header) map[string][]string {
+	header := map[string][]string{}
+	for name, values := range after {
+		if unstoredHeaders[name] || slices.Equal(before[name], values) {
+			continue
+		}
+		header[name] = slices.Clone(values)
+	}
+	return header
+}
+
+func noStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+func replayResponse(w http.ResponseWriter, record *repository.IdempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = slices.Clone(values)
+	}
+	if record.ContentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(record.Body)))
+	w.Header().Set(idempotentReplayHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// recordingResponseWriter passes a response through while keeping a copy to store.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// memoryIdempotencyRepository keeps idempotency records in a map for middleware tests.
+type memoryIdempotencyRepository struct {
+	records map[string]*repository.IdempotencyRecord
+}
+
+func (m *memoryIdempotencyRepository) ReserveIdempotencyKey(ctx context.Context, record *repository.IdempotencyRecord, now int64) error {
+	if _, ok := m.records[record.ID]; ok {
+		return repository.ErrIdempotencyKeyExists
+	}
+	cp := *record
+	m.records[record.ID] = &cp
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) GetIdempotencyRecord(ctx context.Context, id string) (*repository.IdempotencyRecord, error) {
+	record, ok := m.records[id]
+	if !ok {
+		return nil, repository.ErrIdempotencyRecordNotFound
+	}
+	cp := *record
+	return &cp, nil
+}
+
+func (m *memoryIdempotencyRepository) CompleteIdempotencyRecord(ctx context.Context, id string, status int, header map[string][]string, body []byte) error {
+	record, ok := m.records[id]
+	if !ok {
+		return repository.ErrIdempotencyRecordNotFound
+	}
+	record.Status, record.Header, record.Body = status, header, body
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) DeleteIdempotencyRecord(ctx context.Context, id string) error {
+	delete(m.records, id)
+	return nil
+}
+
+func TestIdempotencyHandler_Middleware(t *testing.T) {
+	user := &Principal{Type: PrincipalUser, ID: "user-1", Name: "jane", Role: "user"}
+
+	setup := func(status int) (http.HandlerFunc, *int) {
+		calls := 0
+		svc := service.NewIdempotencyService(&memoryIdempotencyRepository{records: map[string]*repository.IdempotencyRecord{}}, time.Hour)
+		next := func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			RespondJSON(w, status, map[string]any{"name": body["name"], "call": calls})
+		}
+		return NewIdempotencyHandler(svc).Middleware(next), &calls
+	}
+	send := func(h http.HandlerFunc, method, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/clients/add", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		h(w, withTestPrincipal(req, user))
+		return w
+	}
+
+	t.Run("Replay returns the stored response without running the handler", func(t *testing.T) {
+		h, calls := setup(http.StatusCreated)
+
+		first := send(h, http.MethodPost, "key-1", `{"name":"Ada"}`)
+		second := send(h, http.MethodPost, "key-1", `{"name":"Ada"}`)
+
+		if *calls != 1 {
+			t.Fatalf("Expected the handler to run once, ran %d times", *calls)
+		}
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("Expected the identical response, got %d %s want %d %s", second.Code, second.Body.String(), first.Code, first.Body.String())
+		}
+		if second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+			t.Errorf("Expected the stored content type, got %q", second.Header().Get("Content-Type"))
+		}
+		if second.Header().Get(idempotentReplayHeader) != "true" || first.Header().Get(idempotentReplayHeader) != "" {
+			t.Errorf("Expected only the replay to be marked")
+		}
+	})
+
+	t.Run("Different payload with the same key is 422", func(t *testing.T) {
+		h, calls := setup(http.StatusCreated)
+		send(h, http.MethodPost, "key-1", `{"name":"Ada"}`)
+
+		w := send(h, http.MethodPost, "key-1", `{"name":"Grace"}`)
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"code":"idempotency_key_reused"`) {
+			t.Errorf("Expected 422 idempotency_key_reused, got %d %s", w.Code, w.Body.String())
+		}
+		if *calls != 1 {
+			t.Errorf("Expected the handler not to run again, ran %d times", *calls)
+		}
+	})
+
+	t.Run("Server errors are not stored", func(t *testing.T) {
+		h, calls := setup(http.StatusInternalServerError)
+		send(h, http.MethodPost, "key-1", `{"name":"Ada"}`)
+		send(h, http.MethodPost, "key-1", `{"name":"Ada"}`)
+
+		if *calls != 2 {
+			t.Errorf("Expected the retry to run the handler again, ran %d times", *calls)
+		}
+	})
+
+	t.Run("Requests without a key or with a safe method pass through", func(t *testing.T) {
+		h, calls := setup(http.StatusOK)
+		send(h, http.MethodPost, "", `{"name":"Ada"}`)
+		send(h, http.MethodPost, "", `{"name":"Ada"}`)
+		send(h, http.MethodDelete, "key-1", "")
+		send(h, http.MethodDelete, "key-1", "")
+
+		if *calls != 4 {
+			t.Errorf("Expected every request to run the handler, ran %d times", *calls)
+		}
+	})
+
+	t.Run("Replay restores the handler's headers but not hop-by-hop or outer ones", func(t *testing.T) {
+		svc := service.NewIdempotencyService(&memoryIdempotencyRepository{records: map[string]*repository.IdempotencyRecord{}}, time.Hour)
+		h := NewIdempotencyHandler(svc).Middleware(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "/api/clients/c-1")
+			w.Header().Set("Connection", "close")
+			RespondJSON(w, http.StatusCreated, map[string]string{"id": "c-1"})
+		})
+		sendWithRequestID := func(id string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/api/clients/add", strings.NewReader(`{}`))
+			req.Header.Set(IdempotencyKeyHeader, "key-1")
+			w := httptest.NewRecorder()
+			w.Header().Set("X-Request-ID", id)
+			h(w, withTestPrincipal(req, user))
+			return w
+		}
+
+		sendWithRequestID("req-1")
+		w := sendWithRequestID("req-2")
+
+		if w.Header().Get(idempotentReplayHeader) != "true" {
+			t.Fatalf("Expected a replay, got headers %v", w.Header())
+		}
+		if got := w.Header().Get("Location"); got != "/api/clients/c-1" {
+			t.Errorf("Expected the stored Location, got %q", got)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("Expected the stored Content-Type, got %q", got)
+		}
+		if got := w.Header().Get("Connection"); got != "" {
+			t.Errorf("Expected hop-by-hop headers not to be replayed, got Connection %q", got)
+		}
+		if got := w.Header().Get("X-Request-ID"); got != "req-2" {
+			t.Errorf("Expected the retry's own request ID, got %q", got)
+		}
+	})
+
+	t.Run("No-store responses are not stored", func(t *testing.T) {
+		calls := 0
+		svc := service.NewIdempotencyService(&memoryIdempotencyRepository{records: map[string]*repository.IdempotencyRecord{}}, time.Hour)
+		h := NewIdempotencyHandler(svc).Middleware(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Cache-Control", "private, no-store")
+			RespondJSON(w, http.StatusCreated, map[string]string{"key": "secret"})
+		})
+		send(h, http.MethodPost, "key-1", `{}`)
+		w := send(h, http.MethodPost, "key-1", `{}`)
+
+		if calls != 2 || w.Header().Get(idempotentReplayHeader) != "" {
+			t.Errorf("Expected the credential response not to be replayed, ran %d times", calls)
+		}
+	})
+
+	t.Run("Malformed key is 400", func(t *testing.T) {
+		h, calls := setup(http.StatusCreated)
+		w := send(h, http.MethodPost, strings.Repeat("k", service.MaxIdempotencyKeyLength+1), `{}`)
+
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"code":"invalid_idempotency_key"`) || *calls != 0 {
+			t.Errorf("Expected 400 invalid_idempotency_key, got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
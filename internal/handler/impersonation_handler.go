@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// errImpersonationReadOnly rejects writes made with an impersonation token while writes are off.
+var errImpersonationReadOnly = apperror.Forbidden("impersonation_read_only", "impersonation sessions are read-only")
+
+// ImpersonationResponse returns a token that acts as User on behalf of Actor until ExpiresAt.
+type ImpersonationResponse struct {
+	Token     string           `json:"token"`
+	User      *repository.User `json:"user"`
+	Actor     service.Actor    `json:"actor"`
+	ExpiresAt string           `json:"expires_at"`
+	ReadOnly  bool             `json:"read_only"`
+}
+
+// Impersonate lets an admin see the API as another user. The user id comes from the URL path.
+func (h *AuthHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok || p.Type != PrincipalUser || p.Impersonated() {
+		RespondError(w, r, service.ErrImpersonationForbidden)
+		return
+	}
+
+	targetID := route.Param(r, "id")
+	if targetID == "" {
+		RespondError(w, r, missingPathParam("id"))
+		return
+	}
+
+	token, user, expiresAt, err := h.authService.Impersonate(r.Context(), p.ID, targetID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	// no-store keeps the impersonation token out of caches and the idempotency store.
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusOK, ImpersonationResponse{
+		Token:     token,
+		User:      user,
+		Actor:     service.Actor{UserID: p.ID, Username: p.Name},
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+		ReadOnly:  !h.impersonationWrites,
+	})
+}
+
+// allowImpersonated applies the impersonation rules to a request made with an impersonation
+// token: writes are refused unless enabled, and are always audited with both identities. The
+// X-Impersonated-By header lets the frontend show that the session is not the user's own.
+func (h *AuthHandler) allowImpersonated(w http.ResponseWriter, r *http.Request, p *Principal) bool {
+	w.Header().Set("X-Impersonated-By", p.ActorName)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	if !h.impersonationWrites {
+		auditLog.WarnContext(r.Context(), "impersonated write blocked",
+			"method", r.Method, "actor_id", p.ActorID, "actor", p.ActorName, "target", p.Name)
+		RespondError(w, r, errImpersonationReadOnly)
+		return false
+	}
+
+	auditLog.InfoContext(r.Context(), "impersonated write",
+		"method", r.Method, "actor_id", p.ActorID, "actor", p.ActorName, "target", p.Name)
+	return true
+}
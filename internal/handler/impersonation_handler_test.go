@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+func TestAuthHandler_Impersonate(t *testing.T) {
+	admin := &Principal{Type: PrincipalUser, ID: "admin-1", Name: "root", Role: "admin"}
+
+	tests := []struct {
+		name           string
+		principal      *Principal
+		targetID       string
+		err            error
+		expectedStatus int
+	}{
+		{name: "Success", principal: admin, targetID: "user-1", expectedStatus: http.StatusOK},
+		{name: "Failure - API key", principal: &Principal{Type: PrincipalService, ID: "apikey:abc"}, targetID: "user-1", expectedStatus: http.StatusForbidden},
+		{name: "Failure - Already impersonating", principal: &Principal{Type: PrincipalUser, ID: "user-2", ActorID: "admin-1"}, targetID: "user-1", expectedStatus: http.StatusForbidden},
+		{name: "Failure - Missing user id", principal: admin, targetID: "", expectedStatus: http.StatusBadRequest},
+		{name: "Failure - Not found", principal: admin, targetID: "user-1", err: service.ErrImpersonationNotFound, expectedStatus: http.StatusNotFound},
+		{name: "Failure - Self", principal: admin, targetID: "admin-1", err: service.ErrImpersonateSelf, expectedStatus: http.StatusBadRequest},
+		{name: "Failure - Admin target", principal: admin, targetID: "admin-2", err: service.ErrImpersonateAdmin, expectedStatus: http.StatusForbidden},
+		{name: "Failure - Disabled target", principal: admin, targetID: "user-1", err: service.ErrAccountDisabled, expectedStatus: http.StatusForbidden},
+		{name: "Failure - Internal error", principal: admin, targetID: "user-1", err: errors.New("boom"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := time.Now().Add(service.ImpersonationTTL)
+			mockService := &MockAuthService{
+				ImpersonateFunc: func(ctx context.Context, actorID, targetUserID string) (string, *repository.User, time.Time, error) {
+					if tt.err != nil {
+						return "", nil, time.Time{}, tt.err
+					}
+					return "impersonation-token", &repository.User{ID: targetUserID, Username: "jane"}, expiresAt, nil
+				},
+			}
+			handler := NewAuthHandler(mockService)
+
+			req := withTestPrincipal(httptest.NewRequest(http.MethodPost, "/api/auth/impersonate/"+tt.targetID, nil), tt.principal)
+			req = route.WithParams(req, map[string]string{"id": tt.targetID})
+			w := httptest.NewRecorder()
+
+			handler.Impersonate(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var resp ImpersonationResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.Token != "impersonation-token" || resp.User.ID != "user-1" {
+					t.Errorf("Unexpected response: %+v", resp)
+				}
+				if resp.Actor.UserID != "admin-1" || !resp.ReadOnly {
+					t.Errorf("Expected read-only token acting for admin-1, got %+v", resp)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_AuthMiddleware_Impersonation(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		allowWrites    bool
+		expectedStatus int
+	}{
+		{name: "Success - Read", method: http.MethodGet, expectedStatus: http.StatusOK},
+		{name: "Failure - Write blocked", method: http.MethodPost, expectedStatus: http.StatusForbidden},
+		{name: "Failure - Delete blocked", method: http.MethodDelete, expectedStatus: http.StatusForbidden},
+		{name: "Success - Write allowed", method: http.MethodPut, allowWrites: true, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockAuthService{
+				ValidateTokenFunc: func(tokenString string) (*service.Claims, error) {
+					return &service.Claims{
+						UserID:   "user-1",
+						Username: "jane",
+						Role:     "user",
+						Actor:    &service.Actor{UserID: "admin-1", Username: "root"},
+					}, nil
+				},
+			}
+			handler := NewAuthHandler(mockService, WithImpersonationWrites(tt.allowWrites))
+
+			var got *Principal
+			next := func(w http.ResponseWriter, r *http.Request) {
+				got, _ = PrincipalFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest(tt.method, "/api/clients", nil)
+			req.Header.Set("Authorization", "Bearer impersonation-token")
+			w := httptest.NewRecorder()
+
+			handler.AuthMiddleware(next)(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Header().Get("X-Impersonated-By") != "root" {
+				t.Errorf("Expected X-Impersonated-By root, got %q", w.Header().Get("X-Impersonated-By"))
+			}
+			if tt.expectedStatus == http.StatusOK {
+				if got == nil || got.ID != "user-1" || got.ActorID != "admin-1" || !got.Impersonated() {
+					t.Errorf("Unexpected principal: %+v", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+)
+
+// InvitationService interface for dependency injection
+type InvitationService interface {
+	CreateInvitation(ctx context.Context, invitedBy, email, role string) (*repository.Invitation, string, error)
+	ListInvitations(ctx context.Context) ([]repository.Invitation, error)
+	RevokeInvitation(ctx context.Context, id string) error
+}
+
+type InvitationHandler struct {
+	service InvitationService
+}
+
+func NewInvitationHandler(service InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		service: service,
+	}
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// InvitationResponse returns the created invitation together with its token, so an admin can
+// share the link directly if mail delivery is not set up.
+type InvitationResponse struct {
+	Invitation *repository.Invitation `json:"invitation"`
+	Token      string                 `json:"token"`
+}
+
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var req CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, r, invalidBody(err))
+		return
+	}
+
+	invitedBy, _ := r.Context().Value("user_id").(string)
+	inv, token, err := h.service.CreateInvitation(r.Context(), invitedBy, req.Email, req.Role)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	// no-store keeps the invitation token out of caches and the idempotency store.
+	w.Header().Set("Cache-Control", "no-store")
+	RespondJSON(w, http.StatusCreated, InvitationResponse{
+		Invitation: inv,
+		Token:      token,
+	})
+}
+
+func (h *InvitationHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.service.ListInvitations(r.Context())
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, invitations)
+}
+
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(r, "id")
+	if id == "" {
+		RespondError(w, r, missingPathParam("id"))
+		return
+	}
+
+	if err := h.service.RevokeInvitation(r.Context(), id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
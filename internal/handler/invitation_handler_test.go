@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/route"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// Mock InvitationService
+type MockInvitationService struct {
+	CreateInvitationFunc func(ctx context.Context, invitedBy, email, role string) (*repository.Invitation, string, error)
+	ListInvitationsFunc  func(ctx context.Context) ([]repository.Invitation, error)
+	RevokeInvitationFunc func(ctx context.Context, id string) error
+}
+
+func (m *MockInvitationService) CreateInvitation(ctx context.Context, invitedBy, email, role string) (*repository.Invitation, string, error) {
+	if m.CreateInvitationFunc != nil {
+		return m.CreateInvitationFunc(ctx, invitedBy, email, role)
+	}
+	return nil, "", nil
+}
+
+func (m *MockInvitationService) ListInvitations(ctx context.Context) ([]repository.Invitation, error) {
+	if m.ListInvitationsFunc != nil {
+		return m.ListInvitationsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockInvitationService) RevokeInvitation(ctx context.Context, id string) error {
+	if m.RevokeInvitationFunc != nil {
+		return m.RevokeInvitationFunc(ctx, id)
+	}
+	return nil
+}
+
+func TestInvitationHandler_CreateInvitation(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		mockSetup      func(*MockInvitationService)
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:        "Success - Invitation created by caller",
+			requestBody: `{"email":"alice@example.com","role":"counsellor"}`,
+			mockSetup: func(m *MockInvitationService) {
+				m.CreateInvitationFunc = func(ctx context.Context, invitedBy, email, role string) (*repository.Invitation, string, error) {
+					if invitedBy != "admin-1" {
+						t.Errorf("Expected invitedBy admin-1, got %q", invitedBy)
+					}
+					return &repository.Invitation{ID: "invite-1", Email: email, Role: role, Status: "pending"}, "token-1", nil
+				}
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Failure - Invalid JSON",
+			requestBody:    `{"email":`,
+			mockSetup:      func(m *MockInvitationService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_body",
+		},
+		{
+			name:        "Failure - Invalid role",
+			requestBody: `{"email":"alice@example.com","role":"root"}`,
+			mockSetup: func(m *MockInvitationService) {
+				m.CreateInvitationFunc = func(ctx context.Context, invitedBy, email, role string) (*repository.Invitation, string, error) {
+					return nil, "", service.ErrInvalidRole
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "role must be one of",
+		},
+		{
+			name:        "Failure - User exists",
+			requestBody: `{"email":"existing@example.com","role":"staff"}`,
+			mockSetup: func(m *MockInvitationService) {
+				m.CreateInvitationFunc = func(ctx context.Context, invitedBy, email, role string) (*repository.Invitation, string, error) {
+					return nil, "", service.ErrUserExists
+				}
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  "user with this email already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockInvitationService{}
+			tt.mockSetup(mockSvc)
+
+			handler := NewInvitationHandler(mockSvc)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/invitations", bytes.NewReader([]byte(tt.requestBody)))
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", "admin-1"))
+			w := httptest.NewRecorder()
+
+			handler.CreateInvitation(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedError != "" {
+				var errResp Problem
+				if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if errResp.Code != tt.expectedError && !strings.Contains(errResp.Detail, tt.expectedError) {
+					t.Errorf("Expected error containing '%s', got Code='%s' Detail='%s'", tt.expectedError, errResp.Code, errResp.Detail)
+				}
+				return
+			}
+
+			var resp InvitationResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Token != "token-1" || resp.Invitation == nil || resp.Invitation.ID != "invite-1" {
+				t.Errorf("Unexpected response: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestInvitationHandler_RevokeInvitation(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		revokeErr      error
+		expectedStatus int
+	}{
+		{name: "Success - Revoked", id: "invite-1", expectedStatus: http.StatusNoContent},
+		{name: "Failure - Missing id", id: "", expectedStatus: http.StatusBadRequest},
+		{name: "Failure - Already used", id: "invite-1", revokeErr: service.ErrInvitationUsed, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockInvitationService{
+				RevokeInvitationFunc: func(ctx context.Context, id string) error {
+					return tt.revokeErr
+				},
+			}
+			handler := NewInvitationHandler(mockSvc)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/invitations/"+tt.id, nil)
+			if tt.id != "" {
+				req = route.WithParams(req, map[string]string{"id": tt.id})
+			}
+			w := httptest.NewRecorder()
+
+			handler.RevokeInvitation(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
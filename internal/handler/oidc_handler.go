@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// oidcFlowCookie holds the signed SSO flow between the login redirect and the callback.
+const oidcFlowCookie = "oidc_flow"
+
+// errSSOUnavailable is returned when the login redirect cannot be built, usually because the
+// identity provider's discovery document could not be fetched.
+var errSSOUnavailable = apperror.Upstream("sso_unavailable", "identity provider unavailable")
+
+// OIDCService interface for dependency injection
+type OIDCService interface {
+	BeginLogin(ctx context.Context) (string, string, error)
+	CompleteLogin(ctx context.Context, flow, state, code string) (string, *repository.User, error)
+}
+
+type OIDCHandler struct {
+	service      OIDCService
+	secureCookie bool
+	// cookiePath scopes the flow cookie to the SSO routes as the browser sees them, which may
+	// be under a prefix such as an API Gateway stage.
+	cookiePath string
+	// frontendURL is the page the callback sends the browser to; empty answers with JSON.
+	frontendURL string
+}
+
+// OIDCHandlerOption configures optional OIDCHandler behaviour.
+type OIDCHandlerOption func(*OIDCHandler)
+
+// WithFlowCookiePath sets the path of the flow cookie, by default "/". It must cover both
+// the login and callback routes.
+func WithFlowCookiePath(path string) OIDCHandlerOption {
+	return func(h *OIDCHandler) {
+		h.cookiePath = path
+	}
+}
+
+// WithFrontendRedirect makes the callback redirect the browser to frontendURL with the token
+// in the fragment, #token=..., or the problem code as #error=... when the login fails. The
+// fragment never reaches a server, so the token stays out of access logs and Referer headers.
+func WithFrontendRedirect(frontendURL string) OIDCHandlerOption {
+	return func(h *OIDCHandler) {
+		h.frontendURL = frontendURL
+	}
+}
+
+// NewOIDCHandler creates the SSO handler. secureCookie should only be false for local HTTP.
+func NewOIDCHandler(service OIDCService, secureCookie bool, opts ...OIDCHandlerOption) *OIDCHandler {
+	h := &OIDCHandler{
+		service:      service,
+		secureCookie: secureCookie,
+		cookiePath:   "/",
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Login redirects the browser to the identity provider.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, flow, err := h.service.BeginLogin(r.Context())
+	if err != nil {
+		h.fail(w, r, fmt.Errorf("%w: %v", errSSOUnavailable, err))
+		return
+	}
+
+	h.setFlowCookie(w, flow, int(service.OIDCFlowTTL.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback completes the login the identity provider redirected back with. It redirects to the
+// frontend when one is configured, and otherwise returns the same token response as password
+// login.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	// The flow is single-use whatever the outcome.
+	h.setFlowCookie(w, "", -1)
+
+	if idpErr := q.Get("error"); idpErr != "" {
+		message := idpErr
+		if desc := q.Get("error_description"); desc != "" {
+			message += ": " + desc
+		}
+		h.fail(w, r, apperror.Unauthorized("sso_provider_error", "identity provider returned an error: "+message))
+		return
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil || q.Get("code") == "" {
+		h.fail(w, r, service.ErrOIDCLoginExpired)
+		return
+	}
+
+	ctx := service.WithSessionMetadata(r.Context(), sessionMetadata(r))
+	token, user, err := h.service.CompleteLogin(ctx, cookie.Value, q.Get("state"), q.Get("code"))
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if h.frontendURL != "" {
+		h.redirectToFrontend(w, r, url.Values{"token": {token}})
+		return
+	}
+	RespondJSON(w, http.StatusOK, AuthResponse{
+		Token: token,
+		User:  user,
+	})
+}
+
+// fail reports a failed login to the frontend, or as a problem response without one.
+func (h *OIDCHandler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if h.frontendURL == "" {
+		RespondError(w, r, err)
+		return
+	}
+	p := ProblemFor(err)
+	if p.Status == http.StatusInternalServerError {
+		httpLog.ErrorContext(r.Context(), "sso login failed", logger.Error(err))
+	}
+	h.redirectToFrontend(w, r, url.Values{"error": {p.Code}})
+}
+
+func (h *OIDCHandler) redirectToFrontend(w http.ResponseWriter, r *http.Request, fragment url.Values) {
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, h.frontendURL+"#"+fragment.Encode(), http.StatusFound)
+}
+
+func (h *OIDCHandler) setFlowCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     h.cookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// Mock OIDCService
+type MockOIDCService struct {
+	BeginLoginFunc    func(ctx context.Context) (string, string, error)
+	CompleteLoginFunc func(ctx context.Context, flow, state, code string) (string, *repository.User, error)
+}
+
+func (m *MockOIDCService) BeginLogin(ctx context.Context) (string, string, error) {
+	if m.BeginLoginFunc != nil {
+		return m.BeginLoginFunc(ctx)
+	}
+	return "", "", nil
+}
+
+func (m *MockOIDCService) CompleteLogin(ctx context.Context, flow, state, code string) (string, *repository.User, error) {
+	if m.CompleteLoginFunc != nil {
+		return m.CompleteLoginFunc(ctx, flow, state, code)
+	}
+	return "", nil, nil
+}
+
+func TestOIDCHandler_Login(t *testing.T) {
+	mockSvc := &MockOIDCService{
+		BeginLoginFunc: func(ctx context.Context) (string, string, error) {
+			return "https://idp.example.com/authorize?state=abc", "flow-token", nil
+		},
+	}
+	handler := NewOIDCHandler(mockSvc, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status 302, got %d", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "https://idp.example.com/authorize?state=abc" {
+		t.Errorf("Unexpected redirect %q", loc)
+	}
+	cookie := w.Result().Cookies()[0]
+	if cookie.Name != oidcFlowCookie || cookie.Value != "flow-token" || !cookie.HttpOnly || !cookie.Secure {
+		t.Errorf("Unexpected flow cookie: %+v", cookie)
+	}
+}
+
+func TestOIDCHandler_Callback(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		cookie         bool
+		completeErr    error
+		expectedStatus int
+	}{
+		{name: "Success - Token issued", query: "?code=c&state=s", cookie: true, expectedStatus: http.StatusOK},
+		{name: "Failure - Provider error", query: "?error=access_denied&error_description=cancelled", cookie: true, expectedStatus: http.StatusUnauthorized},
+		{name: "Failure - Missing flow cookie", query: "?code=c&state=s", cookie: false, expectedStatus: http.StatusBadRequest},
+		{name: "Failure - State mismatch", query: "?code=c&state=s", cookie: true, completeErr: service.ErrOIDCStateMismatch, expectedStatus: http.StatusBadRequest},
+		{name: "Failure - Invalid ID token", query: "?code=c&state=s", cookie: true, completeErr: service.ErrOIDCInvalidIDToken, expectedStatus: http.StatusUnauthorized},
+		{name: "Failure - No mapped role", query: "?code=c&state=s", cookie: true, completeErr: service.ErrOIDCNoRole, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockOIDCService{
+				CompleteLoginFunc: func(ctx context.Context, flow, state, code string) (string, *repository.User, error) {
+					if tt.completeErr != nil {
+						return "", nil, tt.completeErr
+					}
+					if flow != "flow-token" || state != "s" || code != "c" {
+						t.Errorf("Unexpected callback values: flow=%q state=%q code=%q", flow, state, code)
+					}
+					return "app-token", &repository.User{ID: "user-1", Username: "casey", Role: "counsellor"}, nil
+				},
+			}
+			handler := NewOIDCHandler(mockSvc, true)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback"+tt.query, nil)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: oidcFlowCookie, Value: "flow-token"})
+			}
+			w := httptest.NewRecorder()
+
+			handler.Callback(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Header().Get("Set-Cookie"), "Max-Age=0") {
+				t.Error("Expected the flow cookie to be cleared")
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var resp AuthResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.Token != "app-token" {
+					t.Errorf("Expected token app-token, got %q", resp.Token)
+				}
+			}
+		})
+	}
+}
+
+func TestOIDCHandler_FrontendRedirect(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		completeErr  error
+		wantLocation string
+	}{
+		{name: "Success - Token in fragment", query: "?code=c&state=s", wantLocation: "https://app.example.com/sso#token=app-token"},
+		{name: "Failure - Error code in fragment", query: "?code=c&state=s", completeErr: service.ErrOIDCNoRole, wantLocation: "https://app.example.com/sso#error=sso_no_role"},
+		{name: "Failure - Provider error", query: "?error=access_denied", wantLocation: "https://app.example.com/sso#error=sso_provider_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &MockOIDCService{
+				CompleteLoginFunc: func(ctx context.Context, flow, state, code string) (string, *repository.User, error) {
+					if tt.completeErr != nil {
+						return "", nil, tt.completeErr
+					}
+					return "app-token", &repository.User{ID: "user-1"}, nil
+				},
+			}
+			handler := NewOIDCHandler(mockSvc, true, WithFrontendRedirect("https://app.example.com/sso"))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: oidcFlowCookie, Value: "flow-token"})
+			w := httptest.NewRecorder()
+
+			handler.Callback(w, req)
+
+			if w.Code != http.StatusFound {
+				t.Fatalf("Expected status 302, got %d", w.Code)
+			}
+			if loc := w.Header().Get("Location"); loc != tt.wantLocation {
+				t.Errorf("Expected redirect to %q, got %q", tt.wantLocation, loc)
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Error("Expected the redirect not to be cached")
+			}
+		})
+	}
+}
+
+func TestOIDCHandler_FlowCookiePath(t *testing.T) {
+	mockSvc := &MockOIDCService{
+		BeginLoginFunc: func(ctx context.Context) (string, string, error) {
+			return "https://idp.example.com/authorize", "flow-token", nil
+		},
+	}
+
+	for path, opts := range map[string][]OIDCHandlerOption{
+		"/":                   nil,
+		"/prod/api/auth/oidc": {WithFlowCookiePath("/prod/api/auth/oidc")},
+	} {
+		w := httptest.NewRecorder()
+		NewOIDCHandler(mockSvc, true, opts...).Login(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+		if got := w.Result().Cookies()[0].Path; got != path {
+			t.Errorf("Expected cookie path %q, got %q", path, got)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/jmason/john_ai_project/internal/openapi"
+)
+
+// OpenAPIHandler serves the OpenAPI description of the API and a docs page that renders it.
+type OpenAPIHandler struct {
+	// Document is built from the registered routes, so the router sets it after registering them.
+	Document *openapi.Document
+}
+
+func NewOpenAPIHandler() *OpenAPIHandler {
+	return &OpenAPIHandler{}
+}
+
+// Spec returns the OpenAPI 3 document as JSON.
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	if h.Document == nil {
+		RespondProblem(w, r, Problem{
+			Status: http.StatusServiceUnavailable,
+			Code:   "api_description_unavailable",
+			Detail: "The OpenAPI document has not been built",
+		})
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, h.Document)
+}
+
+// Docs serves the bundled API docs page, which loads the document from /openapi.json.
+func (h *OpenAPIHandler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.DocsPage)
+}
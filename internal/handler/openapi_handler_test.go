@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmason/john_ai_project/internal/openapi"
+	"github.com/jmason/john_ai_project/internal/route"
+)
+
+func TestOpenAPIHandler_Spec(t *testing.T) {
+	spec := openapi.Spec{
+		Info:  openapi.Info{Title: "Test API", Version: "1"},
+		Error: Problem{},
+		Endpoints: []openapi.Endpoint{
+			{Method: http.MethodPost, Pattern: "/api/clients/add", Summary: "Create a client", Request: CreateClientRequest{}, Status: http.StatusCreated, Response: AuthResponse{}, Errors: []int{http.StatusBadRequest}},
+		},
+	}
+	doc, err := spec.Build([]route.Route{{Method: http.MethodPost, Pattern: "/api/clients/add"}})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		document       *openapi.Document
+		expectedStatus int
+	}{
+		{name: "Success", document: doc, expectedStatus: http.StatusOK},
+		{name: "Failure - Not built", document: nil, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewOpenAPIHandler()
+			handler.Document = tt.document
+
+			req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+			w := httptest.NewRecorder()
+
+			handler.Spec(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var got struct {
+				OpenAPI    string                                `json:"openapi"`
+				Paths      map[string]map[string]json.RawMessage `json:"paths"`
+				Components struct {
+					Schemas map[string]struct {
+						Properties map[string]json.RawMessage `json:"properties"`
+					} `json:"schemas"`
+				} `json:"components"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if got.OpenAPI != openapi.Version {
+				t.Errorf("Expected openapi %q, got %q", openapi.Version, got.OpenAPI)
+			}
+			if _, ok := got.Paths["/api/clients/add"]["post"]; !ok {
+				t.Errorf("Expected POST /api/clients/add in paths, got %v", got.Paths)
+			}
+			if _, ok := got.Components.Schemas["CreateClientRequest"].Properties["first_name"]; !ok {
+				t.Error("Expected CreateClientRequest schema with first_name")
+			}
+			if _, ok := got.Components.Schemas["User"].Properties["password_hash"]; ok {
+				t.Error("Fields tagged json:\"-\" must not be documented")
+			}
+		})
+	}
+}
+
+func TestOpenAPIHandler_Docs(t *testing.T) {
+	handler := NewOpenAPIHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	w := httptest.NewRecorder()
+
+	handler.Docs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected HTML, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Error("Expected the docs page to load /openapi.json")
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jmason/john_ai_project/internal/logger"
+)
+
+// Principal types
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+)
+
+const PrincipalKey ContextKey = "principal"
+
+// Principal is the authenticated caller: a human user (JWT) or a service holding an API key.
+type Principal struct {
+	Type   string
+	ID     string
+	Name   string
+	Role   string
+	Scopes []string
+	// SessionID is the login session of a user token; empty for API keys and legacy tokens.
+	SessionID string
+	// ActorID and ActorName identify the admin behind an impersonation token. The fields above
+	// then describe the impersonated user.
+	ActorID   string
+	ActorName string
+}
+
+// Impersonated reports whether an admin is acting as this user.
+func (p *Principal) Impersonated() bool {
+	return p.ActorID != ""
+}
+
+// HasScope reports whether the principal may act within scope. Users are governed by roles,
+// not scopes, so every scope is granted to them; services only get the scopes on their key.
+func (p *Principal) HasScope(scope string) bool {
+	if p.Type == PrincipalUser {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFromContext returns the principal stored by AuthMiddleware.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(PrincipalKey).(*Principal)
+	return p, ok
+}
+
+// withPrincipal stores p in ctx, along with the legacy user_* keys read by existing handlers,
+// and adds the user id to the request's log attributes.
+func withPrincipal(ctx context.Context, p *Principal, email string) context.Context {
+	logger.AddAttrs(ctx, slog.String(logger.KeyUserID, p.ID))
+	ctx = context.WithValue(ctx, PrincipalKey, p)
+	ctx = context.WithValue(ctx, "user_id", p.ID)
+	ctx = context.WithValue(ctx, "user_email", email)
+	ctx = context.WithValue(ctx, "user_username", p.Name)
+	ctx = context.WithValue(ctx, "user_role", p.Role)
+	return ctx
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/requestid"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// Loggers for request handling and for writes made while impersonating.
+var (
+	httpLog  = logger.Component("http")
+	auditLog = logger.Component("audit")
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// Codes for errors raised by the HTTP layer itself rather than a service.
+const (
+	CodeInvalidBody      = "invalid_body"
+	CodeMissingPathParam = "missing_path_param"
+	CodeInvalidParam     = "invalid_param"
+	CodeRouteNotFound    = "route_not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details body. Code is a stable machine-readable identifier
+// (such as "client_not_found") that clients branch on; Detail is for people and may change.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Fields maps request fields to what is wrong with them, for validation errors.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Violations lists every password rule a rejected password failed.
+	Violations []service.PasswordViolation `json:"violations,omitempty"`
+	// RequestID matches the X-Request-ID response header and the server log lines for the request.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+var kindStatus = map[apperror.Kind]int{
+	apperror.KindValidation:     http.StatusBadRequest,
+	apperror.KindUnauthorized:   http.StatusUnauthorized,
+	apperror.KindForbidden:      http.StatusForbidden,
+	apperror.KindNotFound:       http.StatusNotFound,
+	apperror.KindConflict:       http.StatusConflict,
+	apperror.KindUnprocessable:  http.StatusUnprocessableEntity,
+	apperror.KindNotImplemented: http.StatusNotImplemented,
+	apperror.KindUpstream:       http.StatusBadGateway,
+}
+
+// ProblemFor maps err to a problem. Typed errors from apperror keep their code and message;
+// anything else is a 500 whose details stay in the server log.
+func ProblemFor(err error) Problem {
+	appErr, ok := apperror.As(err)
+	if !ok || appErr.Kind == apperror.KindInternal {
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: "An unexpected error occurred",
+			Code:   CodeInternal,
+		}
+	}
+
+	status := kindStatus[appErr.Kind]
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+		Code:   appErr.Code,
+		Fields: appErr.Fields,
+	}
+	var policyErr *service.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		p.Violations = policyErr.Violations
+	}
+	return p
+}
+
+// RespondError writes err as a problem+json response. Unexpected errors are logged, since the
+// client only sees a generic message.
+func RespondError(w http.ResponseWriter, r *http.Request, err error) {
+	p := ProblemFor(err)
+	if p.Status == http.StatusInternalServerError {
+		httpLog.ErrorContext(r.Context(), "request failed", "method", r.Method, logger.Error(err))
+	}
+	RespondProblem(w, r, p)
+}
+
+// RespondProblem writes p, filling in the instance from the request path and the request ID
+// from the context.
+func RespondProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = requestid.FromContext(r.Context())
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		httpLog.WarnContext(r.Context(), "failed to encode problem", logger.Error(err))
+	}
+}
+
+// invalidBody is the error for a request body that is not valid JSON for the endpoint.
+func invalidBody(err error) error {
+	return apperror.Validation(CodeInvalidBody, "invalid request body: "+err.Error())
+}
+
+// missingPathParam is the error for an empty path parameter such as {id}.
+func missingPathParam(name string) error {
+	return apperror.Validation(CodeMissingPathParam, name+" is required in the URL path")
+}
+
+// MethodNotAllowed answers a request whose method the route does not accept. The router sets
+// the Allow header before calling it.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	RespondProblem(w, r, Problem{
+		Status: http.StatusMethodNotAllowed,
+		Code:   CodeMethodNotAllowed,
+		Detail: r.Method + " is not supported for this path",
+	})
+}
+
+// RouteNotFound answers a request for a path that matches no route.
+func RouteNotFound(w http.ResponseWriter, r *http.Request) {
+	RespondProblem(w, r, Problem{
+		Status: http.StatusNotFound,
+		Code:   CodeRouteNotFound,
+		Detail: "No endpoint matches " + r.URL.Path,
+	})
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/requestid"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+func TestRespondError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedDetail string
+		validate       func(t *testing.T, p Problem)
+	}{
+		{
+			name:           "Not found wrapped by the service",
+			err:            fmt.Errorf("failed to get client: %w", fmt.Errorf("%w: c-1", repository.ErrClientNotFound)),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "client_not_found",
+			expectedDetail: "failed to get client: client not found: c-1",
+		},
+		{
+			name:           "Conflict",
+			err:            service.ErrEmailAlreadyExists,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "client_email_exists",
+		},
+		{
+			name:           "Validation with fields",
+			err:            apperror.Validation("invalid_param", "limit must be a positive integer").WithFields(map[string]string{"limit": "must be a positive integer"}),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_param",
+			validate: func(t *testing.T, p Problem) {
+				if p.Fields["limit"] != "must be a positive integer" {
+					t.Errorf("Expected field detail for limit, got %v", p.Fields)
+				}
+			},
+		},
+		{
+			name: "Password policy violations",
+			err: &service.PasswordPolicyError{Violations: []service.PasswordViolation{
+				{Rule: service.PasswordRuleMinLength, Message: "password must be at least 8 characters long"},
+			}},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_password",
+			validate: func(t *testing.T, p Problem) {
+				if len(p.Violations) != 1 || p.Violations[0].Rule != service.PasswordRuleMinLength {
+					t.Errorf("Expected the min length violation, got %+v", p.Violations)
+				}
+				if p.Fields["password"] == "" {
+					t.Errorf("Expected a password field detail, got %v", p.Fields)
+				}
+			},
+		},
+		{name: "Forbidden", err: service.ErrAccountDisabled, expectedStatus: http.StatusForbidden, expectedCode: "account_disabled"},
+		{name: "Unauthorized", err: service.ErrInvalidCredentials, expectedStatus: http.StatusUnauthorized, expectedCode: "invalid_credentials"},
+		{name: "Not implemented", err: service.ErrSessionsDisabled, expectedStatus: http.StatusNotImplemented, expectedCode: "sessions_disabled"},
+		{
+			name:           "Unexpected errors hide their details",
+			err:            errors.New("dial tcp 10.0.0.1:443: connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+			expectedDetail: "An unexpected error occurred",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/clients/c-1", nil)
+			w := httptest.NewRecorder()
+
+			RespondError(w, req, tt.err)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("Expected content type %q, got %q", ProblemContentType, ct)
+			}
+			var p Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("Failed to decode problem: %v", err)
+			}
+			if p.Code != tt.expectedCode {
+				t.Errorf("Expected code %q, got %q", tt.expectedCode, p.Code)
+			}
+			if p.Status != tt.expectedStatus || p.Title != http.StatusText(tt.expectedStatus) || p.Type != "about:blank" {
+				t.Errorf("Unexpected problem members: %+v", p)
+			}
+			if p.Instance != "/api/clients/c-1" {
+				t.Errorf("Expected instance to be the request path, got %q", p.Instance)
+			}
+			if tt.expectedDetail != "" && p.Detail != tt.expectedDetail {
+				t.Errorf("Expected detail %q, got %q", tt.expectedDetail, p.Detail)
+			}
+			if tt.validate != nil {
+				tt.validate(t, p)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowedAndRouteNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	MethodNotAllowed(w, httptest.NewRequest(http.MethodDelete, "/api/clients", nil))
+	if w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Body.String(), `"code":"method_not_allowed"`) {
+		t.Errorf("Unexpected 405 response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	RouteNotFound(w, httptest.NewRequest(http.MethodGet, "/api/nope", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"code":"route_not_found"`) {
+		t.Errorf("Unexpected 404 response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRespondError_includesRequestID(t *testing.T) {
+	h := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RespondError(w, r, service.ErrInvalidCredentials)
+	}))
+
+	t.Run("Client request ID is echoed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.Header.Set(requestid.Header, "req-abc-123")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		var p Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		if w.Header().Get(requestid.Header) != "req-abc-123" || p.RequestID != "req-abc-123" {
+			t.Errorf("Expected request id req-abc-123 in header and body, got %q and %q", w.Header().Get(requestid.Header), p.RequestID)
+		}
+	})
+
+	t.Run("Missing or unsafe request ID is generated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.Header.Set(requestid.Header, "bad id\nwith newline")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		var p Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		id := w.Header().Get(requestid.Header)
+		if id == "" || id == "bad id\nwith newline" || p.RequestID != id {
+			t.Errorf("Expected a generated request id in header and body, got %q and %q", id, p.RequestID)
+		}
+	})
+}
+
+func TestRespondError_logsWithRequestAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger.Configure(logger.Options{Levels: logger.Levels{Default: slog.LevelInfo}}, logger.NewJSONHandler(&buf))
+	defer logger.Configure(logger.Options{Levels: logger.Levels{Default: slog.LevelInfo}}, logger.NewJSONHandler(os.Stderr))
+
+	h := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := logger.NewContext(r.Context())
+		ctx = withPrincipal(ctx, &Principal{Type: PrincipalUser, ID: "user-1"}, "")
+		RespondError(w, r.WithContext(ctx), errors.New("boom"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/clients", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON log record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{"level": "ERROR", "component": "http", "request_id": "req-1", "user_id": "user-1", "error": "boom"}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("Expected %s=%v, got %v in %v", k, v, record[k], record)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// CodeRateLimited is the problem code of a 429 response.
+const CodeRateLimited = "rate_limited"
+
+// Rate limit response headers, as in the IETF RateLimit header fields draft.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// RateLimiter interface for dependency injection
+type RateLimiter interface {
+	Allow(ctx context.Context, group, caller string, limit service.RateLimit) (service.RateLimitDecision, error)
+}
+
+type RateLimitHandler struct {
+	limiter RateLimiter
+	// proxyHops is how many proxies in front of the server append to X-Forwarded-For.
+	proxyHops int
+}
+
+// NewRateLimitHandler limits requests with limiter. proxyHops is the number of trusted proxies,
+// such as a load balancer, that append the caller's address to X-Forwarded-For; with 0 the
+// connection's remote address is used.
+func NewRateLimitHandler(limiter RateLimiter, proxyHops int) *RateLimitHandler {
+	return &RateLimitHandler{
+		limiter:   limiter,
+		proxyHops: proxyHops,
+	}
+}
+
+// Limit returns middleware that applies limit to each caller of the routes in group: the
+// authenticated user or API key when the request has a principal, otherwise the client IP.
+// Every limited response carries RateLimit-* headers, and refused requests get 429 with
+// Retry-After. If the limiter fails the request is let through, so an outage of the rate limit
+// store does not take the API down. To key by user, it must run after AuthMiddleware.
+func (h *RateLimitHandler) Limit(group string, limit service.RateLimit) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		if limit.Unlimited() {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			decision, err := h.limiter.Allow(r.Context(), group, h.caller(r), limit)
+			if err != nil {
+				httpLog.WarnContext(r.Context(), "rate limiter unavailable, allowing request", "group", group, logger.Error(err))
+				next(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set(RateLimitLimitHeader, strconv.Itoa(limit.Requests))
+			header.Set(RateLimitRemainingHeader, strconv.Itoa(decision.Remaining))
+			header.Set(RateLimitResetHeader, seconds(decision.Reset))
+			header.Set(RateLimitPolicyHeader, strconv.Itoa(limit.Requests)+";w="+seconds(limit.Period))
+			if !decision.Allowed {
+				header.Set("Retry-After", seconds(decision.RetryAfter))
+				RespondProblem(w, r, Problem{
+					Status: http.StatusTooManyRequests,
+					Code:   CodeRateLimited,
+					Detail: "Too many requests; retry after " + seconds(decision.RetryAfter) + " seconds",
+				})
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// caller identifies who a request counts against, e.g. "user:u-1", "service:key-1" or
+// "ip:203.0.113.7".
+func (h *RateLimitHandler) caller(r *http.Request) string {
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		return p.Type + ":" + p.ID
+	}
+	return "ip:" + clientIP(r, h.proxyHops)
+}
+
+// clientIP is the address proxyHops proxies back along X-Forwarded-For. Each trusted proxy
+// appends the address it received the request from, so entries further left may be forged by
+// the client and are not used.
+func clientIP(r *http.Request, proxyHops int) string {
+	if proxyHops > 0 {
+		var hops []string
+		for _, hop := range strings.Split(r.Header.Get("X-Forwarded-For"), ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+		if len(hops) > 0 {
+			return hops[max(len(hops)-proxyHops, 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds formats d as whole seconds, rounded up so clients never retry too early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
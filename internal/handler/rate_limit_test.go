@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+func TestRateLimitHandler_Limit(t *testing.T) {
+	limit := service.RateLimit{Requests: 2, Period: time.Minute}
+	h := NewRateLimitHandler(service.NewRateLimiter(repository.NewMemoryRateLimitStore()), 0)
+	limited := h.Limit("clients", limit)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(remoteAddr string, p *Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/clients", nil)
+		req.RemoteAddr = remoteAddr
+		if p != nil {
+			req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, p))
+		}
+		rec := httptest.NewRecorder()
+		limited(rec, req)
+		return rec
+	}
+
+	user := &Principal{Type: PrincipalUser, ID: "user-1"}
+	for want := 1; want >= 0; want-- {
+		rec := request("203.0.113.7:5000", user)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the burst to be allowed, got %d", rec.Code)
+		}
+		if got := rec.Header().Get(RateLimitRemainingHeader); got != strconv.Itoa(want) {
+			t.Errorf("Expected RateLimit-Remaining %d, got %q", want, got)
+		}
+	}
+
+	rec := request("203.0.113.7:5000", user)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the bucket is empty, got %d", rec.Code)
+	}
+	for header, want := range map[string]string{
+		RateLimitLimitHeader:     "2",
+		RateLimitRemainingHeader: "0",
+		RateLimitResetHeader:     "60",
+		RateLimitPolicyHeader:    "2;w=60",
+		"Retry-After":            "30",
+		"Content-Type":           ProblemContentType,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("Expected %s %q, got %q", header, want, got)
+		}
+	}
+	var problem Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil || problem.Code != CodeRateLimited {
+		t.Errorf("Expected a rate_limited problem, got %+v, %v", problem, err)
+	}
+
+	if rec := request("203.0.113.7:5000", &Principal{Type: PrincipalService, ID: "user-1"}); rec.Code != http.StatusOK {
+		t.Errorf("Expected an API key with the same id to have its own bucket, got %d", rec.Code)
+	}
+	if rec := request("203.0.113.7:5000", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected an anonymous caller to be limited by IP separately, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitHandler_IgnoresForgedForwardedFor(t *testing.T) {
+	h := NewRateLimitHandler(service.NewRateLimiter(repository.NewMemoryRateLimitStore()), 0)
+	limited := h.Limit("public", service.RateLimit{Requests: 1, Period: time.Minute})(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	codes := make([]int, 0, 2)
+	for _, forged := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		req.Header.Set("X-Forwarded-For", forged)
+		rec := httptest.NewRecorder()
+		limited(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Expected a new X-Forwarded-For not to reset the bucket, got %v", codes)
+	}
+}
+
+// failingRateLimiter stands in for an unreachable rate limit store.
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Allow(ctx context.Context, group, caller string, limit service.RateLimit) (service.RateLimitDecision, error) {
+	return service.RateLimitDecision{}, errors.New("dynamodb unavailable")
+}
+
+func TestRateLimitHandler_FailsOpen(t *testing.T) {
+	called := false
+	limited := NewRateLimitHandler(failingRateLimiter{}, 0).Limit("clients", service.RateLimit{Requests: 1, Period: time.Second})(
+		func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	rec := httptest.NewRecorder()
+	limited(rec, httptest.NewRequest(http.MethodGet, "/api/clients", nil))
+
+	if !called || rec.Header().Get(RateLimitLimitHeader) != "" {
+		t.Errorf("Expected the request to be served without rate limit headers, called=%v headers=%v", called, rec.Header())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded string
+		hops      int
+		want      string
+	}{
+		{name: "No proxy uses the connection", forwarded: "", hops: 0, want: "192.0.2.10"},
+		{name: "Direct client forging the header", forwarded: "198.51.100.1, 198.51.100.2", hops: 0, want: "192.0.2.10"},
+		{name: "One proxy", forwarded: "198.51.100.1", hops: 1, want: "198.51.100.1"},
+		{name: "Forged entries are skipped", forwarded: "10.0.0.1, 198.51.100.1", hops: 1, want: "198.51.100.1"},
+		{name: "Two proxies", forwarded: "10.0.0.1, 198.51.100.1, 172.16.0.5", hops: 2, want: "198.51.100.1"},
+		{name: "Fewer entries than hops", forwarded: "198.51.100.1", hops: 3, want: "198.51.100.1"},
+		{name: "No header", forwarded: "", hops: 1, want: "192.0.2.10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/login", nil)
+			req.RemoteAddr = "192.0.2.10:41000"
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientIP(req, tt.hops); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
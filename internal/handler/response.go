@@ -19,8 +19,3 @@ type HealthResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
 }
-
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
-}
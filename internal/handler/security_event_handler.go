@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// SecurityEventService interface for dependency injection
+type SecurityEventService interface {
+	ListUserSecurityEvents(ctx context.Context, userID string, limit int) ([]repository.SecurityEvent, error)
+	ListSecurityEvents(ctx context.Context, query service.SecurityEventQuery) ([]repository.SecurityEvent, error)
+}
+
+// SecurityEventHandler serves login history and other security events.
+type SecurityEventHandler struct {
+	service SecurityEventService
+}
+
+func NewSecurityEventHandler(service SecurityEventService) *SecurityEventHandler {
+	return &SecurityEventHandler{
+		service: service,
+	}
+}
+
+// ListMine returns the signed-in user's own events. ?limit= caps how many are returned.
+func (h *SecurityEventHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	limit, ok := limitParam(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := h.service.ListUserSecurityEvents(r.Context(), p.ID, limit)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, events)
+}
+
+// List returns events across all users, filtered by the user_id, type, since, until and limit
+// query parameters.
+func (h *SecurityEventHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, ok := limitParam(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	events, err := h.service.ListSecurityEvents(r.Context(), service.SecurityEventQuery{
+		UserID: q.Get("user_id"),
+		Type:   q.Get("type"),
+		Since:  q.Get("since"),
+		Until:  q.Get("until"),
+		Limit:  limit,
+	})
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, events)
+}
+
+func limitParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		RespondError(w, r, apperror.Validation(CodeInvalidParam, "limit must be a positive integer").
+			WithFields(map[string]string{"limit": "must be a positive integer"}))
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// Mock SecurityEventService
+type MockSecurityEventService struct {
+	ListUserSecurityEventsFunc func(ctx context.Context, userID string, limit int) ([]repository.SecurityEvent, error)
+	ListSecurityEventsFunc     func(ctx context.Context, query service.SecurityEventQuery) ([]repository.SecurityEvent, error)
+}
+
+func (m *MockSecurityEventService) ListUserSecurityEvents(ctx context.Context, userID string, limit int) ([]repository.SecurityEvent, error) {
+	if m.ListUserSecurityEventsFunc != nil {
+		return m.ListUserSecurityEventsFunc(ctx, userID, limit)
+	}
+	return []repository.SecurityEvent{}, nil
+}
+
+func (m *MockSecurityEventService) ListSecurityEvents(ctx context.Context, query service.SecurityEventQuery) ([]repository.SecurityEvent, error) {
+	if m.ListSecurityEventsFunc != nil {
+		return m.ListSecurityEventsFunc(ctx, query)
+	}
+	return []repository.SecurityEvent{}, nil
+}
+
+func TestSecurityEventHandler_ListMine(t *testing.T) {
+	user := &Principal{Type: PrincipalUser, ID: "user-1"}
+
+	tests := []struct {
+		name           string
+		principal      *Principal
+		url            string
+		err            error
+		wantLimit      int
+		expectedStatus int
+	}{
+		{name: "Success", principal: user, url: "/api/auth/me/security-events", expectedStatus: http.StatusOK},
+		{name: "Success - Limit", principal: user, url: "/api/auth/me/security-events?limit=5", wantLimit: 5, expectedStatus: http.StatusOK},
+		{name: "Failure - Bad limit", principal: user, url: "/api/auth/me/security-events?limit=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Failure - API key", principal: &Principal{Type: PrincipalService, ID: "apikey:abc"}, url: "/api/auth/me/security-events", expectedStatus: http.StatusForbidden},
+		{name: "Failure - Disabled", principal: user, url: "/api/auth/me/security-events", err: service.ErrSecurityEventsDisabled, expectedStatus: http.StatusNotImplemented},
+		{name: "Failure - Internal error", principal: user, url: "/api/auth/me/security-events", err: errors.New("boom"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSecurityEventService{
+				ListUserSecurityEventsFunc: func(ctx context.Context, userID string, limit int) ([]repository.SecurityEvent, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					if userID != "user-1" || limit != tt.wantLimit {
+						return nil, fmt.Errorf("unexpected arguments %q, %d", userID, limit)
+					}
+					return []repository.SecurityEvent{{ID: "evt-1", UserID: userID, Type: service.SecurityEventLoginSucceeded}}, nil
+				},
+			}
+			handler := NewSecurityEventHandler(mockService)
+
+			req := withTestPrincipal(httptest.NewRequest(http.MethodGet, tt.url, nil), tt.principal)
+			w := httptest.NewRecorder()
+
+			handler.ListMine(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var events []repository.SecurityEvent
+				if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if len(events) != 1 || events[0].ID != "evt-1" {
+					t.Errorf("Unexpected events: %+v", events)
+				}
+			}
+		})
+	}
+}
+
+func TestSecurityEventHandler_List(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		err            error
+		wantQuery      service.SecurityEventQuery
+		expectedStatus int
+	}{
+		{
+			name:           "Success - No filter",
+			url:            "/api/security-events",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Success - All filters",
+			url:            "/api/security-events?user_id=user-1&type=login_failed&since=2026-01-01T00:00:00Z&until=2026-02-01T00:00:00Z&limit=20",
+			wantQuery:      service.SecurityEventQuery{UserID: "user-1", Type: "login_failed", Since: "2026-01-01T00:00:00Z", Until: "2026-02-01T00:00:00Z", Limit: 20},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Failure - Invalid filter",
+			url:            "/api/security-events?type=logout",
+			err:            service.ErrSecurityEventFilter,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Failure - Negative limit",
+			url:            "/api/security-events?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got service.SecurityEventQuery
+			mockService := &MockSecurityEventService{
+				ListSecurityEventsFunc: func(ctx context.Context, query service.SecurityEventQuery) ([]repository.SecurityEvent, error) {
+					got = query
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return []repository.SecurityEvent{}, nil
+				},
+			}
+			handler := NewSecurityEventHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.List(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK && got != tt.wantQuery {
+				t.Errorf("Expected query %+v, got %+v", tt.wantQuery, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package health runs the readiness checks behind GET /readyz. Each check reports ok, degraded
+// or unavailable; the server is ready unless a check is unavailable. Reports are cached for a
+// short time so frequent probes do not load the dependencies they check.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses, from best to worst.
+const (
+	StatusOK          = "ok"
+	StatusDisabled    = "disabled"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Status string `json:"status"`
+	// Detail explains a status other than ok.
+	Detail string `json:"detail,omitempty"`
+	// Info carries check-specific figures, such as events dropped by the CloudWatch sink.
+	Info       map[string]interface{} `json:"info,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+}
+
+// Report is the outcome of every check. Status is StatusUnavailable if any check is, otherwise
+// StatusDegraded if any check is, otherwise StatusOK.
+type Report struct {
+	Status    string                 `json:"status"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// Ready reports whether the server can take traffic.
+func (r Report) Ready() bool {
+	return r.Status != StatusUnavailable
+}
+
+// CheckFunc checks one dependency. It should return promptly once ctx ends.
+type CheckFunc func(ctx context.Context) CheckResult
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs checks concurrently and caches the report.
+type Checker struct {
+	checks  []check
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	// mu is held while checks run, so concurrent probes share one run.
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewChecker returns a Checker that reuses a report for ttl and gives each run of the checks
+// timeout to finish.
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout, now: time.Now}
+}
+
+// Add registers a check under name, e.g. "dynamodb". Add must not be called once Check has been.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Check returns the cached report if it is younger than the ttl, and otherwise runs every check.
+// Checks run with a context detached from ctx's cancellation, so a probe that gives up does not
+// leave a failed report cached for the next one.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.report != nil && c.now().Sub(c.report.CheckedAt) < c.ttl {
+		return *c.report
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+			start := time.Now()
+			results[i] = chk.fn(ctx)
+			results[i].DurationMS = time.Since(start).Milliseconds()
+		}(i, chk)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, CheckedAt: c.now(), Checks: make(map[string]CheckResult, len(c.checks))}
+	for i, chk := range c.checks {
+		report.Checks[chk.name] = results[i]
+		switch results[i].Status {
+		case StatusUnavailable:
+			report.Status = StatusUnavailable
+		case StatusDegraded:
+			if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+	c.report = &report
+	return report
+}
+
+// OK is a passing result.
+func OK() CheckResult {
+	return CheckResult{Status: StatusOK}
+}
+
+// Unavailable is a failing result explained by err.
+func Unavailable(err error) CheckResult {
+	return CheckResult{Status: StatusUnavailable, Detail: err.Error()}
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker_Status(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []CheckResult
+		want    string
+	}{
+		{name: "All ok", results: []CheckResult{OK(), {Status: StatusDisabled}}, want: StatusOK},
+		{name: "Degraded", results: []CheckResult{OK(), {Status: StatusDegraded}}, want: StatusDegraded},
+		{name: "Unavailable wins", results: []CheckResult{{Status: StatusDegraded}, Unavailable(errors.New("down")), OK()}, want: StatusUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(0, time.Second)
+			for i, res := range tt.results {
+				res := res
+				c.Add(string(rune('a'+i)), func(context.Context) CheckResult { return res })
+			}
+
+			report := c.Check(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("Expected status %q, got %q", tt.want, report.Status)
+			}
+			if report.Ready() != (tt.want != StatusUnavailable) {
+				t.Errorf("Unexpected Ready() %v for status %q", report.Ready(), report.Status)
+			}
+			if len(report.Checks) != len(tt.results) {
+				t.Errorf("Expected %d checks in the report, got %d", len(tt.results), len(report.Checks))
+			}
+		})
+	}
+}
+
+func TestChecker_CachesReport(t *testing.T) {
+	var runs atomic.Int32
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewChecker(5*time.Second, time.Second)
+	c.now = func() time.Time { return now }
+	c.Add("dynamodb", func(context.Context) CheckResult {
+		runs.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return OK()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(context.Background())
+		}()
+	}
+	wg.Wait()
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected concurrent probes to share one run, got %d", got)
+	}
+
+	now = now.Add(4 * time.Second)
+	c.Check(context.Background())
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected the cached report within the ttl, got %d runs", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	c.Check(context.Background())
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Expected the checks to run again after the ttl, got %d runs", got)
+	}
+}
+
+func TestChecker_IgnoresCallerCancellation(t *testing.T) {
+	c := NewChecker(time.Minute, 50*time.Millisecond)
+	c.Add("slow", func(ctx context.Context) CheckResult {
+		<-ctx.Done()
+		return Unavailable(ctx.Err())
+	})
+	c.Add("fast", func(ctx context.Context) CheckResult {
+		if ctx.Err() != nil {
+			return Unavailable(ctx.Err())
+		}
+		return OK()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := c.Check(ctx)
+
+	if report.Checks["fast"].Status != StatusOK {
+		t.Errorf("Expected a cancelled probe not to fail the checks, got %+v", report.Checks["fast"])
+	}
+	if slow := report.Checks["slow"]; slow.Status != StatusUnavailable || slow.Detail != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected the slow check to hit the timeout, got %+v", slow)
+	}
+}
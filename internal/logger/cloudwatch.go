@@ -2,24 +2,434 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// PutLogEvents limits: a batch holds at most 10,000 events and 1,048,576 bytes, counting 26
+// bytes of overhead per event, and one event is at most 256 KB.
+const (
+	cwMaxBatchEvents = 10000
+	cwMaxBatchBytes  = 1048576
+	cwEventOverhead  = 26
+	cwMaxEventBytes  = 262144 - cwEventOverhead
+)
+
+// CloudWatchOptions tune buffering and delivery. Zero fields take the defaults below.
+type CloudWatchOptions struct {
+	// BufferSize is the number of events held in memory waiting to be sent. When it is full,
+	// new events are dropped rather than blocking the request that logged them.
+	BufferSize int
+	// BatchSize is the number of events that triggers a send before FlushInterval.
+	BatchSize int
+	// FlushInterval is the longest an event waits before it is sent.
+	FlushInterval time.Duration
+	// MaxRetries is how many times a failed batch is resent before it is dropped. A negative
+	// value disables retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry; it doubles on each further retry.
+	RetryBackoff time.Duration
+}
+
+// Default CloudWatchOptions.
+const (
+	DefaultCloudWatchBufferSize    = 10000
+	DefaultCloudWatchBatchSize     = 500
+	DefaultCloudWatchFlushInterval = 5 * time.Second
+	DefaultCloudWatchMaxRetries    = 5
+	DefaultCloudWatchRetryBackoff  = 200 * time.Millisecond
 )
 
-type CloudWatchLogger struct {
-	logGroupName  string
-	logStreamName string
+func (o CloudWatchOptions) withDefaults() CloudWatchOptions {
+	if o.BufferSize <= 0 {
+		o.BufferSize = DefaultCloudWatchBufferSize
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultCloudWatchBatchSize
+	}
+	if o.BatchSize > cwMaxBatchEvents {
+		o.BatchSize = cwMaxBatchEvents
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DefaultCloudWatchFlushInterval
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	} else if o.MaxRetries == 0 {
+		o.MaxRetries = DefaultCloudWatchMaxRetries
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = DefaultCloudWatchRetryBackoff
+	}
+	return o
+}
+
+// CloudWatchHandler is a slog handler that ships records as JSON to a CloudWatch Logs stream.
+// Records are queued in a bounded buffer and sent in batches by a background goroutine, so
+// logging never waits on the network. Call Close on shutdown to send what is still buffered.
+type CloudWatchHandler struct {
+	next    slog.Handler
+	shipper *cloudWatchShipper
+}
+
+// NewCloudWatchHandler starts shipping records to logStreamName in logGroupName through api.
+// The stream (and the group, if missing) is created on the first send.
+func NewCloudWatchHandler(api CloudWatchLogsAPI, logGroupName, logStreamName string, opts CloudWatchOptions) *CloudWatchHandler {
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &cloudWatchShipper{
+		api:     api,
+		group:   logGroupName,
+		stream:  logStreamName,
+		opts:    opts,
+		events:  make(chan types.InputLogEvent, opts.BufferSize),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		errLog:  os.Stderr,
+	}
+	go s.run()
+	return &CloudWatchHandler{next: NewJSONHandler(s), shipper: s}
+}
+
+// LogGroupName returns the log group records are shipped to.
+func (h *CloudWatchHandler) LogGroupName() string {
+	return h.shipper.group
+}
+
+// LogStreamName returns the log stream records are shipped to.
+func (h *CloudWatchHandler) LogStreamName() string {
+	return h.shipper.stream
+}
+
+// Dropped returns the number of events discarded because the buffer was full, the handler was
+// closed, or CloudWatch kept rejecting their batch.
+func (h *CloudWatchHandler) Dropped() int64 {
+	return h.shipper.dropped.Load()
+}
+
+// CloudWatchStatus is the delivery state of a CloudWatchHandler.
+type CloudWatchStatus struct {
+	// Buffered is the number of events waiting to be sent.
+	Buffered int
+	// Dropped is the same count as CloudWatchHandler.Dropped.
+	Dropped int64
+	// LastSent is when CloudWatch last accepted a batch; zero if it never has.
+	LastSent time.Time
+	// LastError is why the last failed batch was dropped, and LastErrorAt when; both are
+	// cleared by the next batch CloudWatch accepts.
+	LastError   string
+	LastErrorAt time.Time
+	// Closed reports whether Close has been called.
+	Closed bool
+}
+
+// Status reports whether logs are reaching CloudWatch.
+func (h *CloudWatchHandler) Status() CloudWatchStatus {
+	s := h.shipper
+	st := CloudWatchStatus{Buffered: len(s.events), Dropped: s.dropped.Load()}
+	select {
+	case <-s.stop:
+		st.Closed = true
+	default:
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.LastSent, st.LastError, st.LastErrorAt = s.lastSent, s.lastError, s.lastErrorAt
+	return st
+}
+
+// Flush sends every event logged so far and waits until CloudWatch has accepted it, the send
+// has been given up on, or ctx ends.
+func (h *CloudWatchHandler) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case h.shipper.flushes <- ack:
+	case <-h.shipper.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close sends the buffered events and stops the handler; events logged afterwards are dropped.
+// If ctx ends first, pending sends are abandoned and ctx's error is returned.
+func (h *CloudWatchHandler) Close(ctx context.Context) error {
+	h.shipper.stopOnce.Do(func() { close(h.shipper.stop) })
+	select {
+	case <-h.shipper.done:
+		return nil
+	case <-ctx.Done():
+		h.shipper.cancel()
+		return ctx.Err()
+	}
+}
+
+func (h *CloudWatchHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *CloudWatchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *CloudWatchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &CloudWatchHandler{next: h.next.WithAttrs(attrs), shipper: h.shipper}
+}
+
+func (h *CloudWatchHandler) WithGroup(name string) slog.Handler {
+	return &CloudWatchHandler{next: h.next.WithGroup(name), shipper: h.shipper}
+}
+
+// cloudWatchShipper receives one JSON line per record from the JSON handler and sends them to
+// CloudWatch from its run goroutine, which alone owns the batch and the sequence token.
+type cloudWatchShipper struct {
+	api    CloudWatchLogsAPI
+	group  string
+	stream string
+	opts   CloudWatchOptions
+
+	events   chan types.InputLogEvent
+	flushes  chan chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	dropped  atomic.Int64
+
+	// mu guards the delivery state reported by Status.
+	mu          sync.Mutex
+	lastSent    time.Time
+	lastError   string
+	lastErrorAt time.Time
+
+	// errLog receives delivery failures. They cannot go through slog, which would feed them
+	// back into the shipper.
+	errLog io.Writer
+
+	streamReady   bool
+	sequenceToken string
+}
+
+// Write queues one log line. It never blocks: when the buffer is full the line is dropped.
+func (s *cloudWatchShipper) Write(p []byte) (int, error) {
+	message := strings.TrimSuffix(string(p), "\n")
+	if len(message) > cwMaxEventBytes {
+		message = message[:cwMaxEventBytes]
+	}
+	event := types.InputLogEvent{Timestamp: aws.Int64(time.Now().UnixMilli()), Message: aws.String(message)}
+
+	select {
+	case <-s.stop:
+		s.dropped.Add(1)
+		return len(p), nil
+	default:
+	}
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+func (s *cloudWatchShipper) run() {
+	defer close(s.done)
+	defer s.cancel()
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []types.InputLogEvent
+	batchBytes := 0
+	add := func(e types.InputLogEvent) {
+		size := len(*e.Message) + cwEventOverhead
+		if batchBytes+size > cwMaxBatchBytes {
+			s.send(batch)
+			batch, batchBytes = nil, 0
+		}
+		batch = append(batch, e)
+		batchBytes += size
+		if len(batch) >= s.opts.BatchSize {
+			s.send(batch)
+			batch, batchBytes = nil, 0
+		}
+	}
+	// drain moves everything queued so far into batches and sends them.
+	drain := func() {
+		for {
+			select {
+			case e := <-s.events:
+				add(e)
+			default:
+				s.send(batch)
+				batch, batchBytes = nil, 0
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-s.events:
+			add(e)
+		case <-ticker.C:
+			s.send(batch)
+			batch, batchBytes = nil, 0
+		case ack := <-s.flushes:
+			drain()
+			close(ack)
+		case <-s.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// send delivers one batch, retrying with exponential backoff on throttling, server errors and
+// network failures. Sequence token mismatches are corrected from the error and a missing
+// stream is recreated; neither waits. A batch that still fails is dropped and reported.
+func (s *cloudWatchShipper) send(batch []types.InputLogEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	// PutLogEvents rejects batches that are not in chronological order.
+	sort.SliceStable(batch, func(i, j int) bool { return *batch[i].Timestamp < *batch[j].Timestamp })
+
+	backoff := s.opts.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= s.opts.MaxRetries; attempt++ {
+		if err = s.ensureStream(); err == nil {
+			input := &cloudwatchlogs.PutLogEventsInput{
+				LogGroupName:  aws.String(s.group),
+				LogStreamName: aws.String(s.stream),
+				LogEvents:     batch,
+			}
+			if s.sequenceToken != "" {
+				input.SequenceToken = aws.String(s.sequenceToken)
+			}
+			var out *cloudwatchlogs.PutLogEventsOutput
+			if out, err = s.api.PutLogEvents(s.ctx, input); err == nil {
+				s.sequenceToken = aws.ToString(out.NextSequenceToken)
+				s.delivered(nil)
+				return
+			}
+		}
+
+		var alreadyAccepted *types.DataAlreadyAcceptedException
+		var invalidToken *types.InvalidSequenceTokenException
+		var notFound *types.ResourceNotFoundException
+		switch {
+		case errors.As(err, &alreadyAccepted):
+			s.sequenceToken = aws.ToString(alreadyAccepted.ExpectedSequenceToken)
+			s.delivered(nil)
+			return
+		case errors.As(err, &invalidToken):
+			s.sequenceToken = aws.ToString(invalidToken.ExpectedSequenceToken)
+			continue
+		case errors.As(err, &notFound):
+			s.streamReady, s.sequenceToken = false, ""
+			continue
+		}
+		if !retryable(err) || s.ctx.Err() != nil || attempt == s.opts.MaxRetries {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+		}
+		backoff *= 2
+	}
+
+	s.dropped.Add(int64(len(batch)))
+	s.delivered(err)
+	fmt.Fprintf(s.errLog, "cloudwatch: dropped %d log events for %s/%s: %v\n", len(batch), s.group, s.stream, err)
+}
+
+// delivered records the outcome of a batch for Status.
+func (s *cloudWatchShipper) delivered(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.lastError, s.lastErrorAt = err.Error(), time.Now()
+		return
+	}
+	s.lastSent, s.lastError, s.lastErrorAt = time.Now(), "", time.Time{}
+}
+
+// retryable reports whether a failed call may succeed if sent again unchanged: throttling,
+// server errors and network failures, as the SDK's standard retryer classifies them.
+func retryable(err error) bool {
+	return retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary ||
+		retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// ensureStream creates the log stream once, creating the group first if it does not exist.
+func (s *cloudWatchShipper) ensureStream() error {
+	if s.streamReady {
+		return nil
+	}
+	err := s.createLogStream()
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		_, err = s.api.CreateLogGroup(s.ctx, &cloudwatchlogs.CreateLogGroupInput{LogGroupName: aws.String(s.group)})
+		if err = ignoreAlreadyExists(err); err != nil {
+			return err
+		}
+		err = s.createLogStream()
+	}
+	if err != nil {
+		return err
+	}
+	s.streamReady, s.sequenceToken = true, ""
+	return nil
 }
 
-func NewCloudWatchLogger(ctx context.Context, logGroupName, logStreamName string) (*CloudWatchLogger, error) {
+func (s *cloudWatchShipper) createLogStream() error {
+	_, err := s.api.CreateLogStream(s.ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(s.group),
+		LogStreamName: aws.String(s.stream),
+	})
+	return ignoreAlreadyExists(err)
+}
+
+func ignoreAlreadyExists(err error) error {
+	var exists *types.ResourceAlreadyExistsException
+	if errors.As(err, &exists) {
+		return nil
+	}
+	return err
+}
+
+// ResolveStreamName suffixes base with the EC2 instance id, or the hostname outside EC2, so
+// instances do not share a stream. An empty base means "api-server".
+func ResolveStreamName(ctx context.Context, base string) string {
 	// Ensure we have a base stream name
-	if strings.TrimSpace(logStreamName) == "" {
-		logStreamName = "api-server"
+	if strings.TrimSpace(base) == "" {
+		base = "api-server"
 	}
 
 	// Attempt to enrich stream name with EC2 instance-id when available
@@ -54,54 +464,8 @@ func NewCloudWatchLogger(ctx context.Context, logGroupName, logStreamName string
 	}
 
 	// Compose final stream name, avoid duplicating instance id if already present
-	finalStream := logStreamName
-	if instanceID != "" && !strings.Contains(logStreamName, instanceID) {
-		finalStream = fmt.Sprintf("%s-%s", logStreamName, instanceID)
-	}
-
-	log.Printf("CloudWatch logger initialized for group: %s, stream: %s", logGroupName, finalStream)
-	return &CloudWatchLogger{
-		logGroupName:  logGroupName,
-		logStreamName: finalStream,
-	}, nil
-}
-
-// LogRequest logs an HTTP request to CloudWatch
-func (cwl *CloudWatchLogger) LogRequest(ctx context.Context, method, path string, statusCode int, duration time.Duration, remoteAddr string) error {
-	message := fmt.Sprintf(
-		"[%s] %s %s | Status: %d | Duration: %dms | Remote: %s | Group: %s | Stream: %s",
-		time.Now().Format("2006-01-02 15:04:05"),
-		method,
-		path,
-		statusCode,
-		duration.Milliseconds(),
-		remoteAddr,
-		cwl.logGroupName,
-		cwl.logStreamName,
-	)
-	log.Printf("[CLOUDWATCH] %s", message)
-	return nil
-}
-
-// LogError logs an error to CloudWatch
-func (cwl *CloudWatchLogger) LogError(ctx context.Context, method, path string, err error, statusCode int) error {
-	message := fmt.Sprintf(
-		"[ERROR] [%s] %s %s | Status: %d | Error: %v | Group: %s | Stream: %s",
-		time.Now().Format("2006-01-02 15:04:05"),
-		method,
-		path,
-		statusCode,
-		err,
-		cwl.logGroupName,
-		cwl.logStreamName,
-	)
-	log.Printf("[CLOUDWATCH] %s", message)
-	return nil
-}
-
-// LogMessage logs a custom message to CloudWatch
-func (cwl *CloudWatchLogger) LogMessage(ctx context.Context, message string) error {
-	msg := fmt.Sprintf("[%s] %s | Group: %s | Stream: %s", time.Now().Format("2006-01-02 15:04:05"), message, cwl.logGroupName, cwl.logStreamName)
-	log.Printf("[CLOUDWATCH] %s", msg)
-	return nil
+	if instanceID != "" && !strings.Contains(base, instanceID) {
+		return fmt.Sprintf("%s-%s", base, instanceID)
+	}
+	return base
 }
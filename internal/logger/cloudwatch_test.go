@@ -0,0 +1,249 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/jmason/john_ai_project/internal/cloudwatchtest"
+)
+
+const (
+	testLogGroup  = "/test/api"
+	testLogStream = "api-server-test"
+)
+
+func newTestCloudWatch(t *testing.T, opts CloudWatchOptions) (*cloudwatchtest.Server, *CloudWatchHandler) {
+	t.Helper()
+	fake := cloudwatchtest.NewServer()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := NewCloudWatchLogsClient(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDTEST", "secret", ""),
+	}, srv.URL)
+	h := NewCloudWatchHandler(client, testLogGroup, testLogStream, opts)
+	h.shipper.errLog = io.Discard
+	t.Cleanup(func() { h.Close(context.Background()) })
+	return fake, h
+}
+
+func closeHandler(t *testing.T, h *CloudWatchHandler) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+func TestCloudWatchHandler_ShipsInBatches(t *testing.T) {
+	fake, h := newTestCloudWatch(t, CloudWatchOptions{BatchSize: 2, FlushInterval: time.Hour})
+	fake.CreateLogGroup(testLogGroup)
+
+	log := slog.New(h).With(KeyComponent, "test")
+	for i := 0; i < 5; i++ {
+		log.Info("request served", "n", i)
+	}
+	closeHandler(t, h)
+
+	events := fake.Events(testLogGroup, testLogStream)
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 events, got %d", len(events))
+	}
+	if !strings.Contains(events[0].Message, `"msg":"request served"`) || !strings.Contains(events[0].Message, `"component":"test"`) {
+		t.Errorf("Unexpected event: %s", events[0].Message)
+	}
+	if strings.HasSuffix(events[0].Message, "\n") {
+		t.Error("Expected the trailing newline to be trimmed")
+	}
+	// Two full batches, then the remainder on Close.
+	if got := fake.Calls("PutLogEvents"); got != 3 {
+		t.Errorf("Expected 3 PutLogEvents calls, got %d", got)
+	}
+	if got := fake.Calls("CreateLogStream"); got != 1 {
+		t.Errorf("Expected the stream to be created once, got %d", got)
+	}
+}
+
+func TestCloudWatchHandler_FlushesOnInterval(t *testing.T) {
+	fake, h := newTestCloudWatch(t, CloudWatchOptions{FlushInterval: 20 * time.Millisecond})
+	fake.CreateLogGroup(testLogGroup)
+
+	slog.New(h).Info("hello")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.Events(testLogGroup, testLogStream)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the event to be sent without a Flush")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCloudWatchHandler_CreatesMissingLogGroup(t *testing.T) {
+	fake, h := newTestCloudWatch(t, CloudWatchOptions{})
+
+	slog.New(h).Info("first record")
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if got := fake.Calls("CreateLogGroup"); got != 1 {
+		t.Errorf("Expected the group to be created, got %d CreateLogGroup calls", got)
+	}
+	if got := len(fake.Events(testLogGroup, testLogStream)); got != 1 {
+		t.Errorf("Expected 1 event, got %d", got)
+	}
+}
+
+func TestCloudWatchHandler_RetriesWithBackoff(t *testing.T) {
+	fake, h := newTestCloudWatch(t, CloudWatchOptions{MaxRetries: 3, RetryBackoff: time.Millisecond})
+	fake.CreateLogGroup(testLogGroup)
+	fake.FailNext("PutLogEvents",
+		cloudwatchtest.Failure{Status: http.StatusServiceUnavailable, Type: "ServiceUnavailableException"},
+		cloudwatchtest.Failure{Status: http.StatusBadRequest, Type: "ThrottlingException"},
+	)
+
+	slog.New(h).Info("retried")
+	closeHandler(t, h)
+
+	if got := len(fake.Events(testLogGroup, testLogStream)); got != 1 {
+		t.Fatalf("Expected the event to be delivered after retries, got %d events", got)
+	}
+	if got := fake.Calls("PutLogEvents"); got != 3 {
+		t.Errorf("Expected 3 PutLogEvents calls, got %d", got)
+	}
+	if h.Dropped() != 0 {
+		t.Errorf("Expected nothing dropped, got %d", h.Dropped())
+	}
+}
+
+func TestCloudWatchHandler_RecoversSequenceToken(t *testing.T) {
+	fake, h := newTestCloudWatch(t, CloudWatchOptions{})
+	fake.CreateLogGroup(testLogGroup)
+	log := slog.New(h)
+
+	log.Info("first")
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	fake.ExpireSequenceToken(testLogGroup, testLogStream)
+	log.Info("second")
+	closeHandler(t, h)
+
+	if got := len(fake.Events(testLogGroup, testLogStream)); got != 2 {
+		t.Fatalf("Expected 2 events, got %d", got)
+	}
+	if got := fake.Calls("PutLogEvents"); got != 3 {
+		t.Errorf("Expected one resend with the corrected token (3 calls), got %d", got)
+	}
+}
+
+func TestCloudWatchHandler_DropsRejectedBatch(t *testing.T) {
+	fake, h := newTestCloudWatch(t, CloudWatchOptions{MaxRetries: 3, RetryBackoff: time.Millisecond})
+	fake.CreateLogGroup(testLogGroup)
+	fake.FailNext("PutLogEvents", cloudwatchtest.Failure{Status: http.StatusBadRequest, Type: "InvalidParameterException"})
+
+	slog.New(h).Info("rejected")
+	closeHandler(t, h)
+
+	if got := fake.Calls("PutLogEvents"); got != 1 {
+		t.Errorf("Expected a client error not to be retried, got %d calls", got)
+	}
+	if h.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", h.Dropped())
+	}
+}
+
+func TestCloudWatchHandler_Status(t *testing.T) {
+	fake, h := newTestCloudWatch(t, CloudWatchOptions{MaxRetries: -1})
+	fake.CreateLogGroup(testLogGroup)
+	fake.FailNext("PutLogEvents", cloudwatchtest.Failure{Status: http.StatusBadRequest, Type: "InvalidParameterException"})
+	log := slog.New(h)
+	ctx := context.Background()
+
+	log.Info("rejected")
+	if err := h.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	st := h.Status()
+	if st.LastError == "" || st.LastErrorAt.IsZero() || !st.LastSent.IsZero() || st.Dropped != 1 {
+		t.Errorf("Expected the rejected batch in the status, got %+v", st)
+	}
+
+	log.Info("accepted")
+	if err := h.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	st = h.Status()
+	if st.LastError != "" || st.LastSent.IsZero() || st.Closed {
+		t.Errorf("Expected a successful send to clear the error, got %+v", st)
+	}
+
+	closeHandler(t, h)
+	if !h.Status().Closed {
+		t.Error("Expected the status to report the handler closed")
+	}
+}
+
+// blockingAPI holds every PutLogEvents call until release is closed.
+type blockingAPI struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	events  []types.InputLogEvent
+}
+
+func (b *blockingAPI) CreateLogGroup(context.Context, *cloudwatchlogs.CreateLogGroupInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+func (b *blockingAPI) CreateLogStream(context.Context, *cloudwatchlogs.CreateLogStreamInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func (b *blockingAPI) PutLogEvents(_ context.Context, params *cloudwatchlogs.PutLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	b.once.Do(func() { close(b.started) })
+	<-b.release
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, params.LogEvents...)
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+func TestCloudWatchHandler_DropsWhenBufferFull(t *testing.T) {
+	api := &blockingAPI{started: make(chan struct{}), release: make(chan struct{})}
+	h := NewCloudWatchHandler(api, testLogGroup, testLogStream, CloudWatchOptions{BufferSize: 1, BatchSize: 1})
+	log := slog.New(h)
+
+	log.Info("in flight")
+	<-api.started
+	log.Info("buffered")
+	log.Info("dropped")
+	if h.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", h.Dropped())
+	}
+
+	close(api.release)
+	closeHandler(t, h)
+	if len(api.events) != 2 {
+		t.Errorf("Expected 2 delivered events, got %d", len(api.events))
+	}
+
+	log.Info("after close")
+	if h.Dropped() != 2 {
+		t.Errorf("Expected events logged after Close to be dropped, got %d dropped", h.Dropped())
+	}
+}
@@ -0,0 +1,250 @@
+// Package logger provides structured, leveled logging on log/slog. Records are written as JSON,
+// carry the request-scoped attributes found in their context (request_id, user_id, trace_id), have
+// personal data redacted, and each component (auth, http, ...) can log at its own level.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmason/john_ai_project/internal/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys shared by every component, so the same thing is always called the same name.
+const (
+	KeyComponent  = "component"
+	KeyRequestID  = "request_id"
+	KeyTraceID    = "trace_id"
+	KeySpanID     = "span_id"
+	KeyUserID     = "user_id"
+	KeyRoute      = "route"
+	KeyStatus     = "status"
+	KeyDurationMS = "duration_ms"
+	KeyError      = "error"
+)
+
+// Levels is the minimum level logged by each component, and by any component not listed.
+type Levels struct {
+	Default    slog.Level
+	Components map[string]slog.Level
+}
+
+// For returns the minimum level for component.
+func (l Levels) For(component string) slog.Level {
+	if level, ok := l.Components[component]; ok {
+		return level
+	}
+	return l.Default
+}
+
+// ParseLevels reads a level spec such as "info" or "info,auth=debug,http=warn". The bare level is
+// the default; component=level pairs override it. An empty spec means info.
+func ParseLevels(spec string) (Levels, error) {
+	levels := Levels{Default: slog.LevelInfo, Components: map[string]slog.Level{}}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, scoped := strings.Cut(part, "=")
+		var level slog.Level
+		if !scoped {
+			value = name
+		}
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return Levels{}, fmt.Errorf("invalid log level %q: %w", part, err)
+		}
+		if scoped {
+			levels.Components[strings.TrimSpace(name)] = level
+		} else {
+			levels.Default = level
+		}
+	}
+	return levels, nil
+}
+
+// Error returns the attribute for err under KeyError.
+func Error(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+type config struct {
+	levels  Levels
+	handler slog.Handler
+}
+
+var current atomic.Pointer[config]
+
+func init() {
+	current.Store(&config{
+		levels:  Levels{Default: slog.LevelInfo},
+		handler: NewRedactingHandler(NewJSONHandler(os.Stderr)),
+	})
+}
+
+// NewJSONHandler writes records as JSON lines to w. It passes every level; Configure applies
+// the per-component levels.
+func NewJSONHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+}
+
+// Options configure the logging pipeline.
+type Options struct {
+	Levels Levels
+	// SensitiveFields are attribute keys whose values are redacted in addition to
+	// DefaultSensitiveFields.
+	SensitiveFields []string
+	// DisableRedaction turns off PII redaction. It is only for local development, where the
+	// LogMailer's links are needed.
+	DisableRedaction bool
+}
+
+// Configure sends every record to handlers, such as NewJSONHandler(os.Stderr) or a
+// CloudWatchHandler, filtered by level and with personal data redacted (see
+// NewRedactingHandler). slog.Default and the standard log package are routed through the "app"
+// component, so remaining log.Printf calls come out as JSON too. Loggers returned by Component
+// before Configure pick up the new settings.
+func Configure(opts Options, handlers ...slog.Handler) {
+	var h slog.Handler = multiHandler(handlers)
+	if len(handlers) == 1 {
+		h = handlers[0]
+	}
+	if !opts.DisableRedaction {
+		h = NewRedactingHandler(h, opts.SensitiveFields...)
+	}
+	current.Store(&config{levels: opts.Levels, handler: h})
+	slog.SetDefault(Component("app"))
+}
+
+// Component returns the logger for a named part of the system. Its records carry the component
+// name and are filtered by that component's level.
+func Component(name string) *slog.Logger {
+	return slog.New(&componentHandler{name: name})
+}
+
+type attrSet struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type attrsKey struct{}
+
+// NewContext returns ctx with room for request attributes. Attributes added with AddAttrs to ctx
+// or any context derived from it are logged with every record that uses either, so a user_id
+// found by the auth middleware also appears on the router's access log line.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attrsKey{}, &attrSet{})
+}
+
+// AddAttrs records attrs for the request that ctx belongs to. It does nothing for a context
+// without NewContext.
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	set, ok := ctx.Value(attrsKey{}).(*attrSet)
+	if !ok {
+		return
+	}
+	set.mu.Lock()
+	set.attrs = append(set.attrs, attrs...)
+	set.mu.Unlock()
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if id := requestid.FromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String(KeyRequestID, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String(KeyTraceID, sc.TraceID().String()), slog.String(KeySpanID, sc.SpanID().String()))
+	}
+	if set, ok := ctx.Value(attrsKey{}).(*attrSet); ok {
+		set.mu.Lock()
+		attrs = append(attrs, set.attrs...)
+		set.mu.Unlock()
+	}
+	return attrs
+}
+
+// componentHandler resolves the configured handler when a record is logged, so package-level
+// loggers created before Configure still follow it.
+type componentHandler struct {
+	name string
+	// with replays the logger's With and WithGroup calls on the configured handler.
+	with []func(slog.Handler) slog.Handler
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= current.Load().levels.For(h.name)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	next := current.Load().handler.WithAttrs([]slog.Attr{slog.String(KeyComponent, h.name)})
+	if ctx != nil {
+		if attrs := contextAttrs(ctx); len(attrs) > 0 {
+			next = next.WithAttrs(attrs)
+		}
+	}
+	for _, with := range h.with {
+		next = with(next)
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.extend(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.extend(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *componentHandler) extend(with func(slog.Handler) slog.Handler) slog.Handler {
+	return &componentHandler{name: h.name, with: append(append([]func(slog.Handler) slog.Handler{}, h.with...), with)}
+}
+
+// multiHandler sends each record to every handler that is enabled for it.
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range m {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(multiHandler, len(m))
+	for i, h := range m {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	out := make(multiHandler, len(m))
+	for i, h := range m {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the value of a sensitive field.
+const Redacted = "[REDACTED]"
+
+// Placeholders for personal data found inside other values.
+const (
+	RedactedEmail = "[EMAIL]"
+	RedactedPhone = "[PHONE]"
+	RedactedJWT   = "[JWT]"
+)
+
+// DefaultSensitiveFields are attribute keys whose values are never logged. Keys match without
+// regard to case, and nested keys (a "notes" field inside a logged struct) match too.
+var DefaultSensitiveFields = []string{
+	"password", "current_password", "new_password", "token", "authorization", "secret",
+	"notes", "note", "notes_list", "initial_note", "initial_consult_notes",
+	"date_of_birth", "address", "emergency_contact_name", "emergency_contact_phone", "login",
+}
+
+var (
+	jwtPattern   = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// Phone numbers: an optional +, then at least 7 digits possibly separated by spaces, dots,
+	// dashes or parentheses. IDs and timestamps are not matched because they contain letters,
+	// colons or long unbroken digit runs bounded by other characters.
+	phonePattern = regexp.MustCompile(`(?:^|[^\w-])(\+?\(?\d{1,4}\)?(?:[ .\-]?\(?\d{2,4}\)?){2,5})(?:$|[^\w-])`)
+	datePattern  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// RedactString masks JWTs, email addresses and phone numbers in s.
+func RedactString(s string) string {
+	s = jwtPattern.ReplaceAllString(s, RedactedJWT)
+	s = emailPattern.ReplaceAllString(s, RedactedEmail)
+	return phonePattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := phonePattern.FindStringSubmatch(m)
+		if digits(sub[1]) < 7 || datePattern.MatchString(sub[1]) {
+			return m
+		}
+		return strings.Replace(m, sub[1], RedactedPhone, 1)
+	})
+}
+
+func digits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// redactingHandler masks personal data in every record before passing it on: the values of
+// sensitive fields are replaced, and JWTs, emails and phone numbers are masked in the message
+// and in every string, error and structured value.
+type redactingHandler struct {
+	next   slog.Handler
+	fields map[string]bool
+}
+
+// NewRedactingHandler wraps next so nothing it writes contains the values of DefaultSensitiveFields,
+// the extra fields given, JWTs, email addresses or phone numbers.
+func NewRedactingHandler(next slog.Handler, extraFields ...string) slog.Handler {
+	fields := map[string]bool{}
+	for _, f := range append(append([]string{}, DefaultSensitiveFields...), extraFields...) {
+		if f = normalizeField(f); f != "" {
+			fields[f] = true
+		}
+	}
+	return &redactingHandler{next: next, fields: fields}
+}
+
+func normalizeField(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, RedactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.attr(a)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redacted), fields: h.fields}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name), fields: h.fields}
+}
+
+func (h *redactingHandler) attr(a slog.Attr) slog.Attr {
+	if h.fields[normalizeField(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	return slog.Attr{Key: a.Key, Value: h.value(a.Value)}
+}
+
+func (h *redactingHandler) value(v slog.Value) slog.Value {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.StringValue(RedactString(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, a := range attrs {
+			redacted[i] = h.attr(a)
+		}
+		return slog.GroupValue(redacted...)
+	case slog.KindAny:
+		return h.anyValue(v.Any())
+	default:
+		return v
+	}
+}
+
+// anyValue redacts errors and arbitrary values. Structs, maps and JSON bodies are walked through
+// their JSON form so sensitive fields nested inside them are caught too.
+func (h *redactingHandler) anyValue(v any) slog.Value {
+	switch t := v.(type) {
+	case nil:
+		return slog.AnyValue(nil)
+	case error:
+		return slog.StringValue(RedactString(t.Error()))
+	case []byte:
+		return h.jsonValue(t, string(t))
+	case json.RawMessage:
+		return h.jsonValue(t, string(t))
+	case fmt.Stringer:
+		return slog.StringValue(RedactString(t.String()))
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return slog.StringValue(RedactString(fmt.Sprintf("%+v", v)))
+	}
+	return h.jsonValue(raw, string(raw))
+}
+
+func (h *redactingHandler) jsonValue(raw []byte, fallback string) slog.Value {
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return slog.StringValue(RedactString(fallback))
+	}
+	return slog.AnyValue(h.walk(decoded))
+}
+
+func (h *redactingHandler) walk(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if h.fields[normalizeField(k)] {
+				t[k] = Redacted
+			} else {
+				t[k] = h.walk(val)
+			}
+		}
+		return t
+	case []any:
+		for i, val := range t {
+			t[i] = h.walk(val)
+		}
+		return t
+	case string:
+		return RedactString(t)
+	default:
+		return v
+	}
+}
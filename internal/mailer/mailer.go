@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/jmason/john_ai_project/internal/logger"
+)
+
+var mailLog = logger.Component("mailer")
+
+// LogMailer "delivers" mail by writing it to the server log. It is the default until an
+// outbound mail provider is configured, and is what local development relies on to get
+// invitation and email verification links.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// SendInvitation logs the invitation link addressed to email.
+func (m *LogMailer) SendInvitation(ctx context.Context, email, role, link string) error {
+	mailLog.InfoContext(ctx, "invitation", "to", email, "role", role, "link", link)
+	return nil
+}
+
+// SendEmailVerification logs the link that confirms a change of email address.
+func (m *LogMailer) SendEmailVerification(ctx context.Context, email, link string) error {
+	mailLog.InfoContext(ctx, "email verification", "to", email, "link", link)
+	return nil
+}
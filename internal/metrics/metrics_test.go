@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("http_requests_total", "Requests served.", "route", "status")
+	queue := r.NewGauge("queue_depth", "Items waiting.")
+	latency := r.NewHistogram("latency_seconds", "Latency.", []float64{0.5, 0.1}, "route")
+	r.NewCounter("unused_total", "Never incremented.\nSecond line.")
+
+	requests.Inc("/api/clients/{id}", "200")
+	requests.Add(2, "/api/clients/{id}", "200")
+	requests.Inc(`/say "hi"`, "404")
+	queue.Set(3)
+	queue.Add(-1)
+	latency.Observe(0.05, "/health")
+	latency.Observe(0.3, "/health")
+	latency.Observe(2, "/health")
+
+	var out bytes.Buffer
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	want := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{route="/api/clients/{id}",status="200"} 3
+http_requests_total{route="/say \"hi\"",status="404"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/health",le="0.1"} 1
+latency_seconds_bucket{route="/health",le="0.5"} 2
+latency_seconds_bucket{route="/health",le="+Inf"} 3
+latency_seconds_sum{route="/health"} 2.35
+latency_seconds_count{route="/health"} 3
+# HELP queue_depth Items waiting.
+# TYPE queue_depth gauge
+queue_depth 2
+# HELP unused_total Never incremented.\nSecond line.
+# TYPE unused_total counter
+`
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("logins_total", "Logins.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Expected Content-Type %q, got %q", ContentType, got)
+	}
+	if !strings.Contains(rec.Body.String(), "logins_total 1\n") {
+		t.Errorf("Expected the counter in the body: %s", rec.Body.String())
+	}
+}
+
+func TestRegistry_RejectsMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{name: "Duplicate name", fn: func(r *Registry) {
+			r.NewCounter("dup_total", "")
+			r.NewGauge("dup_total", "")
+		}},
+		{name: "Invalid name", fn: func(r *Registry) { r.NewCounter("http-requests", "") }},
+		{name: "Reserved label", fn: func(r *Registry) { r.NewHistogram("h", "", nil, "le") }},
+		{name: "Wrong label count", fn: func(r *Registry) { r.NewCounter("c_total", "", "route").Inc() }},
+		{name: "Decreasing counter", fn: func(r *Registry) { r.NewCounter("c_total", "").Add(-1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			tt.fn(NewRegistry())
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package openapi
+
+import _ "embed"
+
+// DocsPage is a self-contained HTML page, served at /docs, that renders the document from
+// /openapi.json and can send requests with a pasted token. It loads nothing from other hosts.
+//
+//go:embed docs.html
+var DocsPage []byte
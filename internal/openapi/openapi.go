@@ -0,0 +1,272 @@
+// Package openapi builds an OpenAPI 3 document from the registered routes and the Go types
+// their handlers read and write, so the published API description cannot drift from the code.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmason/john_ai_project/internal/route"
+)
+
+// Version is the OpenAPI version documents are written in.
+const Version = "3.0.3"
+
+// Document is the subset of an OpenAPI 3 document this API needs.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case HTTP methods to operations, as OpenAPI does.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// bearerAuth names the security scheme for JWTs and API keys sent as Authorization: Bearer.
+const bearerAuth = "bearerAuth"
+
+// Endpoint describes one route: what it is for, who may call it and the types it reads and
+// writes. Request and Response are values of the body types, e.g. CreateClientRequest{}.
+type Endpoint struct {
+	Method      string
+	Pattern     string
+	Tag         string
+	Summary     string
+	Description string
+	// Public endpoints need no Authorization header.
+	Public  bool
+	Query   []Parameter
+	Headers []Parameter
+	// Request is the JSON body the endpoint decodes, or nil.
+	Request any
+	// Status is the success status, http.StatusOK when zero.
+	Status int
+	// Response is the JSON body written with Status, or nil for no body.
+	Response any
+	// Errors lists the error statuses the endpoint can return; each uses Spec.Error.
+	Errors []int
+}
+
+// Spec is the hand-written half of the document: the endpoints, and the body of error responses.
+type Spec struct {
+	Info  Info
+	Tags  []Tag
+	Error any
+	// ErrorContentType is the media type of error responses, "application/json" when empty.
+	ErrorContentType string
+	Endpoints        []Endpoint
+	// Customize adjusts a generated component schema by name, e.g. to add the fields a
+	// MarshalJSON method writes.
+	Customize map[string]func(*Schema)
+}
+
+// Build documents every route in routes. It fails if a route has no Endpoint, so a handler
+// cannot be registered without being described. Endpoints for routes that are not registered
+// (such as SSO when it is not configured) are left out.
+func (s Spec) Build(routes []route.Route) (*Document, error) {
+	endpoints := map[route.Route]Endpoint{}
+	for _, e := range s.Endpoints {
+		endpoints[route.Route{Method: e.Method, Pattern: e.Pattern}] = e
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.Info,
+		Tags:    s.Tags,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				bearerAuth: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT or API key",
+					Description:  "A token from /api/auth/login, or an API key created by an admin.",
+				},
+			},
+		},
+	}
+	schemas := newSchemaGenerator(doc.Components.Schemas)
+
+	var missing []string
+	for _, rt := range routes {
+		e, ok := endpoints[rt]
+		if !ok {
+			missing = append(missing, rt.Method+" "+rt.Pattern)
+			continue
+		}
+		item := doc.Paths[rt.Pattern]
+		if item == nil {
+			item = PathItem{}
+			doc.Paths[rt.Pattern] = item
+		}
+		item[strings.ToLower(rt.Method)] = s.operation(e, schemas)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("routes missing from the API spec: %s", strings.Join(missing, ", "))
+	}
+
+	for name, customize := range s.Customize {
+		if schema, ok := doc.Components.Schemas[name]; ok {
+			customize(schema)
+		}
+	}
+	return doc, nil
+}
+
+func (s Spec) operation(e Endpoint, schemas *schemaGenerator) *Operation {
+	op := &Operation{
+		Summary:     e.Summary,
+		Description: e.Description,
+		OperationID: operationID(e.Method, e.Pattern),
+		Responses:   map[string]*Response{},
+	}
+	if e.Tag != "" {
+		op.Tags = []string{e.Tag}
+	}
+	if !e.Public {
+		op.Security = []map[string][]string{{bearerAuth: {}}}
+	}
+
+	for _, name := range pathParams(e.Pattern) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, p := range e.Query {
+		p.In = "query"
+		if p.Schema == nil {
+			p.Schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+	for _, p := range e.Headers {
+		p.In = "header"
+		if p.Schema == nil {
+			p.Schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+
+	if e.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(schemas.schemaOf(reflect.TypeOf(e.Request))),
+		}
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if e.Response != nil {
+		success.Content = jsonContent(schemas.schemaOf(reflect.TypeOf(e.Response)))
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	for _, code := range e.Errors {
+		resp := &Response{Description: http.StatusText(code)}
+		if s.Error != nil {
+			contentType := s.ErrorContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			resp.Content = map[string]*MediaType{contentType: {Schema: schemas.schemaOf(reflect.TypeOf(s.Error))}}
+		}
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+	return op
+}
+
+// Operation returns the operation documented for method and pattern, or nil.
+func (d *Document) Operation(method, pattern string) *Operation {
+	return d.Paths[pattern][strings.ToLower(method)]
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+func pathParams(pattern string) []string {
+	var names []string
+	for _, seg := range strings.Split(pattern, "/") {
+		if len(seg) > 2 && strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, seg[1:len(seg)-1])
+		}
+	}
+	return names
+}
+
+// operationID turns "GET /api/clients/{id}" into "getApiClientsById".
+func operationID(method, pattern string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.FieldsFunc(pattern, func(r rune) bool { return r == '/' || r == '-' || r == '.' || r == '_' }) {
+		if len(seg) > 2 && strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			b.WriteString("By")
+			seg = seg[1 : len(seg)-1]
+		}
+		b.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/db"
+)
+
+// ErrAPIKeyNotFound is returned when no API key exists for the given id.
+var ErrAPIKeyNotFound = apperror.NotFound("api_key_not_found", "api key not found")
+
+// APIKey is a long-lived credential for service-to-service and automation access.
+// ID is the public key prefix; only a hash of the secret part is stored.
+type APIKey struct {
+	ID         string   `dynamodbav:"id" json:"id"`
+	Name       string   `dynamodbav:"name" json:"name"`
+	SecretHash string   `dynamodbav:"secret_hash" json:"-"` // Don't expose in JSON
+	Scopes     []string `dynamodbav:"scopes,stringset" json:"scopes"`
+	CreatedBy  string   `dynamodbav:"created_by" json:"created_by"`
+	CreatedAt  string   `dynamodbav:"created_at" json:"created_at"`
+	LastUsedAt string   `dynamodbav:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  string   `dynamodbav:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+type APIKeyRepository struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+func NewAPIKeyRepository(db *dynamodb.Client, tableName string) *APIKeyRepository {
+	return &APIKeyRepository{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	ctx = db.WithCaller(ctx, "APIKeyRepository", "CreateAPIKey")
+	item, err := attributevalue.MarshalMap(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal api key: %w", err)
+	}
+
+	_, err = r.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByID(ctx context.Context, id string) (*APIKey, error) {
+	ctx = db.WithCaller(ctx, "APIKeyRepository", "GetAPIKeyByID")
+	result, err := r.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	var key APIKey
+	if err := attributevalue.UnmarshalMap(result.Item, &key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	ctx = db.WithCaller(ctx, "APIKeyRepository", "ListAPIKeys")
+	result, err := r.db.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan api keys table: %w", err)
+	}
+
+	keys := make([]APIKey, 0, len(result.Items))
+	for _, item := range result.Items {
+		var key APIKey
+		if err := attributevalue.UnmarshalMap(item, &key); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks the key revoked. Revoked keys are kept so their usage history stays visible.
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	ctx = db.WithCaller(ctx, "APIKeyRepository", "RevokeAPIKey")
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		UpdateExpression:    aws.String("SET revoked_at = :ra"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ra": &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrAPIKeyNotFound
+		}
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+// TouchAPIKey records when the key was last used.
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	ctx = db.WithCaller(ctx, "APIKeyRepository", "TouchAPIKey")
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		UpdateExpression:    aws.String("SET last_used_at = :lu"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":lu": &types.AttributeValueMemberS{Value: usedAt.Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used time: %w", err)
+	}
+
+	return nil
+}
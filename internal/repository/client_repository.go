@@ -11,8 +11,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/db"
 )
 
+// ErrClientNotFound is returned, wrapped with the id or email, when no client matches.
+var ErrClientNotFound = apperror.NotFound("client_not_found", "client not found")
+
 // Note is one entry in a client's notes list.
 type Note struct {
 	Date     string `dynamodbav:"date" json:"date"`
@@ -66,16 +71,19 @@ type ClientRepository struct {
 	tableName string
 }
 
-func NewClientRepository(client *dynamodb.Client) *ClientRepository {
+func NewClientRepository(client *dynamodb.Client, tableName string) *ClientRepository {
 	return &ClientRepository{
 		client:    client,
-		tableName: "clients",
+		tableName: tableName,
 	}
 }
 
 func (r *ClientRepository) GetClientList(ctx context.Context) ([]Client, error) {
+	ctx = db.WithCaller(ctx, "ClientRepository", "GetClientList")
 	input := &dynamodb.ScanInput{
 		TableName: aws.String(r.tableName),
+		// Skip email guard items stored alongside clients
+		FilterExpression: aws.String("attribute_not_exists(owner_id)"),
 	}
 
 	result, err := r.client.Scan(ctx, input)
@@ -97,6 +105,7 @@ func (r *ClientRepository) GetClientList(ctx context.Context) ([]Client, error)
 
 // TODO: parameter validation for the id and return an error if the id is not a valid uuid
 func (r *ClientRepository) GetClientByID(ctx context.Context, id string) (*Client, error) {
+	ctx = db.WithCaller(ctx, "ClientRepository", "GetClientByID")
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]types.AttributeValue{
@@ -109,8 +118,8 @@ func (r *ClientRepository) GetClientByID(ctx context.Context, id string) (*Clien
 		return nil, fmt.Errorf("failed to get client: %w", err)
 	}
 
-	if result.Item == nil {
-		return nil, fmt.Errorf("client not found: %s", id)
+	if result.Item == nil || isGuardItem(result.Item) {
+		return nil, fmt.Errorf("%w: %s", ErrClientNotFound, id)
 	}
 
 	var client Client
@@ -157,6 +166,7 @@ func idFromItem(item map[string]types.AttributeValue) string {
 }
 
 func (r *ClientRepository) GetClientsByStatus(ctx context.Context, status string) ([]Client, error) {
+	ctx = db.WithCaller(ctx, "ClientRepository", "GetClientsByStatus")
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(r.tableName),
 		IndexName:              aws.String("status-index"),
@@ -186,19 +196,31 @@ func (r *ClientRepository) GetClientsByStatus(ctx context.Context, status string
 	return clients, nil
 }
 
+// CreateClient writes the client together with a guard item for its email. It fails with
+// ErrEmailTaken if another client holds the email.
 func (r *ClientRepository) CreateClient(ctx context.Context, client *Client) error {
+	ctx = db.WithCaller(ctx, "ClientRepository", "CreateClient")
 	item, err := attributevalue.MarshalMap(client)
 	if err != nil {
 		return fmt.Errorf("failed to marshal client: %w", err)
 	}
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(r.tableName),
-		Item:      item,
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:           aws.String(r.tableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(id)"),
+			}},
+			putGuard(r.tableName, guardID(guardEmailPrefix, client.Email), client.ID),
+		},
 	}
 
-	_, err = r.client.PutItem(ctx, input)
+	_, err = r.client.TransactWriteItems(ctx, input)
 	if err != nil {
+		if conditionFailedAt(err, 1) {
+			return ErrEmailTaken
+		}
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
@@ -206,6 +228,7 @@ func (r *ClientRepository) CreateClient(ctx context.Context, client *Client) err
 }
 
 func (r *ClientRepository) GetClientByEmail(ctx context.Context, email string) (*Client, error) {
+	ctx = db.WithCaller(ctx, "ClientRepository", "GetClientByEmail")
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(r.tableName),
 		IndexName:              aws.String("email-index"),
@@ -225,7 +248,7 @@ func (r *ClientRepository) GetClientByEmail(ctx context.Context, email string) (
 	}
 
 	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("client not found for email: %s", email)
+		return nil, fmt.Errorf("%w for email: %s", ErrClientNotFound, email)
 	}
 
 	item := result.Items[0]
@@ -259,6 +282,7 @@ type ClientPatch struct {
 }
 
 func (r *ClientRepository) UpdateClient(ctx context.Context, id string, patch ClientPatch) error {
+	ctx = db.WithCaller(ctx, "ClientRepository", "UpdateClient")
 	if patch.FirstName == nil && patch.LastName == nil && patch.Email == nil && patch.Notes == nil &&
 		patch.RequestedCounsellor == nil && patch.Urgency == nil && patch.NextAppointment == nil {
 		return fmt.Errorf("no fields to update")
@@ -307,16 +331,55 @@ func (r *ClientRepository) UpdateClient(ctx context.Context, id string, patch Cl
 		values[":na"] = &types.AttributeValueMemberS{Value: *patch.NextAppointment}
 	}
 
+	names := map[string]string{
+		"#pk": "id",
+	}
+	key := map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: id},
+	}
+
+	// An email change swaps the client's guard item in the same transaction as the update.
+	if patch.Email != nil {
+		current, err := r.getClientItem(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to update client: %w", err)
+		}
+		oldEmail := stringAttrS(current, "email")
+		oldGuard, newGuard := guardID(guardEmailPrefix, oldEmail), guardID(guardEmailPrefix, *patch.Email)
+		if oldGuard != newGuard {
+			names["#email"] = "email"
+			values[":old_em"] = &types.AttributeValueMemberS{Value: oldEmail}
+			_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{Update: &types.Update{
+						TableName: aws.String(r.tableName),
+						Key:       key,
+						// Fails if the email changed after it was read
+						ConditionExpression:       aws.String("attribute_exists(#pk) AND #email = :old_em"),
+						UpdateExpression:          aws.String("SET " + strings.Join(parts, ", ")),
+						ExpressionAttributeNames:  names,
+						ExpressionAttributeValues: values,
+					}},
+					deleteGuard(r.tableName, oldGuard, id),
+					putGuard(r.tableName, newGuard, id),
+				},
+			})
+			if err != nil {
+				if conditionFailedAt(err, 2) {
+					return ErrEmailTaken
+				}
+				return fmt.Errorf("failed to update client: %w", err)
+			}
+			return nil
+		}
+	}
+
 	input := &dynamodb.UpdateItemInput{
-		TableName: aws.String(r.tableName),
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: id},
-		},
-		ConditionExpression: aws.String("attribute_exists(#pk)"),
-		UpdateExpression:    aws.String("SET " + strings.Join(parts, ", ")),
-		ExpressionAttributeNames: map[string]string{
-			"#pk": "id",
-		},
+		TableName:                 aws.String(r.tableName),
+		Key:                       key,
+		ConditionExpression:       aws.String("attribute_exists(#pk)"),
+		UpdateExpression:          aws.String("SET " + strings.Join(parts, ", ")),
+		ExpressionAttributeNames:  names,
 		ExpressionAttributeValues: values,
 	}
 
@@ -327,3 +390,21 @@ func (r *ClientRepository) UpdateClient(ctx context.Context, id string, patch Cl
 
 	return nil
 }
+
+// getClientItem reads the raw client item with a strongly consistent read.
+func (r *ClientRepository) getClientItem(ctx context.Context, id string) (map[string]types.AttributeValue, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	if result.Item == nil || isGuardItem(result.Item) {
+		return nil, fmt.Errorf("%w: %s", ErrClientNotFound, id)
+	}
+	return result.Item, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/db"
+)
+
+// GuardConflict is a record whose unique value is already reserved by a different record,
+// meaning the table holds a duplicate that has to be resolved by hand.
+type GuardConflict struct {
+	GuardID string
+	OwnerID string
+	HeldBy  string
+}
+
+// GuardBackfill summarises writing guard items for the records of one table.
+type GuardBackfill struct {
+	Records   int
+	Created   int
+	Conflicts []GuardConflict
+}
+
+// guardedAttribute is a record attribute that must be unique and the prefix of its guards.
+type guardedAttribute struct {
+	name   string
+	prefix string
+}
+
+// BackfillGuards writes the email and username guards of users created before guard items
+// existed. It is safe to run repeatedly and while the server is running.
+func (r *UserRepository) BackfillGuards(ctx context.Context) (*GuardBackfill, error) {
+	ctx = db.WithCaller(ctx, "UserRepository", "BackfillGuards")
+	return backfillGuards(ctx, r.db, r.tableName, []guardedAttribute{
+		{name: "email", prefix: guardEmailPrefix},
+		{name: "username", prefix: guardUsernamePrefix},
+	})
+}
+
+// BackfillGuards writes the email guards of clients created before guard items existed.
+func (r *ClientRepository) BackfillGuards(ctx context.Context) (*GuardBackfill, error) {
+	ctx = db.WithCaller(ctx, "ClientRepository", "BackfillGuards")
+	return backfillGuards(ctx, r.client, r.tableName, []guardedAttribute{
+		{name: "email", prefix: guardEmailPrefix},
+	})
+}
+
+// backfillGuards claims a guard for each guarded attribute of every record in table. A guard
+// that already belongs to the record is left alone; one held by another record is reported as
+// a conflict rather than taken over.
+func backfillGuards(ctx context.Context, client *dynamodb.Client, table string, attrs []guardedAttribute) (*GuardBackfill, error) {
+	result := &GuardBackfill{}
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:        aws.String(table),
+		FilterExpression: aws.String("attribute_not_exists(" + guardOwnerAttr + ")"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		for _, item := range page.Items {
+			ownerID := stringAttrS(item, "id")
+			if ownerID == "" {
+				continue
+			}
+			result.Records++
+			for _, attr := range attrs {
+				value := stringAttrS(item, attr.name)
+				if value == "" {
+					continue
+				}
+				id := guardID(attr.prefix, value)
+				created, heldBy, err := claimGuard(ctx, client, table, id, ownerID)
+				if err != nil {
+					return nil, err
+				}
+				if created {
+					result.Created++
+				} else if heldBy != ownerID {
+					result.Conflicts = append(result.Conflicts, GuardConflict{GuardID: id, OwnerID: ownerID, HeldBy: heldBy})
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// claimGuard creates guard id for ownerID if nobody holds it. Otherwise it returns the current
+// holder.
+func claimGuard(ctx context.Context, client *dynamodb.Client, table, id, ownerID string) (bool, string, error) {
+	put := putGuard(table, id, ownerID).Put
+	_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           put.TableName,
+		Item:                put.Item,
+		ConditionExpression: put.ConditionExpression,
+	})
+	if err == nil {
+		return true, ownerID, nil
+	}
+	var ccf *types.ConditionalCheckFailedException
+	if !errors.As(err, &ccf) {
+		return false, "", fmt.Errorf("failed to write guard %s: %w", id, err)
+	}
+
+	existing, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(table),
+		Key:            map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read guard %s: %w", id, err)
+	}
+	return false, stringAttrS(existing.Item, guardOwnerAttr), nil
+}
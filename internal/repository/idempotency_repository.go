@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/db"
+)
+
+var (
+	// ErrIdempotencyKeyExists is returned by ReserveIdempotencyKey when an unexpired record
+	// already holds the key.
+	ErrIdempotencyKeyExists = apperror.Conflict("idempotency_key_exists", "idempotency key already used")
+	// ErrIdempotencyRecordNotFound is returned when no record exists for the given id.
+	ErrIdempotencyRecordNotFound = apperror.NotFound("idempotency_record_not_found", "idempotency record not found")
+)
+
+// IdempotencyRecord is the first response to a request sent with an Idempotency-Key. Its ID
+// combines the caller and the key, so two callers can use the same key independently. Status is
+// zero while the first request is still being handled.
+type IdempotencyRecord struct {
+	ID          string `dynamodbav:"id"`
+	CallerID    string `dynamodbav:"caller_id"`
+	RequestHash string `dynamodbav:"request_hash"`
+	Method      string `dynamodbav:"method"`
+	Path        string `dynamodbav:"path"`
+	Status      int    `dynamodbav:"status"`
+	// Header holds the response headers the handler set, minus hop-by-hop headers.
+	Header map[string][]string `dynamodbav:"header,omitempty"`
+	// ContentType is only set on records stored before Header was.
+	ContentType string `dynamodbav:"content_type,omitempty"`
+	Body        []byte `dynamodbav:"body,omitempty"`
+	CreatedAt   string `dynamodbav:"created_at"`
+	// TTL lets DynamoDB delete the item once the key expires (epoch seconds). DynamoDB deletes
+	// lazily, so readers must also treat a past TTL as expired.
+	TTL int64 `dynamodbav:"ttl"`
+}
+
+type IdempotencyRepository struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+func NewIdempotencyRepository(db *dynamodb.Client, tableName string) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+// ReserveIdempotencyKey stores record unless an unexpired record already holds its id. now is
+// the current epoch time used to decide whether an existing record has expired.
+func (r *IdempotencyRepository) ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord, now int64) error {
+	ctx = db.WithCaller(ctx, "IdempotencyRepository", "ReserveIdempotencyKey")
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	_, err = r.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(r.tableName),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(id) OR #ttl < :now"),
+		ExpressionAttributeNames: map[string]string{"#ttl": "ttl"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now, 10)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrIdempotencyKeyExists
+		}
+		return fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) GetIdempotencyRecord(ctx context.Context, id string) (*IdempotencyRecord, error) {
+	ctx = db.WithCaller(ctx, "IdempotencyRepository", "GetIdempotencyRecord")
+	result, err := r.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, ErrIdempotencyRecordNotFound
+	}
+
+	var record IdempotencyRecord
+	if err := attributevalue.UnmarshalMap(result.Item, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+
+	return &record, nil
+}
+
+// CompleteIdempotencyRecord stores the response for a reserved key.
+func (r *IdempotencyRepository) CompleteIdempotencyRecord(ctx context.Context, id string, status int, header map[string][]string, body []byte) error {
+	ctx = db.WithCaller(ctx, "IdempotencyRepository", "CompleteIdempotencyRecord")
+	values := map[string]types.AttributeValue{
+		":st": &types.AttributeValueMemberN{Value: strconv.Itoa(status)},
+	}
+	update := "SET #status = :st"
+	if len(header) > 0 {
+		av, err := attributevalue.Marshal(header)
+		if err != nil {
+			return fmt.Errorf("failed to marshal response header: %w", err)
+		}
+		values[":hd"] = av
+		update += ", header = :hd"
+	}
+	if len(body) > 0 {
+		values[":body"] = &types.AttributeValueMemberB{Value: body}
+		update += ", body = :body"
+	}
+
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression:       aws.String("attribute_exists(id)"),
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeNames:  map[string]string{"#status": "status"},
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrIdempotencyRecordNotFound
+		}
+		return fmt.Errorf("failed to complete idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteIdempotencyRecord releases a key so the request can be retried.
+func (r *IdempotencyRepository) DeleteIdempotencyRecord(ctx context.Context, id string) error {
+	ctx = db.WithCaller(ctx, "IdempotencyRepository", "DeleteIdempotencyRecord")
+	_, err := r.db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/db"
+)
+
+// Invitation statuses
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusRevoked  = "revoked"
+)
+
+// ErrInvitationNotPending is returned when a state change requires a pending invitation
+// but the stored invitation has already been accepted or revoked.
+// Invitation errors
+var (
+	ErrInvitationNotFound   = apperror.NotFound("invitation_not_found", "invitation not found")
+	ErrInvitationNotPending = apperror.Conflict("invitation_not_pending", "invitation is no longer pending")
+)
+
+// Invitation lets an admin onboard a staff member with a pre-assigned role.
+type Invitation struct {
+	ID         string `dynamodbav:"id" json:"id"`
+	Email      string `dynamodbav:"email" json:"email"`
+	Role       string `dynamodbav:"role" json:"role"`
+	Status     string `dynamodbav:"status" json:"status"` // "pending", "accepted", "revoked"
+	InvitedBy  string `dynamodbav:"invited_by" json:"invited_by"`
+	AcceptedBy string `dynamodbav:"accepted_by,omitempty" json:"accepted_by,omitempty"`
+	ExpiresAt  string `dynamodbav:"expires_at" json:"expires_at"`
+	CreatedAt  string `dynamodbav:"created_at" json:"created_at"`
+	UpdatedAt  string `dynamodbav:"updated_at" json:"updated_at"`
+}
+
+type InvitationRepository struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+func NewInvitationRepository(db *dynamodb.Client, tableName string) *InvitationRepository {
+	return &InvitationRepository{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+func (r *InvitationRepository) CreateInvitation(ctx context.Context, inv *Invitation) error {
+	ctx = db.WithCaller(ctx, "InvitationRepository", "CreateInvitation")
+	item, err := attributevalue.MarshalMap(inv)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invitation: %w", err)
+	}
+
+	_, err = r.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *InvitationRepository) GetInvitationByID(ctx context.Context, id string) (*Invitation, error) {
+	ctx = db.WithCaller(ctx, "InvitationRepository", "GetInvitationByID")
+	result, err := r.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvitationNotFound, id)
+	}
+
+	var inv Invitation
+	if err := attributevalue.UnmarshalMap(result.Item, &inv); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal invitation: %w", err)
+	}
+
+	return &inv, nil
+}
+
+func (r *InvitationRepository) ListInvitations(ctx context.Context) ([]Invitation, error) {
+	ctx = db.WithCaller(ctx, "InvitationRepository", "ListInvitations")
+	result, err := r.db.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan invitations table: %w", err)
+	}
+
+	invitations := make([]Invitation, 0, len(result.Items))
+	for _, item := range result.Items {
+		var inv Invitation
+		if err := attributevalue.UnmarshalMap(item, &inv); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal invitation: %w", err)
+		}
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, nil
+}
+
+// TransitionInvitation moves a pending invitation to status. The write is conditional so that
+// two concurrent registrations cannot both accept the same invitation.
+func (r *InvitationRepository) TransitionInvitation(ctx context.Context, id, status, acceptedBy string) error {
+	ctx = db.WithCaller(ctx, "InvitationRepository", "TransitionInvitation")
+	values := map[string]types.AttributeValue{
+		":status":  &types.AttributeValueMemberS{Value: status},
+		":pending": &types.AttributeValueMemberS{Value: InvitationStatusPending},
+		":ua":      &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		":ab":      &types.AttributeValueMemberS{Value: acceptedBy},
+	}
+
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("#status = :pending"),
+		UpdateExpression:    aws.String("SET #status = :status, accepted_by = :ab, updated_at = :ua"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrInvitationNotPending
+		}
+		return fmt.Errorf("failed to update invitation: %w", err)
+	}
+
+	return nil
+}
+
+// ReopenInvitation returns an accepted invitation to pending. It is used to undo a claim when
+// creating the invited user fails after the invitation was accepted.
+func (r *InvitationRepository) ReopenInvitation(ctx context.Context, id string) error {
+	ctx = db.WithCaller(ctx, "InvitationRepository", "ReopenInvitation")
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("#status = :accepted"),
+		UpdateExpression:    aws.String("SET #status = :pending, updated_at = :ua REMOVE accepted_by"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":accepted": &types.AttributeValueMemberS{Value: InvitationStatusAccepted},
+			":pending":  &types.AttributeValueMemberS{Value: InvitationStatusPending},
+			":ua":       &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reopen invitation: %w", err)
+	}
+
+	return nil
+}
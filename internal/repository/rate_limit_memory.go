@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often MemoryRateLimitStore drops expired buckets.
+const memorySweepInterval = time.Minute
+
+// MemoryRateLimitStore keeps token buckets in process memory, for a single server. It offers
+// the same methods as RateLimitRepository.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]RateLimitBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]RateLimitBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) GetRateLimitBucket(ctx context.Context, key string) (*RateLimitBucket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		return nil, ErrRateLimitBucketNotFound
+	}
+	return &bucket, nil
+}
+
+// SaveRateLimitBucket stores bucket if the stored bucket is still at version, or does not exist
+// when version is 0. Buckets past their TTL are dropped now and then, so callers that go away
+// do not hold memory.
+func (s *MemoryRateLimitStore) SaveRateLimitBucket(ctx context.Context, bucket *RateLimitBucket, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.buckets[bucket.Key]
+	if (ok && current.Version != version) || (!ok && version != 0) {
+		return ErrRateLimitBucketConflict
+	}
+	s.buckets[bucket.Key] = *bucket
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		s.lastSweep = now
+		for key, b := range s.buckets {
+			if b.TTL < now.Unix() {
+				delete(s.buckets, key)
+			}
+		}
+	}
+	return nil
+}
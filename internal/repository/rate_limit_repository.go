@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/db"
+)
+
+var (
+	// ErrRateLimitBucketNotFound is returned when no bucket exists for the given key.
+	ErrRateLimitBucketNotFound = apperror.NotFound("rate_limit_bucket_not_found", "rate limit bucket not found")
+	// ErrRateLimitBucketConflict is returned by SaveRateLimitBucket when another request
+	// changed the bucket after it was read.
+	ErrRateLimitBucketConflict = apperror.Conflict("rate_limit_bucket_conflict", "rate limit bucket was changed concurrently")
+)
+
+// RateLimitBucket is the token bucket of one caller for one route group. Version increases
+// with every save so concurrent requests on different servers cannot both spend the same token.
+type RateLimitBucket struct {
+	Key    string  `dynamodbav:"id"`
+	Tokens float64 `dynamodbav:"tokens"`
+	// UpdatedAt is when Tokens was last computed, in Unix milliseconds.
+	UpdatedAt int64 `dynamodbav:"updated_at"`
+	Version   int64 `dynamodbav:"version"`
+	// TTL lets DynamoDB delete the bucket once it would be full again (epoch seconds).
+	TTL int64 `dynamodbav:"ttl"`
+}
+
+// RateLimitRepository keeps token buckets in DynamoDB, so every server in a fleet shares them.
+type RateLimitRepository struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+func NewRateLimitRepository(db *dynamodb.Client, tableName string) *RateLimitRepository {
+	return &RateLimitRepository{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+func (r *RateLimitRepository) GetRateLimitBucket(ctx context.Context, key string) (*RateLimitBucket, error) {
+	ctx = db.WithCaller(ctx, "RateLimitRepository", "GetRateLimitBucket")
+	result, err := r.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: key},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit bucket: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, ErrRateLimitBucketNotFound
+	}
+
+	var bucket RateLimitBucket
+	if err := attributevalue.UnmarshalMap(result.Item, &bucket); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rate limit bucket: %w", err)
+	}
+
+	return &bucket, nil
+}
+
+// SaveRateLimitBucket stores bucket if the stored bucket is still at version, or does not exist
+// when version is 0. bucket.Version must be the next version.
+func (r *RateLimitRepository) SaveRateLimitBucket(ctx context.Context, bucket *RateLimitBucket, version int64) error {
+	ctx = db.WithCaller(ctx, "RateLimitRepository", "SaveRateLimitBucket")
+	item, err := attributevalue.MarshalMap(bucket)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate limit bucket: %w", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	}
+	if version > 0 {
+		input.ConditionExpression = aws.String("version = :version")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+		}
+	}
+
+	if _, err := r.db.PutItem(ctx, input); err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrRateLimitBucketConflict
+		}
+		return fmt.Errorf("failed to save rate limit bucket: %w", err)
+	}
+
+	return nil
+}
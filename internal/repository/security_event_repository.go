@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/db"
+)
+
+// SecurityEvent records something that happened to an account: a login attempt, a password
+// change, a revoked session and so on.
+type SecurityEvent struct {
+	ID string `dynamodbav:"id" json:"id"`
+	// UserID is empty for failed logins that matched no account.
+	UserID string `dynamodbav:"user_id,omitempty" json:"user_id,omitempty"`
+	Type   string `dynamodbav:"type" json:"type"`
+	// Login is the username or email given in a failed login that matched no account.
+	Login string `dynamodbav:"login,omitempty" json:"login,omitempty"`
+	// Method is how a login was made: "password" or "oidc".
+	Method    string `dynamodbav:"method,omitempty" json:"method,omitempty"`
+	MFA       bool   `dynamodbav:"mfa" json:"mfa"`
+	Reason    string `dynamodbav:"reason,omitempty" json:"reason,omitempty"`
+	SessionID string `dynamodbav:"session_id,omitempty" json:"session_id,omitempty"`
+	// ActorID is the admin who caused the event, when it was not the user.
+	ActorID   string `dynamodbav:"actor_id,omitempty" json:"actor_id,omitempty"`
+	IPAddress string `dynamodbav:"ip_address,omitempty" json:"ip_address,omitempty"`
+	UserAgent string `dynamodbav:"user_agent,omitempty" json:"user_agent,omitempty"`
+	CreatedAt string `dynamodbav:"created_at" json:"created_at"`
+	// TTL lets DynamoDB delete the item once it is past retention (epoch seconds).
+	TTL int64 `dynamodbav:"ttl" json:"-"`
+}
+
+// SecurityEventFilter narrows ListSecurityEvents. Empty fields match everything; Since and
+// Until are inclusive RFC 3339 timestamps.
+type SecurityEventFilter struct {
+	UserID string
+	Type   string
+	Since  string
+	Until  string
+	Limit  int
+}
+
+type SecurityEventRepository struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+func NewSecurityEventRepository(db *dynamodb.Client, tableName string) *SecurityEventRepository {
+	return &SecurityEventRepository{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+func (r *SecurityEventRepository) CreateSecurityEvent(ctx context.Context, event *SecurityEvent) error {
+	ctx = db.WithCaller(ctx, "SecurityEventRepository", "CreateSecurityEvent")
+	item, err := attributevalue.MarshalMap(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal security event: %w", err)
+	}
+
+	_, err = r.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create security event: %w", err)
+	}
+
+	return nil
+}
+
+// ListSecurityEvents returns matching events, newest first. A user or type filter is served
+// from the user-index or type-index GSI (both sorted by created_at); otherwise the table is
+// scanned.
+func (r *SecurityEventRepository) ListSecurityEvents(ctx context.Context, filter SecurityEventFilter) ([]SecurityEvent, error) {
+	ctx = db.WithCaller(ctx, "SecurityEventRepository", "ListSecurityEvents")
+	if filter.UserID == "" && filter.Type == "" {
+		return r.scanSecurityEvents(ctx, filter)
+	}
+
+	values := map[string]types.AttributeValue{}
+	var filters []string
+	index, keyCondition := "type-index", "#type = :type"
+	if filter.UserID != "" {
+		index, keyCondition = "user-index", "user_id = :uid"
+		values[":uid"] = &types.AttributeValueMemberS{Value: filter.UserID}
+		if filter.Type != "" {
+			filters = append(filters, "#type = :type")
+		}
+	}
+	if filter.Type != "" {
+		values[":type"] = &types.AttributeValueMemberS{Value: filter.Type}
+	}
+	if cond := createdAtCondition(filter, values); cond != "" {
+		keyCondition += " AND " + cond
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tableName),
+		IndexName:                 aws.String(index),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false),
+	}
+	if filter.Type != "" {
+		input.ExpressionAttributeNames = map[string]string{"#type": "type"}
+	}
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+
+	var events []SecurityEvent
+	paginator := dynamodb.NewQueryPaginator(r.db, input)
+	for paginator.HasMorePages() && (filter.Limit <= 0 || len(events) < filter.Limit) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query security events: %w", err)
+		}
+		for _, item := range page.Items {
+			var event SecurityEvent
+			if err := attributevalue.UnmarshalMap(item, &event); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal security event: %w", err)
+			}
+			events = append(events, event)
+		}
+	}
+
+	return limitSecurityEvents(events, filter.Limit), nil
+}
+
+func (r *SecurityEventRepository) scanSecurityEvents(ctx context.Context, filter SecurityEventFilter) ([]SecurityEvent, error) {
+	values := map[string]types.AttributeValue{}
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	}
+	if cond := createdAtCondition(filter, values); cond != "" {
+		input.FilterExpression = aws.String(cond)
+		input.ExpressionAttributeValues = values
+	}
+
+	var events []SecurityEvent
+	paginator := dynamodb.NewScanPaginator(r.db, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan security events: %w", err)
+		}
+		for _, item := range page.Items {
+			var event SecurityEvent
+			if err := attributevalue.UnmarshalMap(item, &event); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal security event: %w", err)
+			}
+			events = append(events, event)
+		}
+	}
+
+	// RFC 3339 timestamps in the same zone sort lexically.
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt > events[j].CreatedAt })
+	return limitSecurityEvents(events, filter.Limit), nil
+}
+
+// createdAtCondition adds the time range of filter to values and returns its condition, or ""
+// when the filter has no range.
+func createdAtCondition(filter SecurityEventFilter, values map[string]types.AttributeValue) string {
+	switch {
+	case filter.Since != "" && filter.Until != "":
+		values[":since"] = &types.AttributeValueMemberS{Value: filter.Since}
+		values[":until"] = &types.AttributeValueMemberS{Value: filter.Until}
+		return "created_at BETWEEN :since AND :until"
+	case filter.Since != "":
+		values[":since"] = &types.AttributeValueMemberS{Value: filter.Since}
+		return "created_at >= :since"
+	case filter.Until != "":
+		values[":until"] = &types.AttributeValueMemberS{Value: filter.Until}
+		return "created_at <= :until"
+	}
+	return ""
+}
+
+func limitSecurityEvents(events []SecurityEvent, limit int) []SecurityEvent {
+	if limit > 0 && len(events) > limit {
+		return events[:limit]
+	}
+	return events
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/db"
+)
+
+// ErrSessionNotFound is returned when no session exists for the given id.
+var ErrSessionNotFound = apperror.NotFound("session_not_found", "session not found")
+
+// Session is one login. Its ID is the jti of the access token issued for it, so revoking the
+// session invalidates that token.
+type Session struct {
+	ID         string `dynamodbav:"id" json:"id"`
+	UserID     string `dynamodbav:"user_id" json:"-"`
+	UserAgent  string `dynamodbav:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IPAddress  string `dynamodbav:"ip_address,omitempty" json:"ip_address,omitempty"`
+	CreatedAt  string `dynamodbav:"created_at" json:"created_at"`
+	LastSeenAt string `dynamodbav:"last_seen_at,omitempty" json:"last_seen_at,omitempty"`
+	ExpiresAt  string `dynamodbav:"expires_at" json:"expires_at"`
+	RevokedAt  string `dynamodbav:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	// TTL lets DynamoDB delete the item once the token has expired (epoch seconds).
+	TTL int64 `dynamodbav:"ttl" json:"-"`
+}
+
+type SessionRepository struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+func NewSessionRepository(db *dynamodb.Client, tableName string) *SessionRepository {
+	return &SessionRepository{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+func (r *SessionRepository) CreateSession(ctx context.Context, session *Session) error {
+	ctx = db.WithCaller(ctx, "SessionRepository", "CreateSession")
+	item, err := attributevalue.MarshalMap(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	_, err = r.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) GetSessionByID(ctx context.Context, id string) (*Session, error) {
+	ctx = db.WithCaller(ctx, "SessionRepository", "GetSessionByID")
+	result, err := r.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	var session Session
+	if err := attributevalue.UnmarshalMap(result.Item, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (r *SessionRepository) ListSessionsByUser(ctx context.Context, userID string) ([]Session, error) {
+	ctx = db.WithCaller(ctx, "SessionRepository", "ListSessionsByUser")
+	// Use the user-index GSI for efficient lookup
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String("user-index"),
+		KeyConditionExpression: aws.String("user_id = :uid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uid": &types.AttributeValueMemberS{Value: userID},
+		},
+	}
+
+	var sessions []Session
+	paginator := dynamodb.NewQueryPaginator(r.db, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query sessions by user: %w", err)
+		}
+		for _, item := range page.Items {
+			var session Session
+			if err := attributevalue.UnmarshalMap(item, &session); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+			}
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession marks the session revoked. The item is kept until its TTL so the token stays
+// rejected for the rest of its lifetime.
+func (r *SessionRepository) RevokeSession(ctx context.Context, id string) error {
+	ctx = db.WithCaller(ctx, "SessionRepository", "RevokeSession")
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		UpdateExpression:    aws.String("SET revoked_at = if_not_exists(revoked_at, :ra)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ra": &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// TouchSession records when the session was last used.
+func (r *SessionRepository) TouchSession(ctx context.Context, id string, seenAt time.Time) error {
+	ctx = db.WithCaller(ctx, "SessionRepository", "TouchSession")
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		UpdateExpression:    aws.String("SET last_seen_at = :ls"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ls": &types.AttributeValueMemberS{Value: seenAt.Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update session last seen time: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+)
+
+// Uniqueness errors, returned when a guard item for the value already exists.
+var (
+	ErrEmailTaken    = apperror.Conflict("email_taken", "email is already in use")
+	ErrUsernameTaken = apperror.Conflict("username_taken", "username is already in use")
+)
+
+// Guard items reserve a unique value in the same table as the record that owns it. Their id is
+// the prefix plus the lowercased value and owner_id is the owning record's id. They carry no
+// other attributes, so they never appear in the email, username or status GSIs. Writing the
+// record and its guards in one TransactWriteItems call makes the uniqueness check atomic.
+const (
+	guardEmailPrefix    = "EMAIL#"
+	guardUsernamePrefix = "USERNAME#"
+	guardOwnerAttr      = "owner_id"
+)
+
+func guardID(prefix, value string) string {
+	return prefix + strings.ToLower(strings.TrimSpace(value))
+}
+
+// EmailGuardID is the id of the guard item reserving email, for tools that write guards directly.
+func EmailGuardID(email string) string {
+	return guardID(guardEmailPrefix, email)
+}
+
+// UsernameGuardID is the id of the guard item reserving username.
+func UsernameGuardID(username string) string {
+	return guardID(guardUsernamePrefix, username)
+}
+
+// isGuardItem reports whether a raw item is a guard rather than a record.
+func isGuardItem(item map[string]types.AttributeValue) bool {
+	_, ok := item[guardOwnerAttr]
+	return ok
+}
+
+// putGuard claims a guard for ownerID; the transaction fails if anyone already holds it.
+func putGuard(table, id, ownerID string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String(table),
+			Item: map[string]types.AttributeValue{
+				"id":           &types.AttributeValueMemberS{Value: id},
+				guardOwnerAttr: &types.AttributeValueMemberS{Value: ownerID},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(id)"),
+		},
+	}
+}
+
+// deleteGuard releases a guard held by ownerID. Records created before guards were introduced
+// have none, so a missing guard is not an error.
+func deleteGuard(table, id, ownerID string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName: aws.String(table),
+			Key: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: id},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(id) OR owner_id = :owner"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":owner": &types.AttributeValueMemberS{Value: ownerID},
+			},
+		},
+	}
+}
+
+// conditionFailedAt reports whether err is a cancelled transaction whose item at index failed
+// its condition check.
+func conditionFailedAt(err error, index int) bool {
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &tce) || index >= len(tce.CancellationReasons) {
+		return false
+	}
+	return aws.ToString(tce.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
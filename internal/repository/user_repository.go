@@ -2,14 +2,23 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/db"
 )
 
+// ErrUserNotFound is returned when no user matches the lookup.
+var ErrUserNotFound = apperror.NotFound("user_not_found", "user not found")
+
+// AuthProviderOIDC marks users who sign in through the corporate identity provider.
+const AuthProviderOIDC = "oidc"
+
 type User struct {
 	ID           string `dynamodbav:"id" json:"id"`
 	Username     string `dynamodbav:"username" json:"username"`
@@ -19,6 +28,10 @@ type User struct {
 	LastName     string `dynamodbav:"last_name" json:"last_name"`
 	Role         string `dynamodbav:"role" json:"role"` // "admin", "user"
 	IsActive     bool   `dynamodbav:"is_active" json:"is_active"`
+	// AuthProvider and ExternalID link the user to an identity provider account ("oidc" and the
+	// ID token subject). Both are empty for password-only users.
+	AuthProvider string `dynamodbav:"auth_provider,omitempty" json:"auth_provider,omitempty"`
+	ExternalID   string `dynamodbav:"external_id,omitempty" json:"-"`
 	CreatedAt    string `dynamodbav:"created_at" json:"created_at"`
 	UpdatedAt    string `dynamodbav:"updated_at" json:"updated_at"`
 }
@@ -28,31 +41,121 @@ type UserRepository struct {
 	tableName string
 }
 
-func NewUserRepository(db *dynamodb.Client) *UserRepository {
+func NewUserRepository(db *dynamodb.Client, tableName string) *UserRepository {
 	return &UserRepository{
 		db:        db,
-		tableName: "users",
+		tableName: tableName,
 	}
 }
 
+// CreateUser writes the user together with guard items for its email and username. It fails
+// with ErrEmailTaken or ErrUsernameTaken if another user holds either.
 func (r *UserRepository) CreateUser(ctx context.Context, user *User) error {
+	ctx = db.WithCaller(ctx, "UserRepository", "CreateUser")
 	item, err := attributevalue.MarshalMap(user)
 	if err != nil {
 		return fmt.Errorf("failed to marshal user: %w", err)
 	}
 
-	_, err = r.db.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(r.tableName),
-		Item:      item,
+	_, err = r.db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:           aws.String(r.tableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(id)"),
+			}},
+			putGuard(r.tableName, guardID(guardEmailPrefix, user.Email), user.ID),
+			putGuard(r.tableName, guardID(guardUsernamePrefix, user.Username), user.ID),
+		},
 	})
 	if err != nil {
+		switch {
+		case conditionFailedAt(err, 1):
+			return ErrEmailTaken
+		case conditionFailedAt(err, 2):
+			return ErrUsernameTaken
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return nil
 }
 
+// UpdateUser replaces an existing user record. When the email or username changes, the old
+// guard is released and the new one claimed in the same transaction.
+func (r *UserRepository) UpdateUser(ctx context.Context, user *User) error {
+	ctx = db.WithCaller(ctx, "UserRepository", "UpdateUser")
+	item, err := attributevalue.MarshalMap(user)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user: %w", err)
+	}
+
+	current, err := r.getUser(ctx, user.ID, true)
+	if err != nil {
+		return err
+	}
+
+	// The email and username conditions make the guard swap fail if another update changed
+	// them after they were read.
+	items := []types.TransactWriteItem{{Put: &types.Put{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("email = :old_email AND username = :old_username"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":old_email":    &types.AttributeValueMemberS{Value: current.Email},
+			":old_username": &types.AttributeValueMemberS{Value: current.Username},
+		},
+	}}}
+	// taken maps the index of each new guard to the error reported when it is already held.
+	taken := map[int]error{}
+	for _, g := range []struct {
+		prefix, old, new string
+		err              error
+	}{
+		{guardEmailPrefix, current.Email, user.Email, ErrEmailTaken},
+		{guardUsernamePrefix, current.Username, user.Username, ErrUsernameTaken},
+	} {
+		oldID, newID := guardID(g.prefix, g.old), guardID(g.prefix, g.new)
+		if oldID == newID {
+			continue
+		}
+		taken[len(items)+1] = g.err
+		items = append(items, deleteGuard(r.tableName, oldID, user.ID), putGuard(r.tableName, newID, user.ID))
+	}
+
+	if len(taken) == 0 {
+		_, err = r.db.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(r.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_exists(id)"),
+		})
+		if err != nil {
+			var ccf *types.ConditionalCheckFailedException
+			if errors.As(err, &ccf) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	}
+
+	_, err = r.db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		for i, takenErr := range taken {
+			if conditionFailedAt(err, i) {
+				return takenErr
+			}
+		}
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return nil
+}
+
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	ctx = db.WithCaller(ctx, "UserRepository", "GetUserByEmail")
 	// Use the email-index GSI for efficient lookup
 	result, err := r.db.Query(ctx, &dynamodb.QueryInput{
 		TableName:              aws.String(r.tableName),
@@ -67,7 +170,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*Use
 	}
 
 	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	var user User
@@ -79,6 +182,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*Use
 }
 
 func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	ctx = db.WithCaller(ctx, "UserRepository", "GetUserByUsername")
 	// Use the username-index GSI for efficient lookup
 	result, err := r.db.Query(ctx, &dynamodb.QueryInput{
 		TableName:              aws.String(r.tableName),
@@ -93,7 +197,7 @@ func (r *UserRepository) GetUserByUsername(ctx context.Context, username string)
 	}
 
 	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	var user User
@@ -105,18 +209,24 @@ func (r *UserRepository) GetUserByUsername(ctx context.Context, username string)
 }
 
 func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	ctx = db.WithCaller(ctx, "UserRepository", "GetUserByID")
+	return r.getUser(ctx, id, false)
+}
+
+func (r *UserRepository) getUser(ctx context.Context, id string, consistent bool) (*User, error) {
 	result, err := r.db.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]types.AttributeValue{
 			"id": &types.AttributeValueMemberS{Value: id},
 		},
+		ConsistentRead: aws.Bool(consistent),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	if result.Item == nil {
-		return nil, fmt.Errorf("user not found")
+	if result.Item == nil || isGuardItem(result.Item) {
+		return nil, ErrUserNotFound
 	}
 
 	var user User
@@ -126,4 +236,3 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*User, err
 
 	return &user, nil
 }
-
@@ -0,0 +1,61 @@
+// Package requestid carries the ID of the HTTP request being served through a context, so the
+// log lines one request produces in the router, services and loggers can be correlated.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header is the request and response header that carries the request ID.
+const Header = "X-Request-ID"
+
+// MaxLength is the longest client-supplied request ID that is kept; longer ones are replaced.
+const MaxLength = 128
+
+type contextKey struct{}
+
+// New returns a fresh request ID.
+func New() string {
+	return uuid.New().String()
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or "" outside a request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Valid reports whether a client-supplied id is safe to reuse: 1-MaxLength printable ASCII
+// characters without spaces, so it cannot break log lines or headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= 0x20 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware reuses the caller's X-Request-ID when it is valid and generates one otherwise. The
+// ID is stored in the request context and echoed in the response header.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !Valid(id) {
+			id = New()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
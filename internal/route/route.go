@@ -0,0 +1,295 @@
+// Package route is a small HTTP routing layer: patterns with {name} path parameters, handlers
+// registered per method, automatic 405 (with an Allow header), HEAD and OPTIONS responses, and
+// groups of routes that share a path prefix and middleware.
+package route
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Middleware wraps a handler, e.g. AuthMiddleware.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Route is one registered method and pattern, such as GET /api/clients/{id}.
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+type paramsKey struct{}
+
+// Param returns the value of the {name} path parameter matched for r, or "".
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// WithParams returns r carrying the given path parameters, as the Mux does when a route with
+// parameters matches. Handler tests use it to call handlers directly.
+func WithParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+}
+
+// Mux dispatches requests to the route whose pattern matches the path. Literal segments take
+// precedence over parameters, so /api/clients/active is never read as a client id.
+type Mux struct {
+	root     Group
+	patterns []*pattern
+	// NotFound serves paths that match no pattern. Defaults to http.NotFound.
+	NotFound http.HandlerFunc
+	// MethodNotAllowed serves paths that match a pattern but not the method, after the Allow
+	// header has been set. Defaults to a plain "Method not allowed".
+	MethodNotAllowed http.HandlerFunc
+}
+
+// Group registers routes under a path prefix, wrapped in the group's middleware.
+type Group struct {
+	mux        *Mux
+	prefix     string
+	middleware []Middleware
+}
+
+type pattern struct {
+	raw      string
+	segments []string
+	handlers map[string]http.HandlerFunc
+}
+
+func New() *Mux {
+	m := &Mux{
+		NotFound: http.NotFound,
+		MethodNotAllowed: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		},
+	}
+	m.root = Group{mux: m}
+	return m
+}
+
+// Group returns a group of routes under prefix wrapped in mw.
+func (m *Mux) Group(prefix string, mw ...Middleware) *Group {
+	return m.root.Group(prefix, mw...)
+}
+
+// Handle registers h for method and pattern with no group middleware.
+func (m *Mux) Handle(method, pattern string, h http.HandlerFunc, mw ...Middleware) {
+	m.root.Handle(method, pattern, h, mw...)
+}
+
+// Group returns a sub-group whose routes are prefixed with prefix and wrapped in mw after the
+// middleware of g.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		mux:        g.mux,
+		prefix:     g.prefix + prefix,
+		middleware: append(append([]Middleware{}, g.middleware...), mw...),
+	}
+}
+
+// Handle registers h for method on the group prefix plus path. mw wraps h inside the group's
+// middleware. Registering the same method and pattern twice panics.
+func (g *Group) Handle(method, path string, h http.HandlerFunc, mw ...Middleware) {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		h = g.middleware[i](h)
+	}
+	g.mux.add(method, g.prefix+path, h)
+}
+
+func (g *Group) Get(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodGet, path, h, mw...)
+}
+
+func (g *Group) Post(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodPost, path, h, mw...)
+}
+
+func (g *Group) Put(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodPut, path, h, mw...)
+}
+
+func (g *Group) Patch(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodPatch, path, h, mw...)
+}
+
+func (g *Group) Delete(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodDelete, path, h, mw...)
+}
+
+func (m *Mux) add(method, raw string, h http.HandlerFunc) {
+	segments := split(raw)
+	for _, p := range m.patterns {
+		if p.raw == raw {
+			if _, exists := p.handlers[method]; exists {
+				panic("route: duplicate route " + method + " " + raw)
+			}
+			p.handlers[method] = h
+			return
+		}
+	}
+	m.patterns = append(m.patterns, &pattern{
+		raw:      raw,
+		segments: segments,
+		handlers: map[string]http.HandlerFunc{method: h},
+	})
+}
+
+// Routes lists every registered route, sorted by pattern then method.
+func (m *Mux) Routes() []Route {
+	var routes []Route
+	for _, p := range m.patterns {
+		for method := range p.handlers {
+			routes = append(routes, Route{Method: method, Pattern: p.raw})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, params := m.match(r.URL.Path)
+	if p == nil {
+		m.NotFound(w, r)
+		return
+	}
+	if len(params) > 0 {
+		r = WithParams(r, params)
+	}
+
+	if h, ok := p.handlers[r.Method]; ok {
+		h(w, r)
+		return
+	}
+	if r.Method == http.MethodHead {
+		if h, ok := p.handlers[http.MethodGet]; ok {
+			h(headResponseWriter{w}, r)
+			return
+		}
+	}
+
+	w.Header().Set("Allow", p.allow())
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	m.MethodNotAllowed(w, r)
+}
+
+// Pattern returns the pattern that serves path, such as /api/clients/{id}, or "" when none
+// matches. Logs and metrics use it to group requests by route rather than by raw path.
+func (m *Mux) Pattern(path string) string {
+	p, _ := m.match(path)
+	if p == nil {
+		return ""
+	}
+	return p.raw
+}
+
+// match returns the most specific pattern matching path and its parameters. At the first
+// segment where two candidates differ, a literal beats a parameter.
+func (m *Mux) match(path string) (*pattern, map[string]string) {
+	segments := split(path)
+	var best *pattern
+	for _, p := range m.patterns {
+		if !p.matches(segments) {
+			continue
+		}
+		if best == nil || p.moreSpecificThan(best) {
+			best = p
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	var params map[string]string
+	for i, seg := range best.segments {
+		if name, ok := paramName(seg); ok {
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[name] = segments[i]
+		}
+	}
+	return best, params
+}
+
+func (p *pattern) matches(segments []string) bool {
+	if len(segments) != len(p.segments) {
+		return false
+	}
+	for i, seg := range p.segments {
+		if _, ok := paramName(seg); ok {
+			if segments[i] == "" {
+				return false
+			}
+		} else if seg != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pattern) moreSpecificThan(other *pattern) bool {
+	for i, seg := range p.segments {
+		_, isParam := paramName(seg)
+		_, otherIsParam := paramName(other.segments[i])
+		if isParam != otherIsParam {
+			return !isParam
+		}
+	}
+	return false
+}
+
+// allow lists the methods the pattern accepts, including the automatic HEAD and OPTIONS.
+func (p *pattern) allow() string {
+	methods := []string{http.MethodOptions}
+	for method := range p.handlers {
+		if method != http.MethodOptions {
+			methods = append(methods, method)
+		}
+	}
+	if _, ok := p.handlers[http.MethodGet]; ok {
+		if _, ok := p.handlers[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// split breaks a path into segments, ignoring one trailing slash.
+func split(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func paramName(segment string) (string, bool) {
+	if len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}' {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// headResponseWriter answers HEAD with the headers and status of GET but no body.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,180 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// respond returns a handler that writes body, so tests can tell which route served a request.
+func respond(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Route", body)
+		w.Write([]byte(body + " " + Param(r, "id")))
+	}
+}
+
+func newTestMux() *Mux {
+	mux := New()
+	clients := mux.Group("/api/clients")
+	clients.Get("", respond("list"))
+	clients.Post("/add", respond("add"))
+	clients.Get("/active", respond("active"))
+	clients.Get("/{id}", respond("get"))
+	clients.Put("/{id}", respond("update"))
+	clients.Patch("/{id}", respond("update"))
+	clients.Get("/{id}/notes", respond("notes"))
+	return mux
+}
+
+func serve(mux *Mux, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestMux_Dispatch(t *testing.T) {
+	mux := newTestMux()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Literal route", method: http.MethodGet, path: "/api/clients", expectedStatus: http.StatusOK, expectedBody: "list "},
+		{name: "Parameter", method: http.MethodGet, path: "/api/clients/c-1", expectedStatus: http.StatusOK, expectedBody: "get c-1"},
+		{name: "Parameter before literal", method: http.MethodGet, path: "/api/clients/c-1/notes", expectedStatus: http.StatusOK, expectedBody: "notes c-1"},
+		{name: "Literal beats parameter", method: http.MethodGet, path: "/api/clients/active", expectedStatus: http.StatusOK, expectedBody: "active "},
+		{name: "Method picks the handler", method: http.MethodPatch, path: "/api/clients/c-1", expectedStatus: http.StatusOK, expectedBody: "update c-1"},
+		{name: "Trailing slash", method: http.MethodGet, path: "/api/clients/c-1/", expectedStatus: http.StatusOK, expectedBody: "get c-1"},
+		{name: "Trailing slash on literal", method: http.MethodGet, path: "/api/clients/", expectedStatus: http.StatusOK, expectedBody: "list "},
+		{name: "Empty parameter segment", method: http.MethodGet, path: "/api/clients//notes", expectedStatus: http.StatusNotFound},
+		{name: "Extra segment", method: http.MethodGet, path: "/api/clients/c-1/notes/n-1", expectedStatus: http.StatusNotFound},
+		{name: "Unknown path", method: http.MethodGet, path: "/api/unknown", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, tt.method, tt.path)
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMux_MethodNotAllowed(t *testing.T) {
+	mux := newTestMux()
+
+	rec := serve(mux, http.MethodDelete, "/api/clients/c-1")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS, PATCH, PUT" {
+		t.Errorf("Expected Allow to list the pattern's methods, got %q", got)
+	}
+
+	mux.MethodNotAllowed = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}
+	rec = serve(mux, http.MethodGet, "/api/clients/add")
+	if rec.Code != http.StatusTeapot || rec.Header().Get("Allow") != "OPTIONS, POST" {
+		t.Errorf("Expected the custom handler after Allow is set, got %d and Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestMux_Head(t *testing.T) {
+	mux := newTestMux()
+
+	rec := serve(mux, http.MethodHead, "/api/clients/c-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected HEAD to be served by GET, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Route") != "get" {
+		t.Errorf("Expected the GET handler's headers, got %v", rec.Header())
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected no body, got %q", rec.Body.String())
+	}
+
+	if rec := serve(mux, http.MethodHead, "/api/clients/add"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for HEAD on a route without GET, got %d", rec.Code)
+	}
+}
+
+func TestMux_Options(t *testing.T) {
+	rec := serve(newTestMux(), http.MethodOptions, "/api/clients")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow %q, got %q", "GET, HEAD, OPTIONS", got)
+	}
+}
+
+func TestMux_DuplicateRoutePanics(t *testing.T) {
+	mux := newTestMux()
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering GET /api/clients/{id} twice to panic")
+		}
+	}()
+	mux.Handle(http.MethodGet, "/api/clients/{id}", respond("again"))
+}
+
+func TestGroup_MiddlewareOrder(t *testing.T) {
+	var calls []string
+	mark := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next(w, r)
+			}
+		}
+	}
+
+	mux := New()
+	api := mux.Group("/api", mark("outer"))
+	admin := api.Group("/admin", mark("inner"))
+	admin.Get("/keys", func(w http.ResponseWriter, r *http.Request) { calls = append(calls, "handler") }, mark("route"))
+
+	serve(mux, http.MethodGet, "/api/admin/keys")
+
+	if got := strings.Join(calls, ","); got != "outer,inner,route,handler" {
+		t.Errorf("Expected outer,inner,route,handler, got %s", got)
+	}
+}
+
+func TestMux_Pattern(t *testing.T) {
+	mux := newTestMux()
+
+	tests := map[string]string{
+		"/api/clients":         "/api/clients",
+		"/api/clients/active":  "/api/clients/active",
+		"/api/clients/c-1":     "/api/clients/{id}",
+		"/api/clients/c-1/":    "/api/clients/{id}",
+		"/api/clients/x/notes": "/api/clients/{id}/notes",
+		"/api/unknown":         "",
+	}
+	for path, want := range tests {
+		if got := mux.Pattern(path); got != want {
+			t.Errorf("Pattern(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMux_Routes(t *testing.T) {
+	routes := newTestMux().Routes()
+	if len(routes) != 7 {
+		t.Fatalf("Expected 7 routes, got %d: %v", len(routes), routes)
+	}
+	if routes[0] != (Route{Method: http.MethodGet, Pattern: "/api/clients"}) {
+		t.Errorf("Expected routes sorted by pattern then method, got %v first", routes[0])
+	}
+}
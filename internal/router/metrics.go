@@ -0,0 +1,106 @@
+package router
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/config"
+	"github.com/jmason/john_ai_project/internal/handler"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/metrics"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// unmatchedRoute labels requests no route matched, so unknown paths cannot grow the label set.
+const unmatchedRoute = "unmatched"
+
+// HTTP metrics, labelled by route template rather than path, and business gauges refreshed by
+// watchClientStats.
+var (
+	httpRequests = metrics.NewCounter("http_requests_total",
+		"HTTP requests served, by method, route template and status.", "method", "route", "status")
+	httpDuration = metrics.NewHistogram("http_request_duration_seconds",
+		"HTTP request latency in seconds, by method, route template and status.", nil, "method", "route", "status")
+	clientsGauge = metrics.NewGauge("clients",
+		"Clients by status.", "status")
+	intakeQueueGauge = metrics.NewGauge("intake_queue_depth",
+		"Active clients with no appointment booked, by urgency (\"none\" when unset).", "urgency")
+)
+
+// newMetricsHandler returns the /metrics handler, or nil when the endpoint must not be served.
+// API Gateway forwards every path to the server, so outside development the metrics are only
+// served behind METRICS_TOKEN.
+func newMetricsHandler(cfg *config.Config) http.HandlerFunc {
+	if cfg.Metrics.Token == "" && !cfg.Development() {
+		serverLog.Warn("GET /metrics disabled; set METRICS_TOKEN to serve it")
+		return nil
+	}
+	return metricsHandler(cfg.Metrics.Token)
+}
+
+// metricsHandler serves the registered metrics. With a token, scrapers must send
+// "Authorization: Bearer <token>", since API Gateway forwards every path to the server.
+func metricsHandler(token string) http.HandlerFunc {
+	serve := metrics.Handler()
+	if token == "" {
+		return serve
+	}
+	want := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			handler.RespondProblem(w, r, handler.Problem{
+				Status: http.StatusUnauthorized,
+				Code:   "metrics_unauthorized",
+				Detail: "A valid metrics bearer token is required",
+			})
+			return
+		}
+		serve(w, r)
+	}
+}
+
+// observeRequest records one served request.
+func observeRequest(method, pattern string, status int, elapsed time.Duration) {
+	if pattern == "" {
+		pattern = unmatchedRoute
+	}
+	code := strconv.Itoa(status)
+	httpRequests.Inc(method, pattern, code)
+	httpDuration.Observe(elapsed.Seconds(), method, pattern, code)
+}
+
+// watchClientStats refreshes the client gauges now and then every interval until ctx ends.
+// Counting scans the clients table, so the interval should be well above the scrape interval.
+func watchClientStats(ctx context.Context, clients *service.ClientService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		refreshClientStats(ctx, clients)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func refreshClientStats(ctx context.Context, clients *service.ClientService) {
+	stats, err := clients.Stats(ctx)
+	if err != nil {
+		serverLog.WarnContext(ctx, "failed to refresh client metrics", logger.Error(err))
+		return
+	}
+	for status, n := range stats.ByStatus {
+		clientsGauge.Set(float64(n), status)
+	}
+	for urgency, n := range stats.AwaitingIntake {
+		if urgency == "" {
+			urgency = "none"
+		}
+		intakeQueueGauge.Set(float64(n), urgency)
+	}
+}
@@ -0,0 +1,232 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/jmason/john_ai_project/internal/handler"
+	"github.com/jmason/john_ai_project/internal/health"
+	"github.com/jmason/john_ai_project/internal/openapi"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/service"
+)
+
+// Errors every authenticated route can return on top of its own: no or bad token, the caller's
+// rate limit, and for admin and client routes the wrong role or API key scope.
+var (
+	authErrors       = []int{http.StatusUnauthorized, http.StatusTooManyRequests}
+	restrictedErrors = []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests}
+)
+
+// idempotencyKeyParam documents the optional Idempotency-Key header accepted by authenticated writes.
+var idempotencyKeyParam = openapi.Parameter{
+	Name: handler.IdempotencyKeyHeader,
+	Description: "Optional key that makes the request safe to retry: a repeat with the same key and body " +
+		"returns the first response, and reusing the key with a different body returns 422.",
+}
+
+func withErrors(common []int, codes ...int) []int {
+	return append(append([]int{}, common...), codes...)
+}
+
+// apiSpec describes every route in registerRoutes for the OpenAPI document. Request and response
+// schemas come from the handler and repository types, so only the prose lives here.
+func apiSpec() openapi.Spec {
+	clientUpdate := func(method, pattern, summary string) openapi.Endpoint {
+		return openapi.Endpoint{
+			Method: method, Pattern: pattern, Tag: "Clients", Summary: summary,
+			Description: "Partial update: include only the fields to change. Requires the clients:write scope for API keys.",
+			Request:     handler.UpdateClientRequest{},
+			Response:    repository.Client{},
+			Errors:      withErrors(restrictedErrors, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError),
+		}
+	}
+
+	spec := openapi.Spec{
+		Info: openapi.Info{
+			Title:       "John AI API",
+			Version:     "1.0",
+			Description: "Client management and authentication API.",
+		},
+		Tags: []openapi.Tag{
+			{Name: "System", Description: "Health, signing keys and this description"},
+			{Name: "Auth", Description: "Registration, login and SSO"},
+			{Name: "Account", Description: "The signed-in user's profile, password and sessions"},
+			{Name: "Clients", Description: "Client records"},
+			{Name: "Admin", Description: "Invitations, API keys, security events and impersonation (admin role)"},
+		},
+		Error:            handler.Problem{},
+		ErrorContentType: handler.ProblemContentType,
+		Customize: map[string]func(*openapi.Schema){
+			// Client.MarshalJSON adds display helpers.
+			"Client": func(s *openapi.Schema) {
+				s.Properties["name"] = &openapi.Schema{Type: "string", Description: "First and last name"}
+				s.Properties["initial_consult_notes"] = &openapi.Schema{Type: "string", Description: "Body of the first note"}
+			},
+		},
+		Endpoints: []openapi.Endpoint{
+			// System
+			{Method: http.MethodGet, Pattern: "/health", Tag: "System", Summary: "Health check", Public: true, Response: handler.HealthResponse{}},
+			{
+				Method: http.MethodGet, Pattern: "/healthz", Tag: "System", Summary: "Liveness probe", Public: true,
+				Description: "Answers while the process is running, without checking dependencies.",
+				Response:    handler.HealthResponse{},
+			},
+			{
+				Method: http.MethodGet, Pattern: "/readyz", Tag: "System", Summary: "Readiness probe", Public: true,
+				Description: "Checks that DynamoDB answers, that the clients and users tables and their indexes are ready, and whether logs are reaching CloudWatch. " +
+					"Returns 503 with the same body when any check is unavailable; a degraded CloudWatch sink does not fail readiness. Results are cached for READINESS_CACHE_TTL (default 5s).",
+				Response: health.Report{},
+			},
+			{Method: http.MethodGet, Pattern: "/.well-known/jwks.json", Tag: "System", Summary: "Public keys for verifying tokens", Public: true, Response: service.JWKS{}},
+			{
+				Method: http.MethodGet, Pattern: "/metrics", Tag: "System", Summary: "Prometheus metrics (text exposition format)", Public: true,
+				Description: "HTTP request counts and latency by route template, login outcomes, DynamoDB latency, errors and throttles per repository method, and client gauges. Requires `Authorization: Bearer <METRICS_TOKEN>` when METRICS_TOKEN is set; outside development the endpoint is not served without it.",
+			},
+			{Method: http.MethodGet, Pattern: "/openapi.json", Tag: "System", Summary: "This OpenAPI 3 document (JSON)", Public: true},
+			{Method: http.MethodGet, Pattern: "/docs", Tag: "System", Summary: "Browsable API docs (HTML)", Public: true},
+
+			// Auth
+			{
+				Method: http.MethodPost, Pattern: "/api/auth/register", Tag: "Auth", Summary: "Register a new user", Public: true,
+				Description: "invite_token is required when OPEN_REGISTRATION=false; the invited role is applied.",
+				Request:     handler.RegisterRequest{}, Status: http.StatusCreated, Response: handler.AuthResponse{},
+				Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusConflict, http.StatusInternalServerError, http.StatusTooManyRequests},
+			},
+			{
+				Method: http.MethodPost, Pattern: "/api/auth/login", Tag: "Auth", Summary: "Log in with username or email and password", Public: true,
+				Request: handler.LoginRequest{}, Response: handler.AuthResponse{},
+				Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests},
+			},
+			{
+				Method: http.MethodPost, Pattern: "/api/auth/email/verify", Tag: "Auth", Summary: "Confirm an email change with the emailed token", Public: true,
+				Request: handler.VerifyEmailRequest{}, Response: repository.User{},
+				Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError, http.StatusTooManyRequests},
+			},
+			{
+				Method: http.MethodGet, Pattern: "/api/auth/oidc/login", Tag: "Auth", Summary: "Start SSO login", Public: true,
+				Description: "Redirects to the identity provider. Only available when OIDC_ISSUER_URL is set.",
+				Status:      http.StatusFound, Errors: []int{http.StatusBadGateway, http.StatusTooManyRequests},
+			},
+			{
+				Method: http.MethodGet, Pattern: "/api/auth/oidc/callback", Tag: "Auth", Summary: "Complete SSO login", Public: true,
+				Description: "With OIDC_FRONTEND_URL set, redirects there with #token=... or #error=<code> instead of answering with JSON.",
+				Query: []openapi.Parameter{
+					{Name: "code", Description: "Authorization code from the identity provider"},
+					{Name: "state", Description: "State echoed by the identity provider"},
+				},
+				Response: handler.AuthResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError, http.StatusTooManyRequests},
+			},
+
+			// Account
+			{Method: http.MethodGet, Pattern: "/api/auth/me", Tag: "Account", Summary: "Current user", Response: repository.User{}, Errors: withErrors(authErrors, http.StatusNotFound)},
+			{
+				Method: http.MethodPatch, Pattern: "/api/auth/me", Tag: "Account", Summary: "Update name or email",
+				Description: "A new email is only applied once confirmed through the emailed link.",
+				Request:     handler.UpdateProfileRequest{}, Response: handler.UpdateProfileResponse{},
+				Errors: withErrors(authErrors, http.StatusBadRequest, http.StatusForbidden, http.StatusConflict, http.StatusInternalServerError),
+			},
+			{
+				Method: http.MethodGet, Pattern: "/api/auth/me/security-events", Tag: "Account", Summary: "Your login history and account changes",
+				Query:    []openapi.Parameter{{Name: "limit", Description: "Maximum number of events", Schema: &openapi.Schema{Type: "integer"}}},
+				Response: []repository.SecurityEvent{},
+				Errors:   withErrors(authErrors, http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError, http.StatusNotImplemented),
+			},
+			{
+				Method: http.MethodPost, Pattern: "/api/auth/password", Tag: "Account", Summary: "Change password",
+				Description: "Signs out every other session.",
+				Request:     handler.ChangePasswordRequest{}, Status: http.StatusNoContent,
+				Errors: withErrors(authErrors, http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError),
+			},
+			{Method: http.MethodGet, Pattern: "/api/auth/sessions", Tag: "Account", Summary: "List active sessions", Response: []repository.Session{}, Errors: withErrors(authErrors, http.StatusForbidden, http.StatusInternalServerError)},
+			{
+				Method: http.MethodDelete, Pattern: "/api/auth/sessions", Tag: "Account", Summary: "Sign out all other sessions",
+				Response: handler.RevokeSessionsResponse{},
+				Errors:   withErrors(authErrors, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented),
+			},
+			{
+				Method: http.MethodDelete, Pattern: "/api/auth/sessions/{id}", Tag: "Account", Summary: "Sign out one session",
+				Status: http.StatusNoContent,
+				Errors: withErrors(authErrors, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError),
+			},
+
+			// Clients
+			{Method: http.MethodGet, Pattern: "/api/clients", Tag: "Clients", Summary: "List all clients", Response: []repository.Client{}, Errors: withErrors(restrictedErrors, http.StatusInternalServerError)},
+			{Method: http.MethodGet, Pattern: "/api/clients/active", Tag: "Clients", Summary: "List active clients", Response: []repository.Client{}, Errors: withErrors(restrictedErrors, http.StatusInternalServerError)},
+			{Method: http.MethodGet, Pattern: "/api/clients/inactive", Tag: "Clients", Summary: "List inactive clients", Response: []repository.Client{}, Errors: withErrors(restrictedErrors, http.StatusInternalServerError)},
+			{
+				Method: http.MethodGet, Pattern: "/api/clients/by-email", Tag: "Clients", Summary: "Find a client by email",
+				Query:    []openapi.Parameter{{Name: "email", Required: true}},
+				Response: repository.Client{},
+				Errors:   withErrors(restrictedErrors, http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError),
+			},
+			{
+				Method: http.MethodPost, Pattern: "/api/clients/add", Tag: "Clients", Summary: "Create a client",
+				Description: "first_name, last_name and email are required. Requires the clients:write scope for API keys.",
+				Request:     handler.CreateClientRequest{}, Status: http.StatusCreated, Response: repository.Client{},
+				Errors: withErrors(restrictedErrors, http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError),
+			},
+			{Method: http.MethodGet, Pattern: "/api/clients/{id}", Tag: "Clients", Summary: "Get a client", Response: repository.Client{}, Errors: withErrors(restrictedErrors, http.StatusBadRequest, http.StatusNotFound)},
+			clientUpdate(http.MethodPut, "/api/clients/{id}", "Update a client"),
+			clientUpdate(http.MethodPatch, "/api/clients/{id}", "Update a client"),
+			clientUpdate(http.MethodPut, "/api/clients/update/{id}", "Update a client (alternate path)"),
+			clientUpdate(http.MethodPatch, "/api/clients/update/{id}", "Update a client (alternate path)"),
+
+			// Admin
+			{
+				Method: http.MethodPost, Pattern: "/api/invitations", Tag: "Admin", Summary: "Invite a user with a role",
+				Request: handler.CreateInvitationRequest{}, Status: http.StatusCreated, Response: handler.InvitationResponse{},
+				Errors: withErrors(restrictedErrors, http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError),
+			},
+			{Method: http.MethodGet, Pattern: "/api/invitations", Tag: "Admin", Summary: "List invitations", Response: []repository.Invitation{}, Errors: withErrors(restrictedErrors, http.StatusInternalServerError)},
+			{
+				Method: http.MethodDelete, Pattern: "/api/invitations/{id}", Tag: "Admin", Summary: "Revoke a pending invitation",
+				Status: http.StatusNoContent,
+				Errors: withErrors(restrictedErrors, http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError),
+			},
+			{
+				Method: http.MethodPost, Pattern: "/api/api-keys", Tag: "Admin", Summary: "Create a scoped API key",
+				Description: "The key is only returned once.",
+				Request:     handler.CreateAPIKeyRequest{}, Status: http.StatusCreated, Response: handler.CreateAPIKeyResponse{},
+				Errors: withErrors(restrictedErrors, http.StatusBadRequest, http.StatusInternalServerError),
+			},
+			{Method: http.MethodGet, Pattern: "/api/api-keys", Tag: "Admin", Summary: "List API keys", Response: []repository.APIKey{}, Errors: withErrors(restrictedErrors, http.StatusInternalServerError)},
+			{
+				Method: http.MethodDelete, Pattern: "/api/api-keys/{id}", Tag: "Admin", Summary: "Revoke an API key",
+				Status: http.StatusNoContent,
+				Errors: withErrors(restrictedErrors, http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError),
+			},
+			{
+				Method: http.MethodGet, Pattern: "/api/security-events", Tag: "Admin", Summary: "Security events for all users",
+				Query: []openapi.Parameter{
+					{Name: "user_id"},
+					{Name: "type", Description: "e.g. login_failed"},
+					{Name: "since", Description: "RFC 3339 time", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+					{Name: "until", Description: "RFC 3339 time", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+					{Name: "limit", Schema: &openapi.Schema{Type: "integer"}},
+				},
+				Response: []repository.SecurityEvent{},
+				Errors:   withErrors(restrictedErrors, http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented),
+			},
+			{
+				Method: http.MethodPost, Pattern: "/api/auth/impersonate/{id}", Tag: "Admin", Summary: "Act as a user with a short-lived token",
+				Description: "The token is read-only unless IMPERSONATION_ALLOW_WRITES=true.",
+				Response:    handler.ImpersonationResponse{},
+				Errors:      withErrors(restrictedErrors, http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError),
+			},
+		},
+	}
+
+	// Every authenticated write outside the admin routes runs the idempotency middleware.
+	for i, e := range spec.Endpoints {
+		if e.Public || e.Tag == "Admin" {
+			continue
+		}
+		switch e.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			spec.Endpoints[i].Headers = append(e.Headers, idempotencyKeyParam)
+			spec.Endpoints[i].Errors = withErrors(e.Errors, http.StatusConflict, http.StatusUnprocessableEntity)
+		}
+	}
+	return spec
+}
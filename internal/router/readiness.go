@@ -0,0 +1,111 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/jmason/john_ai_project/internal/config"
+	"github.com/jmason/john_ai_project/internal/db"
+	"github.com/jmason/john_ai_project/internal/handler"
+	"github.com/jmason/john_ai_project/internal/health"
+	"github.com/jmason/john_ai_project/internal/logger"
+)
+
+// requiredTables are the tables and indexes the client and auth routes cannot serve without.
+func requiredTables(tables config.Tables) []db.Table {
+	return []db.Table{
+		{Name: tables.Clients, Indexes: []string{"email-index", "status-index"}},
+		{Name: tables.Users, Indexes: []string{"email-index", "username-index"}},
+	}
+}
+
+// cloudWatchFailureWindow is how long after a dropped batch the CloudWatch sink reports
+// degraded when nothing has been delivered since.
+const cloudWatchFailureWindow = 5 * time.Minute
+
+// newReadinessChecker checks that DynamoDB answers, that requiredTables are ready, and whether
+// logs are reaching CloudWatch. Only DynamoDB can make the server unready: losing log shipping
+// is reported as degraded.
+func newReadinessChecker(dbClient *db.Client, tables config.Tables, cloudWatch *logger.CloudWatchHandler, ttl time.Duration) *health.Checker {
+	checker := health.NewChecker(ttl, 3*time.Second)
+	checker.Add("dynamodb", func(ctx context.Context) health.CheckResult {
+		if err := dbClient.Ping(ctx); err != nil {
+			return dynamoDBUnavailable(ctx, "dynamodb", err)
+		}
+		return health.OK()
+	})
+	for _, table := range requiredTables(tables) {
+		table := table
+		name := "table:" + table.Name
+		checker.Add(name, func(ctx context.Context) health.CheckResult {
+			if err := dbClient.CheckTable(ctx, table); err != nil {
+				return dynamoDBUnavailable(ctx, name, err)
+			}
+			return health.OK()
+		})
+	}
+	checker.Add("cloudwatch", func(context.Context) health.CheckResult {
+		if cloudWatch == nil {
+			return health.CheckResult{Status: health.StatusDisabled}
+		}
+		return cloudWatchResult(cloudWatch.Status(), time.Now())
+	})
+	return checker
+}
+
+// dynamoDBUnavailable logs err and reports it without AWS error messages, which can carry
+// account and role ARNs, since /readyz is public.
+func dynamoDBUnavailable(ctx context.Context, check string, err error) health.CheckResult {
+	serverLog.WarnContext(ctx, "readiness check failed", "check", check, logger.Error(err))
+	if errors.Is(err, db.ErrTableNotReady) {
+		return health.Unavailable(err)
+	}
+	detail := "DynamoDB request failed"
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		detail += ": " + apiErr.ErrorCode()
+	}
+	return health.CheckResult{Status: health.StatusUnavailable, Detail: detail}
+}
+
+func cloudWatchResult(st logger.CloudWatchStatus, now time.Time) health.CheckResult {
+	info := map[string]interface{}{
+		"buffered": st.Buffered,
+		"dropped":  st.Dropped,
+	}
+	if !st.LastSent.IsZero() {
+		info["last_sent"] = st.LastSent
+	}
+	switch {
+	case st.Closed:
+		return health.CheckResult{Status: health.StatusDegraded, Detail: "log shipping has stopped", Info: info}
+	case st.LastError != "" && now.Sub(st.LastErrorAt) < cloudWatchFailureWindow:
+		return health.CheckResult{Status: health.StatusDegraded, Detail: "recent log batches were rejected", Info: info}
+	}
+	return health.CheckResult{Status: health.StatusOK, Info: info}
+}
+
+// liveness answers the liveness probes, /health and /healthz. It checks no dependencies: the
+// process answering is enough.
+func liveness(w http.ResponseWriter, r *http.Request) {
+	handler.RespondJSON(w, http.StatusOK, handler.HealthResponse{
+		Status:  "ok",
+		Message: "Server is running",
+	})
+}
+
+// readinessHandler serves the readiness report, with 503 when the server should not take traffic.
+func readinessHandler(checker *health.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Check(r.Context())
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		handler.RespondJSON(w, status, report)
+	}
+}
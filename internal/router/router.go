@@ -3,7 +3,7 @@ package router
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,306 +11,371 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/jmason/john_ai_project/internal/config"
+	"github.com/jmason/john_ai_project/internal/cors"
 	"github.com/jmason/john_ai_project/internal/db"
 	"github.com/jmason/john_ai_project/internal/handler"
 	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/mailer"
+	"github.com/jmason/john_ai_project/internal/openapi"
 	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/requestid"
+	"github.com/jmason/john_ai_project/internal/route"
 	"github.com/jmason/john_ai_project/internal/service"
+	"github.com/jmason/john_ai_project/internal/tracing"
+	"net/url"
+	"path"
 )
 
 // Router handles HTTP routing
 type Router struct {
-	mux           *http.ServeMux
-	server        *http.Server
-	handler       *handler.ClientHandler
-	cloudWatchLog *logger.CloudWatchLogger
+	mux     *route.Mux
+	apiDoc  *openapi.Document
+	server  *http.Server
+	handler *handler.ClientHandler
+	// cloudWatch is nil unless logs are shipped to CloudWatch Logs.
+	cloudWatch *logger.CloudWatchHandler
+	// shutdownTracing exports the spans still buffered and stops tracing.
+	shutdownTracing func(context.Context) error
+	// shutdownTimeout bounds graceful shutdown, including flushing logs and traces.
+	shutdownTimeout time.Duration
 }
 
-func NewRouter(ctx context.Context) (*Router, error) {
-	dbClient, err := db.NewClient(ctx)
+// Loggers for server lifecycle and configuration, and for the access log.
+var (
+	serverLog = logger.Component("server")
+	httpLog   = logger.Component("http")
+)
+
+// NewRouter wires the server from cfg, which must have passed cfg.Validate.
+func NewRouter(ctx context.Context, cfg *config.Config) (*Router, error) {
+	logLevels, err := logger.ParseLevels(cfg.Log.Level)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create DB client: %w", err)
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
 	}
 
-	if err := dbClient.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping DynamoDB: %w", err)
+	dbClient, err := db.NewClient(ctx, cfg.AWS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB client: %w", err)
 	}
 
-	var cwLogger *logger.CloudWatchLogger
-	logGroupName := getEnv("CLOUDWATCH_LOG_GROUP", "/aws/ec2/john-ai-backend")
-	logStreamName := getEnv("CLOUDWATCH_LOG_STREAM", "api-server")
+	logHandlers := []slog.Handler{logger.NewJSONHandler(os.Stderr)}
+	cloudWatch := newCloudWatchHandler(ctx, cfg.CloudWatch, dbClient.AWSConfig)
+	if cloudWatch != nil {
+		logHandlers = append(logHandlers, cloudWatch)
+	}
+	logger.Configure(logger.Options{
+		Levels:          logLevels,
+		SensitiveFields: cfg.Log.RedactFields,
+		// Local development reads invitation and email verification links from the log.
+		DisableRedaction: cfg.Development() && !cfg.Log.Redaction,
+	}, logHandlers...)
+	serverLog.Info("effective configuration", "config", cfg)
+	if cloudWatch != nil {
+		serverLog.Info("shipping logs to CloudWatch", "log_group", cloudWatch.LogGroupName(), "log_stream", cloudWatch.LogStreamName())
+	}
 
-	cwLogger, err = logger.NewCloudWatchLogger(ctx, logGroupName, logStreamName)
+	// The OTLP exporter reads its endpoint from OTEL_EXPORTER_OTLP_ENDPOINT or
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT.
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
 	if err != nil {
-		log.Printf("Warning: CloudWatch logging not available: %v", err)
+		return nil, fmt.Errorf("invalid tracing configuration: %w", err)
+	}
+
+	if err := dbClient.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping DynamoDB: %w", err)
 	}
 
 	// Setup repositories
-	clientRepo := repository.NewClientRepository(dbClient.DynamoDB)
-	userRepo := repository.NewUserRepository(dbClient.DynamoDB)
+	clientRepo := repository.NewClientRepository(dbClient.DynamoDB, cfg.Tables.Clients)
+	userRepo := repository.NewUserRepository(dbClient.DynamoDB, cfg.Tables.Users)
+	invitationRepo := repository.NewInvitationRepository(dbClient.DynamoDB, cfg.Tables.Invitations)
+	apiKeyRepo := repository.NewAPIKeyRepository(dbClient.DynamoDB, cfg.Tables.APIKeys)
+	sessionRepo := repository.NewSessionRepository(dbClient.DynamoDB, cfg.Tables.Sessions)
+	securityEventRepo := repository.NewSecurityEventRepository(dbClient.DynamoDB, cfg.Tables.SecurityEvents)
+	idempotencyRepo := repository.NewIdempotencyRepository(dbClient.DynamoDB, cfg.Tables.IdempotencyKeys)
 
 	// Setup services
 	clientService := service.NewClientService(clientRepo)
-	jwtSecret := getEnv("JWT_SECRET", "your-secret-key-CHANGE-IN-PRODUCTION-via-env-var")
-	authService := service.NewAuthService(userRepo, jwtSecret)
+	signer, err := newTokenSigner(cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT signing keys: %w", err)
+	}
+	passwordPolicy, err := newPasswordPolicy(cfg.Passwords)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password policy configuration: %w", err)
+	}
+	logMailer := mailer.NewLogMailer()
+	invitationService := service.NewInvitationService(invitationRepo, userRepo, logMailer,
+		cfg.Invitations.Secret, cfg.Invitations.TTL, cfg.Invitations.BaseURL)
+	authService := service.NewAuthService(userRepo, cfg.JWT.Secret,
+		service.WithTokenSigner(signer),
+		service.WithInvitations(invitationService),
+		service.WithOpenRegistration(cfg.Auth.OpenRegistration),
+		service.WithSessions(sessionRepo),
+		service.WithEmailVerification(logMailer, cfg.Auth.EmailVerifyBaseURL),
+		service.WithPasswordHasher(newPasswordHasher(cfg.Passwords)),
+		service.WithPasswordPolicy(passwordPolicy),
+		service.WithSecurityEvents(securityEventRepo),
+	)
+
+	// Business gauges on /metrics are recounted every METRICS_REFRESH_INTERVAL; 0 disables them.
+	if cfg.Metrics.RefreshInterval > 0 {
+		go watchClientStats(ctx, clientService, cfg.Metrics.RefreshInterval)
+	}
+
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg.Idempotency.TTL)
 
 	// Setup handlers
 	clientHandler := handler.NewClientHandler(clientService)
-	authHandler := handler.NewAuthHandler(authService)
-
-	mux := http.NewServeMux()
-
-	// Public routes
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handler.RespondJSON(w, http.StatusOK, handler.HealthResponse{
-				Status:  "ok",
-				Message: "Server is running",
-			})
-		} else {
-			http.NotFound(w, r)
-		}
-	})
-
-	// Auth routes (public)
-	mux.HandleFunc("/api/auth/register", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			authHandler.Register(w, r)
-		} else {
-			http.NotFound(w, r)
-		}
-	})
+	authHandler := handler.NewAuthHandler(authService,
+		handler.WithAPIKeys(apiKeyService),
+		handler.WithImpersonationWrites(cfg.Auth.ImpersonationAllowWrites),
+	)
+	invitationHandler := handler.NewInvitationHandler(invitationService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	accountHandler := handler.NewAccountHandler(authService)
+	securityEventHandler := handler.NewSecurityEventHandler(authService)
+	idempotencyHandler := handler.NewIdempotencyHandler(idempotencyService)
+	oidcHandler, err := newOIDCHandler(cfg, userRepo, authService, authService)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSO configuration: %w", err)
+	}
+	limits, err := parseRateLimits(cfg.RateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit configuration: %w", err)
+	}
 
-	mux.HandleFunc("/api/auth/login", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			authHandler.Login(w, r)
-		} else {
-			http.NotFound(w, r)
-		}
+	openAPIHandler := handler.NewOpenAPIHandler()
+
+	mux := route.New()
+	mux.NotFound = handler.RouteNotFound
+	mux.MethodNotAllowed = handler.MethodNotAllowed
+	registerRoutes(mux, routeHandlers{
+		client:        clientHandler,
+		auth:          authHandler,
+		account:       accountHandler,
+		invitation:    invitationHandler,
+		apiKey:        apiKeyHandler,
+		securityEvent: securityEventHandler,
+		idempotency:   idempotencyHandler,
+		oidc:          oidcHandler,
+		openAPI:       openAPIHandler,
+		metrics:       newMetricsHandler(cfg),
+		readiness:     readinessHandler(newReadinessChecker(dbClient, cfg.Tables, cloudWatch, cfg.Readiness.CacheTTL)),
+		rateLimit:     newRateLimitHandler(cfg.RateLimit, dbClient, cfg.Tables.RateLimits),
+		limits:        limits,
 	})
-
-	mux.HandleFunc("/api/auth/me", authHandler.AuthMiddleware(authHandler.Me))
-
-	// Protected API routes
-	// IMPORTANT: More specific routes must be registered BEFORE less specific ones
-	// because Go's ServeMux matches by longest prefix
-
-	mux.HandleFunc("/api/clients/active", authHandler.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			clientHandler.GetActiveClients(w, r)
-		} else {
-			http.NotFound(w, r)
-		}
-	}))
-
-	mux.HandleFunc("/api/clients/inactive", authHandler.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			clientHandler.GetInactiveClients(w, r)
-		} else {
-			http.NotFound(w, r)
-		}
-	}))
-
-	mux.HandleFunc("/api/clients/add", authHandler.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("[ROUTER] /api/clients/add handler - Method: %s, Path: %s", r.Method, r.URL.Path)
-		fmt.Fprintf(os.Stderr, "[ROUTER] /api/clients/add handler - Method: %s, Path: %s\n", r.Method, r.URL.Path)
-		if r.Method == http.MethodPost {
-			log.Printf("[ROUTER] Calling CreateClient handler")
-			fmt.Fprintf(os.Stderr, "[ROUTER] Calling CreateClient handler\n")
-			clientHandler.CreateClient(w, r)
-		} else {
-			log.Printf("[ROUTER] Method not POST for /api/clients/add: %s", r.Method)
-			fmt.Fprintf(os.Stderr, "[ROUTER] Method not POST for /api/clients/add: %s\n", r.Method)
-			http.NotFound(w, r)
-		}
-	}))
-
-	mux.HandleFunc("/api/clients/by-email", authHandler.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			clientHandler.GetClientByEmail(w, r)
-		} else {
-			http.NotFound(w, r)
-		}
-	}))
-
-	// PUT/PATCH /api/clients/update/{id} — alternate URL for client updates (must register before /api/clients/)
-	const clientsUpdatePrefix = "/api/clients/update/"
-	mux.HandleFunc(clientsUpdatePrefix, authHandler.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		path := r.URL.Path
-		if !strings.HasPrefix(path, clientsUpdatePrefix) {
-			http.NotFound(w, r)
-			return
-		}
-		id := strings.TrimSuffix(path[len(clientsUpdatePrefix):], "/")
-		if id == "" || handler.ReservedClientPathID(id) {
-			http.NotFound(w, r)
-			return
-		}
-		if r.Method != http.MethodPut && r.Method != http.MethodPatch {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		r = r.WithContext(context.WithValue(r.Context(), handler.ClientIDKey, id))
-		clientHandler.UpdateClient(w, r)
-	}))
-
-	// Base route for GET /api/clients (protected)
-	mux.HandleFunc("/api/clients", authHandler.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet && r.URL.Path == "/api/clients" {
-			clientHandler.GetClientList(w, r)
-		} else {
-			http.NotFound(w, r)
-		}
-	}))
-
-	// Catch-all route for /api/clients/{id} (protected)
-	mux.HandleFunc("/api/clients/", authHandler.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		path := r.URL.Path
-		method := r.Method
-
-		log.Printf("[ROUTER] Catch-all /api/clients/ handler - Method: %s, Path: %s", method, path)
-		fmt.Fprintf(os.Stderr, "[ROUTER] Catch-all /api/clients/ handler - Method: %s, Path: %s\n", method, path)
-
-		if path == "/api/clients" || path == "/api/clients/" {
-			http.NotFound(w, r)
-			return
-		}
-
-		// Remove /api/clients/ prefix to get the segment (may be a reserved route or a client id).
-		id := strings.TrimSuffix(path[len("/api/clients/"):], "/")
-		if id == "" {
-			http.NotFound(w, r)
-			return
-		}
-
-		// Depending on Go version / ServeMux, the subtree pattern /api/clients/ can also match
-		// paths that have dedicated handlers. Delegate instead of 404 or treating as a client id.
-		switch id {
-		case "active":
-			if method == http.MethodGet {
-				clientHandler.GetActiveClients(w, r)
-			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-			return
-		case "inactive":
-			if method == http.MethodGet {
-				clientHandler.GetInactiveClients(w, r)
-			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-			return
-		case "add":
-			if method == http.MethodPost {
-				clientHandler.CreateClient(w, r)
-			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-			return
-		case "by-email":
-			if method == http.MethodGet {
-				clientHandler.GetClientByEmail(w, r)
-			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-			return
-		}
-
-		switch method {
-		case http.MethodGet:
-			r = r.WithContext(context.WithValue(r.Context(), handler.ClientIDKey, id))
-			clientHandler.GetClientByID(w, r)
-		case http.MethodPut, http.MethodPatch:
-			r = r.WithContext(context.WithValue(r.Context(), handler.ClientIDKey, id))
-			clientHandler.UpdateClient(w, r)
-		default:
-			http.NotFound(w, r)
-		}
-	}))
+	apiDoc, err := apiSpec().Build(mux.Routes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI document: %w", err)
+	}
+	openAPIHandler.Document = apiDoc
+
+	// CORS wraps the mux rather than a route group so preflight requests, which carry no
+	// credentials, are answered on every route before authentication.
+	api := cors.New(cors.Options{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	}).Middleware(mux)
 
 	// Middleware to log requests, strip stage prefix, and recover from panics
 	logAndStripHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(logger.NewContext(r.Context()))
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("[MIDDLEWARE] PANIC recovered: %v", err)
-				fmt.Fprintf(os.Stderr, "[MIDDLEWARE] PANIC recovered: %v\n", err)
-				handler.RespondJSON(w, http.StatusInternalServerError, handler.ErrorResponse{
-					Error:   "Internal server error",
-					Message: "Request processing failed",
-				})
+				httpLog.ErrorContext(r.Context(), "panic recovered", "panic", err, "method", r.Method)
+				handler.RespondError(w, r, fmt.Errorf("panic: %v", err))
 			}
 		}()
 
 		start := time.Now()
-		originalPath := r.URL.Path
 		path := r.URL.Path
 		method := r.Method
 
-		// Log immediately to ensure we see all requests
-		log.Printf("[MIDDLEWARE] Incoming request: %s %s (original: %s)", method, path, originalPath)
-		fmt.Fprintf(os.Stderr, "[MIDDLEWARE] Incoming request: %s %s (original: %s)\n", method, path, originalPath)
-
 		// Remove common stage prefixes if present
-		if len(path) > 5 && path[:5] == "/prod" && path[5] == '/' {
-			r.URL.Path = path[5:]
-			log.Printf("[MIDDLEWARE] Stripped /prod prefix, new path: %s", r.URL.Path)
-		} else if len(path) > 4 && path[:4] == "/dev" && path[4] == '/' {
-			r.URL.Path = path[4:]
-			log.Printf("[MIDDLEWARE] Stripped /dev prefix, new path: %s", r.URL.Path)
-		} else if len(path) > 8 && path[:8] == "/staging" && path[8] == '/' {
-			r.URL.Path = path[8:]
-			log.Printf("[MIDDLEWARE] Stripped /staging prefix, new path: %s", r.URL.Path)
+		for _, stage := range []string{"/prod", "/dev", "/staging"} {
+			if strings.HasPrefix(path, stage+"/") {
+				r.URL.Path = path[len(stage):]
+				httpLog.DebugContext(r.Context(), "stripped stage prefix", "stage", stage)
+				break
+			}
+		}
+
+		// Log the route pattern rather than the path, which can carry client ids. Unmatched
+		// paths are logged as-is so 404s can be diagnosed.
+		attrs := []slog.Attr{slog.String("method", method)}
+		pattern := mux.Pattern(r.URL.Path)
+		tracing.SetRoute(r, pattern)
+		if pattern != "" {
+			logger.AddAttrs(r.Context(), slog.String(logger.KeyRoute, pattern))
+		} else {
+			attrs = append(attrs, slog.String("path", r.URL.Path))
 		}
 
 		// Wrap response writer to capture status code
 		wrapped := &responseWriterWrapper{ResponseWriter: w, statusCode: http.StatusOK}
 
-		mux.ServeHTTP(wrapped, r)
+		api.ServeHTTP(wrapped, r)
 
-		// Log the request
-		duration := time.Since(start)
-		log.Printf("[MIDDLEWARE] %s %s | Status: %d | Duration: %dms | Remote: %s", method, r.URL.Path, wrapped.statusCode, duration.Milliseconds(), r.RemoteAddr)
-		fmt.Fprintf(os.Stderr, "[MIDDLEWARE] %s %s | Status: %d | Duration: %dms | Remote: %s\n", method, r.URL.Path, wrapped.statusCode, duration.Milliseconds(), r.RemoteAddr)
+		elapsed := time.Since(start)
+		observeRequest(method, pattern, wrapped.statusCode, elapsed)
 
-		if cwLogger != nil {
-			go cwLogger.LogRequest(r.Context(), method, r.URL.Path, wrapped.statusCode, duration, r.RemoteAddr)
+		level := slog.LevelInfo
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
 		}
+		httpLog.LogAttrs(r.Context(), level, "request", append(attrs,
+			slog.Int(logger.KeyStatus, wrapped.statusCode),
+			slog.Int64(logger.KeyDurationMS, elapsed.Milliseconds()),
+			slog.String("remote_addr", r.RemoteAddr),
+		)...)
 	})
 
-	port := getEnv("HTTP_PORT", "8081")
 	server := &http.Server{
-		Addr:         ":" + port,
-		Handler:      logAndStripHandler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:         ":" + cfg.HTTP.Port,
+		Handler:      requestid.Middleware(tracing.Middleware(logAndStripHandler)),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
 	}
 
 	return &Router{
-		mux:           mux,
-		server:        server,
-		handler:       clientHandler,
-		cloudWatchLog: cwLogger,
+		mux:             mux,
+		apiDoc:          apiDoc,
+		server:          server,
+		handler:         clientHandler,
+		cloudWatch:      cloudWatch,
+		shutdownTracing: shutdownTracing,
+		shutdownTimeout: cfg.HTTP.ShutdownTimeout,
 	}, nil
 }
 
+// routeHandlers are the handlers registerRoutes wires up. oidc is nil when SSO is disabled.
+type routeHandlers struct {
+	client        *handler.ClientHandler
+	auth          *handler.AuthHandler
+	account       *handler.AccountHandler
+	invitation    *handler.InvitationHandler
+	apiKey        *handler.APIKeyHandler
+	securityEvent *handler.SecurityEventHandler
+	idempotency   *handler.IdempotencyHandler
+	oidc          *handler.OIDCHandler
+	openAPI       *handler.OpenAPIHandler
+	metrics       http.HandlerFunc
+	readiness     http.HandlerFunc
+	rateLimit     *handler.RateLimitHandler // nil when rate limiting is disabled
+	limits        rateLimits
+}
+
+// rateLimits are the per-caller limits of each route group; zero limits are unlimited.
+type rateLimits struct {
+	public, account, clients, admin service.RateLimit
+}
+
+// registerRoutes is the route table. Every route must also be described in apiSpec, or
+// building the OpenAPI document fails.
+func registerRoutes(mux *route.Mux, h routeHandlers) {
+	// Route groups share middleware: authenticated routes run AuthMiddleware, admin routes also
+	// require the admin role, and client routes check API key scopes per method. Each group has
+	// its own rate limit, per user or API key once authenticated and per client IP before.
+	// Authenticated writes honour Idempotency-Key.
+	requireAdmin := func(next http.HandlerFunc) http.HandlerFunc {
+		return h.auth.RequireRole(next, "admin")
+	}
+	clientsRead := func(next http.HandlerFunc) http.HandlerFunc {
+		return h.auth.RequireScope(next, service.ScopeClientsRead)
+	}
+	clientsWrite := func(next http.HandlerFunc) http.HandlerFunc {
+		return h.auth.RequireScope(next, service.ScopeClientsWrite)
+	}
+	// With rate limiting disabled there is no rate limit handler, and groups are not wrapped.
+	rateLimit := func(group string, limit service.RateLimit) func(http.HandlerFunc) http.HandlerFunc {
+		if h.rateLimit == nil {
+			return func(next http.HandlerFunc) http.HandlerFunc { return next }
+		}
+		return h.rateLimit.Limit(group, limit)
+	}
+
+	// Public routes. Probes, metrics and docs are not rate limited, so load balancers and
+	// scrapers sharing an address are never refused.
+	public := mux.Group("")
+	// Probes: liveness only needs the process to answer; readiness checks its dependencies.
+	public.Get("/health", liveness)
+	public.Get("/healthz", liveness)
+	public.Get("/readyz", h.readiness)
+	// Public signing keys for verifying tokens (frontend, API Gateway authorizer)
+	public.Get("/.well-known/jwks.json", h.auth.JWKS)
+	// Prometheus metrics, served only with METRICS_TOKEN outside development
+	if h.metrics != nil {
+		public.Get("/metrics", h.metrics)
+	}
+	// API description and docs
+	public.Get("/openapi.json", h.openAPI.Spec)
+	public.Get("/docs", h.openAPI.Docs)
+
+	// Login and registration, limited per client IP
+	signIn := mux.Group("/api/auth", rateLimit("public", h.limits.public))
+	signIn.Post("/register", h.auth.Register)
+	signIn.Post("/login", h.auth.Login)
+	signIn.Post("/email/verify", h.account.VerifyEmail)
+	// SSO routes (only when OIDC_ISSUER_URL is configured)
+	if h.oidc != nil {
+		signIn.Get("/oidc/login", h.oidc.Login)
+		signIn.Get("/oidc/callback", h.oidc.Callback)
+	}
+
+	// Self-service account routes
+	account := mux.Group("/api/auth", h.auth.AuthMiddleware, rateLimit("account", h.limits.account), h.idempotency.Middleware)
+	account.Get("/me", h.auth.Me)
+	account.Patch("/me", h.account.UpdateProfile)
+	account.Get("/me/security-events", h.securityEvent.ListMine)
+	account.Post("/password", h.account.ChangePassword)
+	account.Get("/sessions", h.account.ListSessions)
+	account.Delete("/sessions", h.account.RevokeOtherSessions)
+	account.Delete("/sessions/{id}", h.account.RevokeSession)
+
+	// Client routes
+	clients := mux.Group("/api/clients", h.auth.AuthMiddleware, rateLimit("clients", h.limits.clients), h.idempotency.Middleware)
+	clients.Get("", h.client.GetClientList, clientsRead)
+	clients.Get("/active", h.client.GetActiveClients, clientsRead)
+	clients.Get("/inactive", h.client.GetInactiveClients, clientsRead)
+	clients.Get("/by-email", h.client.GetClientByEmail, clientsRead)
+	clients.Post("/add", h.client.CreateClient, clientsWrite)
+	clients.Get("/{id}", h.client.GetClientByID, clientsRead)
+	clients.Put("/{id}", h.client.UpdateClient, clientsWrite)
+	clients.Patch("/{id}", h.client.UpdateClient, clientsWrite)
+	// Alternate URL for client updates
+	clients.Put("/update/{id}", h.client.UpdateClient, clientsWrite)
+	clients.Patch("/update/{id}", h.client.UpdateClient, clientsWrite)
+
+	// Admin routes. Their writes return credentials (API keys, invitation and impersonation
+	// tokens), which must not be stored for replay, so they skip the idempotency middleware.
+	admin := mux.Group("/api", h.auth.AuthMiddleware, requireAdmin, rateLimit("admin", h.limits.admin))
+	admin.Post("/invitations", h.invitation.CreateInvitation)
+	admin.Get("/invitations", h.invitation.ListInvitations)
+	admin.Delete("/invitations/{id}", h.invitation.RevokeInvitation)
+	admin.Post("/api-keys", h.apiKey.CreateAPIKey)
+	admin.Get("/api-keys", h.apiKey.ListAPIKeys)
+	admin.Delete("/api-keys/{id}", h.apiKey.RevokeAPIKey)
+	admin.Get("/security-events", h.securityEvent.List)
+	admin.Post("/auth/impersonate/{id}", h.auth.Impersonate)
+}
+
 func (r *Router) Start() error {
-	log.Printf("Starting server on %s", r.server.Addr)
-	log.Printf("Available endpoints:")
-	log.Printf("  Public:")
-	log.Printf("    GET  /health - Health check")
-	log.Printf("    POST /api/auth/register - Register new user")
-	log.Printf("    POST /api/auth/login - Login (returns JWT token)")
-	log.Printf("  Protected (requires Authorization: Bearer <token>):")
-	log.Printf("    GET  /api/auth/me - Get current user info")
-	log.Printf("    GET  /api/clients - Get all clients")
-	log.Printf("    GET  /api/clients/{id} - Get client by ID")
-	log.Printf("    GET  /api/clients/by-email?email=... - Get client by email")
-	log.Printf("    PUT/PATCH /api/clients/{id} - Update a client")
-	log.Printf("    PUT/PATCH /api/clients/update/{id} - Update a client (alternate path)")
-	log.Printf("    GET  /api/clients/active - Get active clients")
-	log.Printf("    GET  /api/clients/inactive - Get inactive clients")
-	log.Printf("    POST /api/clients/add - Create a new client")
+	serverLog.Info("starting server", "addr", r.server.Addr, "docs", "GET /openapi.json, GET /docs")
+	for _, rt := range r.mux.Routes() {
+		serverLog.Debug("route", "method", rt.Method, logger.KeyRoute, rt.Pattern, "summary", r.apiDoc.Operation(rt.Method, rt.Pattern).Summary)
+	}
 
 	if err := r.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("server failed to start: %w", err)
@@ -324,32 +389,208 @@ func (r *Router) StartWithGracefulShutdown() error {
 
 	go func() {
 		if err := r.Start(); err != nil {
-			log.Fatalf("Server failed: %v", err)
+			serverLog.Error("server failed", logger.Error(err))
+			os.Exit(1)
 		}
 	}()
 
 	// Wait for interrupt signal
 	<-stop
-	log.Println("Shutting down server...")
+	serverLog.Info("shutting down server")
 
-	// Create shutdown context with 10 second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Create shutdown context bounded by HTTP_SHUTDOWN_TIMEOUT
+	ctx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
 	defer cancel()
+	// Runs after Shutdown, so the final log lines are shipped too.
+	defer r.closeLogShipping(ctx)
+	defer r.closeTracing(ctx)
 
 	// Shutdown server
 	if err := r.server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 
-	log.Println("Server stopped")
+	serverLog.Info("server stopped")
 	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// closeLogShipping sends the logs still buffered for CloudWatch.
+func (r *Router) closeLogShipping(ctx context.Context) {
+	if r.cloudWatch == nil {
+		return
+	}
+	if err := r.cloudWatch.Close(ctx); err != nil {
+		serverLog.Error("failed to flush logs to CloudWatch", logger.Error(err), "dropped", r.cloudWatch.Dropped())
+	}
+}
+
+// closeTracing exports the spans still buffered.
+func (r *Router) closeTracing(ctx context.Context) {
+	if r.shutdownTracing == nil {
+		return
+	}
+	if err := r.shutdownTracing(ctx); err != nil {
+		serverLog.Error("failed to export traces", logger.Error(err))
+	}
+}
+
+// newTokenSigner builds the JWT key set. With JWT_KEYS_DIR set, tokens are signed with the
+// RS256/ES256 key named by JWT_ACTIVE_KID and every other key in the directory is accepted for
+// verification. The HS256 key from JWT_SECRET stays verify-only so tokens issued before the
+// switch remain valid until they expire. Without JWT_KEYS_DIR, tokens are signed with HS256.
+func newTokenSigner(cfg config.JWT) (*service.KeySet, error) {
+	hmacKey := service.NewHMACSigningKey(service.DefaultHMACKeyID, []byte(cfg.Secret))
+
+	if cfg.KeysDir == "" {
+		return service.NewKeySet(hmacKey)
+	}
+
+	active, others, err := service.LoadSigningKeysDir(cfg.KeysDir, cfg.ActiveKID)
+	if err != nil {
+		return nil, err
+	}
+	ks, err := service.NewKeySet(active, append(others, hmacKey)...)
+	if err != nil {
+		return nil, err
+	}
+	serverLog.Info("jwt signing keys loaded", "kid", active.ID, "alg", active.Method.Alg(), "verification_only_keys", len(others)+1)
+	return ks, nil
+}
+
+// newPasswordHasher hashes new passwords with PASSWORD_HASH_ALGORITHM (argon2id or bcrypt) and
+// accepts hashes from either, so switching algorithm or cost only upgrades users as they log in.
+func newPasswordHasher(cfg config.Passwords) *service.PasswordHashers {
+	params := service.DefaultArgon2idParams
+	params.Memory = cfg.Argon2MemoryKiB
+	params.Iterations = cfg.Argon2Iterations
+	params.Parallelism = cfg.Argon2Parallelism
+
+	argon2id := service.NewArgon2idHasher(params)
+	bcryptHasher := service.NewBcryptHasher(cfg.BcryptCost)
+	if cfg.HashAlgorithm == "bcrypt" {
+		return service.NewPasswordHashers(bcryptHasher, argon2id)
+	}
+	return service.NewPasswordHashers(argon2id, bcryptHasher)
+}
+
+// newPasswordPolicy starts from DefaultPasswordPolicy and applies cfg, where 0 or false disables
+// a rule.
+func newPasswordPolicy(cfg config.Passwords) (service.PasswordPolicy, error) {
+	policy, err := service.DefaultPasswordPolicy()
+	if err != nil {
+		return policy, err
+	}
+	policy.MinLength = cfg.MinLength
+	policy.MinEntropyBits = cfg.MinEntropyBits
+	policy.RejectPersonalInfo = cfg.RejectPersonalInfo
+	if !cfg.CheckBreached {
+		policy.Breached = nil
+	}
+	return policy, nil
+}
+
+// newOIDCHandler enables SSO when OIDC_ISSUER_URL is set. OIDC_GROUP_ROLES maps identity
+// provider groups to roles ("staff-admins=admin,counsellors=counsellor"); users in no mapped
+// group get OIDC_DEFAULT_ROLE, or are refused when it is empty.
+func newOIDCHandler(cfg *config.Config, userRepo *repository.UserRepository, tokens service.TokenIssuer, events service.SecurityEventRecorder) (*handler.OIDCHandler, error) {
+	if cfg.OIDC.IssuerURL == "" {
+		return nil, nil
+	}
+
+	groupRoles, err := service.ParseGroupRoles(cfg.OIDC.GroupRoles)
+	if err != nil {
+		return nil, err
+	}
+	oidcCfg := service.OIDCConfig{
+		IssuerURL:    cfg.OIDC.IssuerURL,
+		ClientID:     cfg.OIDC.ClientID,
+		ClientSecret: cfg.OIDC.ClientSecret,
+		RedirectURL:  cfg.OIDC.RedirectURL,
+		Scopes:       cfg.OIDC.Scopes,
+		GroupsClaim:  cfg.OIDC.GroupsClaim,
+		GroupRoles:   groupRoles,
+		DefaultRole:  cfg.OIDC.DefaultRole,
+	}
+	if len(groupRoles) == 0 && oidcCfg.DefaultRole == "" {
+		serverLog.Warn("SSO enabled without OIDC_GROUP_ROLES or OIDC_DEFAULT_ROLE; every SSO login will be refused")
+	}
+
+	oidcService := service.NewOIDCService(oidcCfg, userRepo, tokens, cfg.OIDC.StateSecret,
+		service.WithOIDCSecurityEvents(events))
+	serverLog.Info("SSO enabled", "issuer", oidcCfg.IssuerURL)
+	opts := []handler.OIDCHandlerOption{handler.WithFlowCookiePath(oidcCookiePath(cfg.OIDC.RedirectURL))}
+	if cfg.OIDC.FrontendURL != "" {
+		opts = append(opts, handler.WithFrontendRedirect(cfg.OIDC.FrontendURL))
+	}
+	return handler.NewOIDCHandler(oidcService, !cfg.Development(), opts...), nil
+}
+
+// oidcCookiePath is the directory of the callback URL, such as /prod/api/auth/oidc behind an
+// API Gateway stage, so the flow cookie set by the login route reaches the callback whatever
+// prefix the browser sees.
+func oidcCookiePath(redirectURL string) string {
+	u, err := url.Parse(redirectURL)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	return path.Dir(u.Path)
+}
+
+// newCloudWatchHandler ships logs straight to CloudWatch Logs when enabled, using the AWS
+// credentials of the DynamoDB client. CLOUDWATCH_LOGS_ENDPOINT points it at a stand-in such as
+// cmd/dev-cloudwatch. It returns nil when shipping is disabled.
+func newCloudWatchHandler(ctx context.Context, cfg config.CloudWatch, awsCfg aws.Config) *logger.CloudWatchHandler {
+	if !cfg.Enabled {
+		return nil
+	}
+	client := logger.NewCloudWatchLogsClient(awsCfg, cfg.Endpoint)
+	stream := logger.ResolveStreamName(ctx, cfg.LogStream)
+	return logger.NewCloudWatchHandler(client, cfg.LogGroup, stream, logger.CloudWatchOptions{
+		BufferSize:    cfg.BufferSize,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+	})
+}
+
+// parseRateLimits reads the limit of each route group. With rate limiting disabled every
+// group is unlimited.
+func parseRateLimits(cfg config.RateLimit) (rateLimits, error) {
+	var limits rateLimits
+	if !cfg.Enabled {
+		return limits, nil
+	}
+	for _, group := range []struct {
+		env   string
+		value string
+		limit *service.RateLimit
+	}{
+		{"RATE_LIMIT_PUBLIC", cfg.Public, &limits.public},
+		{"RATE_LIMIT_ACCOUNT", cfg.Account, &limits.account},
+		{"RATE_LIMIT_CLIENTS", cfg.Clients, &limits.clients},
+		{"RATE_LIMIT_ADMIN", cfg.Admin, &limits.admin},
+	} {
+		limit, err := service.ParseRateLimit(group.value)
+		if err != nil {
+			return limits, fmt.Errorf("%s: %w", group.env, err)
+		}
+		*group.limit = limit
+	}
+	return limits, nil
+}
+
+// newRateLimitHandler keeps token buckets in memory, or in DynamoDB when RATE_LIMIT_STORE is
+// dynamodb so every server in a fleet enforces the same limits. It returns nil when rate
+// limiting is disabled.
+func newRateLimitHandler(cfg config.RateLimit, dbClient *db.Client, table string) *handler.RateLimitHandler {
+	if !cfg.Enabled {
+		return nil
+	}
+	var store service.RateLimitStore = repository.NewMemoryRateLimitStore()
+	if cfg.Store == "dynamodb" {
+		store = repository.NewRateLimitRepository(dbClient.DynamoDB, table)
 	}
-	return defaultValue
+	serverLog.Info("rate limiting enabled", "store", cfg.Store)
+	return handler.NewRateLimitHandler(service.NewRateLimiter(store), cfg.TrustedProxyHops)
 }
 
 // responseWriterWrapper wraps http.ResponseWriter to capture status code
@@ -0,0 +1,257 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/config"
+	"github.com/jmason/john_ai_project/internal/handler"
+	"github.com/jmason/john_ai_project/internal/health"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/openapi"
+	"github.com/jmason/john_ai_project/internal/route"
+)
+
+// allRoutes registers every route, including the SSO and metrics routes that are optional at
+// runtime. The handlers are never called, so their services can be nil.
+func allRoutes() *route.Mux {
+	mux := route.New()
+	registerRoutes(mux, routeHandlers{oidc: &handler.OIDCHandler{}, metrics: metricsHandler("")})
+	return mux
+}
+
+func TestAPISpec_DescribesEveryRoute(t *testing.T) {
+	mux := allRoutes()
+
+	doc, err := apiSpec().Build(mux.Routes())
+	if err != nil {
+		t.Fatalf("Every registered route needs an endpoint in apiSpec: %v", err)
+	}
+
+	for _, rt := range mux.Routes() {
+		op := doc.Operation(rt.Method, rt.Pattern)
+		if op == nil {
+			t.Errorf("%s %s is missing from the OpenAPI document", rt.Method, rt.Pattern)
+			continue
+		}
+		if op.Summary == "" {
+			t.Errorf("%s %s has no summary", rt.Method, rt.Pattern)
+		}
+	}
+}
+
+func TestAPISpec_HasNoStaleEndpoints(t *testing.T) {
+	registered := map[route.Route]bool{}
+	for _, rt := range allRoutes().Routes() {
+		registered[rt] = true
+	}
+
+	for _, e := range apiSpec().Endpoints {
+		if !registered[route.Route{Method: e.Method, Pattern: e.Pattern}] {
+			t.Errorf("apiSpec describes %s %s, which is not registered", e.Method, e.Pattern)
+		}
+	}
+}
+
+func TestAPISpec_Schemas(t *testing.T) {
+	doc, err := apiSpec().Build(allRoutes().Routes())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	for _, name := range []string{"CreateClientRequest", "UpdateClientRequest", "AuthResponse", "Problem", "Client", "User"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("Expected a %s schema", name)
+		}
+	}
+	if _, ok := doc.Components.Schemas["Client"].Properties["name"]; !ok {
+		t.Error("Expected the name field Client.MarshalJSON adds")
+	}
+
+	op := doc.Operation(http.MethodPost, "/api/clients/add")
+	if op.RequestBody == nil || op.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/CreateClientRequest" {
+		t.Errorf("Expected POST /api/clients/add to take a CreateClientRequest, got %+v", op.RequestBody)
+	}
+	if _, ok := op.Responses["201"]; !ok {
+		t.Errorf("Expected a 201 response, got %v", op.Responses)
+	}
+	if op.Security == nil {
+		t.Error("Expected client routes to require a token")
+	}
+	if doc.Operation(http.MethodGet, "/health").Security != nil {
+		t.Error("Expected /health to be public")
+	}
+}
+
+func TestAPISpec_DocumentsIdempotencyKey(t *testing.T) {
+	doc, err := apiSpec().Build(allRoutes().Routes())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	hasKey := func(op *openapi.Operation) bool {
+		for _, p := range op.Parameters {
+			if p.In == "header" && p.Name == handler.IdempotencyKeyHeader {
+				return true
+			}
+		}
+		return false
+	}
+
+	op := doc.Operation(http.MethodPost, "/api/clients/add")
+	if !hasKey(op) {
+		t.Error("Expected POST /api/clients/add to document the Idempotency-Key header")
+	}
+	if _, ok := op.Responses["422"]; !ok {
+		t.Errorf("Expected a 422 response for reused keys, got %v", op.Responses)
+	}
+	if hasKey(doc.Operation(http.MethodPost, "/api/auth/login")) {
+		t.Error("Expected public routes not to take an Idempotency-Key")
+	}
+	if hasKey(doc.Operation(http.MethodGet, "/api/clients/{id}")) {
+		t.Error("Expected reads not to take an Idempotency-Key")
+	}
+	if hasKey(doc.Operation(http.MethodPost, "/api/api-keys")) {
+		t.Error("Expected admin routes, which return credentials, not to take an Idempotency-Key")
+	}
+}
+
+func TestNewMetricsHandler_DisabledWithoutTokenInProduction(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          string
+		token        string
+		expectServed bool
+	}{
+		{name: "Development without a token", env: config.EnvDevelopment, expectServed: true},
+		{name: "Production with a token", env: config.EnvProduction, token: "s3cret", expectServed: true},
+		{name: "Production without a token", env: config.EnvProduction, expectServed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Env: tt.env, Metrics: config.Metrics{Token: tt.token}}
+			if served := newMetricsHandler(cfg) != nil; served != tt.expectServed {
+				t.Errorf("Expected served=%v, got %v", tt.expectServed, served)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_RequiresToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		expectStatus  int
+	}{
+		{name: "Open without a token", expectStatus: http.StatusOK},
+		{name: "Valid bearer token", token: "s3cret", authorization: "Bearer s3cret", expectStatus: http.StatusOK},
+		{name: "Missing bearer token", token: "s3cret", expectStatus: http.StatusUnauthorized},
+		{name: "Wrong bearer token", token: "s3cret", authorization: "Bearer guess", expectStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			metricsHandler(tt.token)(rec, req)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if tt.expectStatus == http.StatusOK && !strings.Contains(rec.Body.String(), "# TYPE http_requests_total counter") {
+				t.Errorf("Expected the HTTP metrics in the body: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		result       health.CheckResult
+		expectStatus int
+	}{
+		{name: "Ready", result: health.OK(), expectStatus: http.StatusOK},
+		{name: "Degraded is still ready", result: health.CheckResult{Status: health.StatusDegraded}, expectStatus: http.StatusOK},
+		{name: "Unavailable", result: health.Unavailable(errors.New("table clients does not exist")), expectStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.NewChecker(0, time.Second)
+			checker.Add("dynamodb", func(context.Context) health.CheckResult { return tt.result })
+			rec := httptest.NewRecorder()
+			readinessHandler(checker)(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			var report health.Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+			if report.Checks["dynamodb"].Status != tt.result.Status {
+				t.Errorf("Expected the dynamodb check in the breakdown, got %+v", report.Checks)
+			}
+		})
+	}
+}
+
+func TestCloudWatchResult(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status logger.CloudWatchStatus
+		want   string
+	}{
+		{name: "Delivering", status: logger.CloudWatchStatus{LastSent: now.Add(-time.Second)}, want: health.StatusOK},
+		{name: "Recent failure", status: logger.CloudWatchStatus{LastError: "AccessDenied", LastErrorAt: now.Add(-time.Minute)}, want: health.StatusDegraded},
+		{name: "Old failure", status: logger.CloudWatchStatus{LastError: "AccessDenied", LastErrorAt: now.Add(-time.Hour)}, want: health.StatusOK},
+		{name: "Closed", status: logger.CloudWatchStatus{Closed: true}, want: health.StatusDegraded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cloudWatchResult(tt.status, now); got.Status != tt.want {
+				t.Errorf("Expected %q, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOIDCCookiePath(t *testing.T) {
+	tests := map[string]string{
+		"https://api.example.com/api/auth/oidc/callback":              "/api/auth/oidc",
+		"https://abc.execute-api.aws.com/prod/api/auth/oidc/callback": "/prod/api/auth/oidc",
+		"https://api.example.com":                                     "/",
+	}
+	for redirectURL, want := range tests {
+		if got := oidcCookiePath(redirectURL); got != want {
+			t.Errorf("oidcCookiePath(%q) = %q, want %q", redirectURL, got, want)
+		}
+	}
+}
+
+func TestLivenessProbes(t *testing.T) {
+	mux := allRoutes()
+
+	for _, path := range []string{"/health", "/healthz"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"ok"`) {
+			t.Errorf("Expected %s to answer 200 ok, got %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+	"github.com/jmason/john_ai_project/internal/validate"
+)
+
+// Account errors
+var (
+	ErrSessionsDisabled          = apperror.NotImplemented("sessions_disabled", "session tracking is not enabled")
+	ErrSessionNotFound           = apperror.NotFound("session_not_found", "session not found")
+	ErrSessionRevoked            = apperror.Unauthorized("session_revoked", "session has been revoked")
+	ErrCurrentPasswordIncorrect  = apperror.Forbidden("current_password_incorrect", "current password is incorrect")
+	ErrPasswordNotSet            = apperror.Forbidden("password_not_set", "account signs in through the identity provider and has no password")
+	ErrProfileInvalidName        = apperror.Validation("invalid_name", "first_name and last_name cannot be empty")
+	ErrEmailManagedByProvider    = apperror.Forbidden("email_managed_by_provider", "email is managed by the identity provider")
+	ErrEmailVerificationDisabled = apperror.Forbidden("email_change_disabled", "email changes are not enabled")
+	ErrEmailChangeInvalid        = apperror.Validation("email_change_invalid", "email verification link is invalid or has expired")
+	// ErrPasswordFieldsMissing names current_password and/or new_password in its Fields.
+	ErrPasswordFieldsMissing = apperror.Validation("missing_password_fields", "password fields are required")
+)
+
+const (
+	emailChangeAudience = "email-change"
+	emailChangeTTL      = 24 * time.Hour
+	// sessionSeenResolution limits how often an active session writes its last-seen time.
+	sessionSeenResolution = 5 * time.Minute
+)
+
+// SessionRepository interface for dependency injection
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *repository.Session) error
+	GetSessionByID(ctx context.Context, id string) (*repository.Session, error)
+	ListSessionsByUser(ctx context.Context, userID string) ([]repository.Session, error)
+	RevokeSession(ctx context.Context, id string) error
+	TouchSession(ctx context.Context, id string, seenAt time.Time) error
+}
+
+// AccountMailer delivers email verification links.
+type AccountMailer interface {
+	SendEmailVerification(ctx context.Context, email, link string) error
+}
+
+// SessionMetadata describes the client making a request. It is stored on new sessions and on
+// security events.
+type SessionMetadata struct {
+	UserAgent string
+	IPAddress string
+}
+
+type sessionMetadataKey struct{}
+
+// WithSessionMetadata attaches request details that IssueToken and RecordSecurityEvent record.
+func WithSessionMetadata(ctx context.Context, meta SessionMetadata) context.Context {
+	return context.WithValue(ctx, sessionMetadataKey{}, meta)
+}
+
+func sessionMetadataFromContext(ctx context.Context) SessionMetadata {
+	meta, _ := ctx.Value(sessionMetadataKey{}).(SessionMetadata)
+	return meta
+}
+
+// ProfileUpdate holds the fields of a PATCH /api/auth/me request; nil fields are unchanged.
+type ProfileUpdate struct {
+	FirstName *string
+	LastName  *string
+	Email     *string
+}
+
+// ActiveSession is a session as shown to its owner.
+type ActiveSession struct {
+	repository.Session
+	Current bool `json:"current"`
+}
+
+type emailChangeClaims struct {
+	UserID   string `json:"user_id"`
+	OldEmail string `json:"old_email"`
+	NewEmail string `json:"new_email"`
+	jwt.RegisteredClaims
+}
+
+// CheckSession rejects tokens whose session has been revoked. Tokens issued without a session
+// (before tracking was enabled) stay valid until they expire.
+func (s *AuthService) CheckSession(ctx context.Context, claims *Claims) error {
+	ctx, span := tracing.Start(ctx, "AuthService.CheckSession")
+	defer span.End()
+
+	if s.sessions == nil || claims.ID == "" {
+		return nil
+	}
+
+	session, err := s.sessions.GetSessionByID(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrSessionRevoked
+		}
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+	if session.RevokedAt != "" || session.UserID != claims.UserID {
+		return ErrSessionRevoked
+	}
+
+	now := time.Now()
+	if last, err := time.Parse(time.RFC3339, session.LastSeenAt); err != nil || now.Sub(last) >= sessionSeenResolution {
+		if err := s.sessions.TouchSession(ctx, session.ID, now); err != nil {
+			authLog.WarnContext(ctx, "failed to record session activity", "session_id", session.ID, logger.Error(err))
+		}
+	}
+	return nil
+}
+
+// UpdateProfile changes the user's name immediately. A new email is not applied until the
+// link sent to that address is confirmed; the pending address is returned.
+func (s *AuthService) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (*repository.User, string, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.UpdateProfile")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	changed := false
+	if update.FirstName != nil {
+		user.FirstName = strings.TrimSpace(*update.FirstName)
+		changed = true
+	}
+	if update.LastName != nil {
+		user.LastName = strings.TrimSpace(*update.LastName)
+		changed = true
+	}
+	if changed && (user.FirstName == "" || user.LastName == "") {
+		return nil, "", ErrProfileInvalidName
+	}
+
+	pendingEmail := ""
+	if update.Email != nil && !strings.EqualFold(strings.TrimSpace(*update.Email), user.Email) {
+		pendingEmail = strings.TrimSpace(*update.Email)
+		if err := s.checkEmailChange(ctx, user, pendingEmail); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if changed {
+		user.UpdatedAt = time.Now().Format(time.RFC3339)
+		if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+			return nil, "", fmt.Errorf("failed to update profile: %w", err)
+		}
+	}
+
+	if pendingEmail != "" {
+		if err := s.sendEmailVerification(ctx, user, pendingEmail); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return user, pendingEmail, nil
+}
+
+func (s *AuthService) checkEmailChange(ctx context.Context, user *repository.User, email string) error {
+	if user.AuthProvider == repository.AuthProviderOIDC {
+		return ErrEmailManagedByProvider
+	}
+	if s.accountMailer == nil {
+		return ErrEmailVerificationDisabled
+	}
+	if !authEmailRegex.MatchString(email) {
+		return ErrAuthInvalidEmail
+	}
+	if existing, err := s.userRepo.GetUserByEmail(ctx, email); err == nil && existing.ID != user.ID {
+		return ErrUserExists
+	}
+	return nil
+}
+
+func (s *AuthService) sendEmailVerification(ctx context.Context, user *repository.User, email string) error {
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, emailChangeClaims{
+		UserID:   user.ID,
+		OldEmail: user.Email,
+		NewEmail: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{emailChangeAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(emailChangeTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}).SignedString(s.secret)
+	if err != nil {
+		return fmt.Errorf("failed to sign email verification: %w", err)
+	}
+
+	if err := s.accountMailer.SendEmailVerification(ctx, email, tokenLink(s.verifyBaseURL, token)); err != nil {
+		return fmt.Errorf("failed to send email verification: %w", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange applies the email change carried by a verification token. The token only
+// works while the account still has the email it was issued for, so it cannot be replayed.
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, token string) (*repository.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ConfirmEmailChange")
+	defer span.End()
+
+	var claims emailChangeClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !claims.VerifyAudience(emailChangeAudience, true) {
+		return nil, ErrEmailChangeInvalid
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil || user.Email != claims.OldEmail {
+		return nil, ErrEmailChangeInvalid
+	}
+	if existing, err := s.userRepo.GetUserByEmail(ctx, claims.NewEmail); err == nil && existing.ID != user.ID {
+		return nil, ErrUserExists
+	}
+
+	user.Email = claims.NewEmail
+	user.UpdatedAt = time.Now().Format(time.RFC3339)
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to update email: %w", err)
+	}
+	s.RecordSecurityEvent(ctx, repository.SecurityEvent{UserID: user.ID, Type: SecurityEventEmailChanged})
+	authLog.InfoContext(ctx, "email changed", logger.KeyUserID, user.ID, "username", user.Username)
+	return user, nil
+}
+
+// ChangePassword replaces the password after checking the current one, then signs out every
+// other session.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, currentSessionID, currentPassword, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	// Passwords are not trimmed, so only empty values are missing.
+	v := validate.New(time.Now())
+	if currentPassword == "" {
+		v.Add("current_password", "is required")
+	}
+	if newPassword == "" {
+		v.Add("new_password", "is required")
+	}
+	if err := v.Err(ErrPasswordFieldsMissing); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" {
+		return ErrPasswordNotSet
+	}
+	if ok, _ := s.passwords.Verify(user.PasswordHash, currentPassword); !ok {
+		return ErrCurrentPasswordIncorrect
+	}
+	if err := s.passwordPolicy.Check(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = hashedPassword
+	user.UpdatedAt = time.Now().Format(time.RFC3339)
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	s.RecordSecurityEvent(ctx, repository.SecurityEvent{UserID: user.ID, Type: SecurityEventPasswordChanged, SessionID: currentSessionID})
+	if s.sessions != nil {
+		if _, err := s.RevokeOtherSessions(ctx, userID, currentSessionID); err != nil {
+			authLog.ErrorContext(ctx, "failed to revoke sessions after password change", logger.Error(err))
+		}
+	}
+	authLog.InfoContext(ctx, "password changed", "username", user.Username)
+	return nil
+}
+
+// ListSessions returns the user's unexpired, unrevoked sessions, newest first.
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]ActiveSession, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ListSessions")
+	defer span.End()
+
+	if s.sessions == nil {
+		return nil, ErrSessionsDisabled
+	}
+
+	sessions, err := s.sessions.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	now := time.Now().Unix()
+	active := make([]ActiveSession, 0, len(sessions))
+	for _, session := range sessions {
+		if session.RevokedAt != "" || session.TTL <= now {
+			continue
+		}
+		active = append(active, ActiveSession{Session: session, Current: session.ID == currentSessionID})
+	}
+	// RFC 3339 timestamps in the same zone sort lexically.
+	sort.Slice(active, func(i, j int) bool { return active[i].CreatedAt > active[j].CreatedAt })
+	return active, nil
+}
+
+// RevokeSession signs out one of the user's sessions.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.RevokeSession")
+	defer span.End()
+
+	if s.sessions == nil {
+		return ErrSessionsDisabled
+	}
+
+	session, err := s.sessions.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+	// Other users' sessions are reported as missing rather than forbidden.
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	if err := s.sessions.RevokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	s.RecordSecurityEvent(ctx, repository.SecurityEvent{UserID: userID, Type: SecurityEventSessionRevoked, SessionID: sessionID})
+	return nil
+}
+
+// RevokeOtherSessions signs out every session of the user except keepSessionID and returns how
+// many were revoked.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) (int, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RevokeOtherSessions")
+	defer span.End()
+
+	if s.sessions == nil {
+		return 0, ErrSessionsDisabled
+	}
+
+	sessions, err := s.sessions.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == keepSessionID || session.RevokedAt != "" {
+			continue
+		}
+		if err := s.sessions.RevokeSession(ctx, session.ID); err != nil {
+			return revoked, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		s.RecordSecurityEvent(ctx, repository.SecurityEvent{UserID: userID, Type: SecurityEventSessionRevoked, SessionID: session.ID})
+		revoked++
+	}
+	return revoked, nil
+}
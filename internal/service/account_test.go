@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockSessionRepository is a map-backed SessionRepository
+type MockSessionRepository struct {
+	sessions map[string]*repository.Session
+}
+
+func NewMockSessionRepository() *MockSessionRepository {
+	return &MockSessionRepository{sessions: map[string]*repository.Session{}}
+}
+
+func (m *MockSessionRepository) CreateSession(ctx context.Context, session *repository.Session) error {
+	cp := *session
+	m.sessions[session.ID] = &cp
+	return nil
+}
+
+func (m *MockSessionRepository) GetSessionByID(ctx context.Context, id string) (*repository.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, repository.ErrSessionNotFound
+	}
+	cp := *session
+	return &cp, nil
+}
+
+func (m *MockSessionRepository) ListSessionsByUser(ctx context.Context, userID string) ([]repository.Session, error) {
+	var out []repository.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID {
+			out = append(out, *session)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockSessionRepository) RevokeSession(ctx context.Context, id string) error {
+	session, ok := m.sessions[id]
+	if !ok {
+		return repository.ErrSessionNotFound
+	}
+	session.RevokedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (m *MockSessionRepository) TouchSession(ctx context.Context, id string, seenAt time.Time) error {
+	m.sessions[id].LastSeenAt = seenAt.Format(time.RFC3339)
+	return nil
+}
+
+// MockAccountMailer records the last verification link
+type MockAccountMailer struct {
+	email string
+	link  string
+}
+
+func (m *MockAccountMailer) SendEmailVerification(ctx context.Context, email, link string) error {
+	m.email, m.link = email, link
+	return nil
+}
+
+func newAccountTestService(t *testing.T) (*AuthService, *MemoryUserRepository, *MockSessionRepository, *MockAccountMailer) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	users := &MemoryUserRepository{users: map[string]*repository.User{
+		"casey@example.com": {ID: "user-1", Username: "casey", Email: "casey@example.com", PasswordHash: string(hash), FirstName: "Casey", LastName: "Jones", Role: "counsellor", IsActive: true},
+		"sam@example.com":   {ID: "user-2", Username: "sam", Email: "sam@example.com", FirstName: "Sam", LastName: "Lee", Role: "staff", IsActive: true, AuthProvider: repository.AuthProviderOIDC},
+	}}
+	sessions := NewMockSessionRepository()
+	mailer := &MockAccountMailer{}
+	svc := NewAuthService(users, "test-jwt-secret", WithSessions(sessions), WithEmailVerification(mailer, "https://app.example.com/verify-email"))
+	return svc, users, sessions, mailer
+}
+
+func issueSession(t *testing.T, svc *AuthService, users *MemoryUserRepository, email string) *Claims {
+	t.Helper()
+	ctx := WithSessionMetadata(context.Background(), SessionMetadata{UserAgent: "test-agent", IPAddress: "203.0.113.7"})
+	token, err := svc.IssueToken(ctx, users.users[email])
+	if err != nil {
+		t.Fatalf("IssueToken failed: %v", err)
+	}
+	claims, err := svc.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	return claims
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	ctx := context.Background()
+	svc, users, sessions, _ := newAccountTestService(t)
+
+	first := issueSession(t, svc, users, "casey@example.com")
+	second := issueSession(t, svc, users, "casey@example.com")
+	other := issueSession(t, svc, users, "sam@example.com")
+
+	stored := sessions.sessions[first.ID]
+	if stored == nil || stored.UserAgent != "test-agent" || stored.IPAddress != "203.0.113.7" || stored.TTL == 0 {
+		t.Fatalf("Expected session with request metadata, got %+v", stored)
+	}
+	if err := svc.CheckSession(ctx, first); err != nil {
+		t.Errorf("Expected active session, got: %v", err)
+	}
+	if sessions.sessions[first.ID].LastSeenAt == "" {
+		t.Error("Expected last seen time to be recorded")
+	}
+
+	list, err := svc.ListSessions(ctx, "user-1", second.ID)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(list))
+	}
+	for _, s := range list {
+		if s.Current != (s.ID == second.ID) {
+			t.Errorf("Session %s: unexpected current=%v", s.ID, s.Current)
+		}
+	}
+
+	if err := svc.RevokeSession(ctx, "user-1", other.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for another user's session, got: %v", err)
+	}
+	if err := svc.RevokeSession(ctx, "user-1", first.ID); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if err := svc.CheckSession(ctx, first); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked, got: %v", err)
+	}
+	if list, _ := svc.ListSessions(ctx, "user-1", second.ID); len(list) != 1 {
+		t.Errorf("Expected revoked session to be hidden, got %d sessions", len(list))
+	}
+
+	// Tokens issued before session tracking have no jti and stay valid
+	legacy, err := svc.GenerateToken(users.users["casey@example.com"])
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+	legacyClaims, err := svc.ValidateToken(legacy)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if err := svc.CheckSession(ctx, legacyClaims); err != nil {
+		t.Errorf("Expected legacy token to pass, got: %v", err)
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name          string
+		userID        string
+		current       string
+		newPassword   string
+		expectedError error
+	}{
+		{name: "Success - Password changed", userID: "user-1", current: "old-password", newPassword: "new-password-123"},
+		{name: "Failure - Wrong current password", userID: "user-1", current: "guess", newPassword: "new-password-123", expectedError: ErrCurrentPasswordIncorrect},
+		{name: "Failure - New password too short", userID: "user-1", current: "old-password", newPassword: "short", expectedError: ErrAuthInvalidPassword},
+		{name: "Failure - SSO user has no password", userID: "user-2", current: "anything", newPassword: "new-password-123", expectedError: ErrPasswordNotSet},
+		{name: "Failure - Missing fields", userID: "user-1", current: "", newPassword: "", expectedError: ErrPasswordFieldsMissing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc, users, sessions, _ := newAccountTestService(t)
+			current := issueSession(t, svc, users, "casey@example.com")
+			otherDevice := issueSession(t, svc, users, "casey@example.com")
+
+			err := svc.ChangePassword(ctx, tt.userID, current.ID, tt.current, tt.newPassword)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected %v, got: %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if _, _, err := svc.Login(ctx, "casey", tt.newPassword); err != nil {
+				t.Errorf("Expected login with new password, got: %v", err)
+			}
+			if sessions.sessions[current.ID].RevokedAt != "" {
+				t.Error("Expected the current session to stay active")
+			}
+			if sessions.sessions[otherDevice.ID].RevokedAt == "" {
+				t.Error("Expected other sessions to be revoked")
+			}
+		})
+	}
+}
+
+func TestAuthService_ChangePassword_ReportsMissingFields(t *testing.T) {
+	svc, users, _, _ := newAccountTestService(t)
+	current := issueSession(t, svc, users, "casey@example.com")
+
+	err := svc.ChangePassword(context.Background(), "user-1", current.ID, "old-password", "")
+
+	appErr, ok := apperror.As(err)
+	if !ok || !errors.Is(err, ErrPasswordFieldsMissing) {
+		t.Fatalf("Expected ErrPasswordFieldsMissing, got: %v", err)
+	}
+	if _, ok := appErr.Fields["new_password"]; !ok || len(appErr.Fields) != 1 {
+		t.Errorf("Expected only new_password in the fields, got %v", appErr.Fields)
+	}
+}
+
+func TestAuthService_UpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	svc, users, _, mailer := newAccountTestService(t)
+	str := func(s string) *string { return &s }
+
+	user, pending, err := svc.UpdateProfile(ctx, "user-1", ProfileUpdate{FirstName: str("Cass")})
+	if err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+	if user.FirstName != "Cass" || pending != "" || users.users["casey@example.com"].FirstName != "Cass" {
+		t.Errorf("Expected name change to be saved, got %+v pending=%q", user, pending)
+	}
+
+	if _, _, err := svc.UpdateProfile(ctx, "user-1", ProfileUpdate{LastName: str(" ")}); !errors.Is(err, ErrProfileInvalidName) {
+		t.Errorf("Expected ErrProfileInvalidName, got: %v", err)
+	}
+	if _, _, err := svc.UpdateProfile(ctx, "user-1", ProfileUpdate{Email: str("sam@example.com")}); !errors.Is(err, ErrUserExists) {
+		t.Errorf("Expected ErrUserExists, got: %v", err)
+	}
+	if _, _, err := svc.UpdateProfile(ctx, "user-1", ProfileUpdate{Email: str("not-an-email")}); !errors.Is(err, ErrAuthInvalidEmail) {
+		t.Errorf("Expected ErrAuthInvalidEmail, got: %v", err)
+	}
+	if _, _, err := svc.UpdateProfile(ctx, "user-2", ProfileUpdate{Email: str("sam.lee@example.com")}); !errors.Is(err, ErrEmailManagedByProvider) {
+		t.Errorf("Expected ErrEmailManagedByProvider, got: %v", err)
+	}
+
+	// The new email is only applied once the emailed link is confirmed
+	user, pending, err = svc.UpdateProfile(ctx, "user-1", ProfileUpdate{Email: str("casey.jones@example.com")})
+	if err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+	if pending != "casey.jones@example.com" || user.Email != "casey@example.com" {
+		t.Errorf("Expected pending email change, got email=%q pending=%q", user.Email, pending)
+	}
+	if mailer.email != "casey.jones@example.com" {
+		t.Fatalf("Expected verification mail to the new address, got %q", mailer.email)
+	}
+	link, err := url.Parse(mailer.link)
+	if err != nil {
+		t.Fatalf("invalid verification link: %v", err)
+	}
+	token := link.Query().Get("token")
+
+	if _, err := svc.ValidateToken(token); err == nil {
+		t.Error("Verification token must not be accepted as an access token")
+	}
+
+	user, err = svc.ConfirmEmailChange(ctx, token)
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange failed: %v", err)
+	}
+	if user.Email != "casey.jones@example.com" {
+		t.Errorf("Expected email to change, got %q", user.Email)
+	}
+
+	// Replaying the link fails because the account no longer has the old email
+	if _, err := svc.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrEmailChangeInvalid) {
+		t.Errorf("Expected ErrEmailChangeInvalid on replay, got: %v", err)
+	}
+	if _, err := svc.ConfirmEmailChange(ctx, "garbage"); !errors.Is(err, ErrEmailChangeInvalid) {
+		t.Errorf("Expected ErrEmailChangeInvalid, got: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+)
+
+// API key scopes
+const (
+	ScopeClientsRead  = "clients:read"
+	ScopeClientsWrite = "clients:write"
+)
+
+// APIKeyPrefix starts every API key so it can be told apart from a JWT.
+const APIKeyPrefix = "jak_"
+
+// lastUsedResolution limits how often a busy key writes its last-used time.
+const lastUsedResolution = time.Minute
+
+// API key errors
+var (
+	ErrAPIKeyMissingName   = apperror.Validation("api_key_name_required", "api key name is required")
+	ErrAPIKeyInvalidScopes = apperror.Validation("invalid_scopes", "at least one scope is required; valid scopes: clients:read, clients:write")
+	ErrAPIKeyInvalid       = apperror.Unauthorized("invalid_api_key", "invalid api key")
+	ErrAPIKeyRevoked       = apperror.Unauthorized("api_key_revoked", "api key has been revoked")
+	ErrAPIKeyNotFound      = apperror.NotFound("api_key_not_found", "api key not found")
+)
+
+var validScopes = map[string]bool{
+	ScopeClientsRead:  true,
+	ScopeClientsWrite: true,
+}
+
+// APIKeyRepository interface for dependency injection
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *repository.APIKey) error
+	GetAPIKeyByID(ctx context.Context, id string) (*repository.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]repository.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type APIKeyService struct {
+	repo APIKeyRepository
+	now  func() time.Time
+}
+
+func NewAPIKeyService(repo APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// IsAPIKey reports whether credential looks like an API key rather than a JWT.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// CreateAPIKey issues a new key. The plaintext key is returned once and cannot be recovered;
+// only the prefix and a SHA-256 hash of the secret are stored.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, createdBy, name string, scopes []string) (*repository.APIKey, string, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrAPIKeyMissingName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id, err := randomToken(6, hex.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &repository.APIKey{
+		ID:         id,
+		Name:       name,
+		SecretHash: hashAPIKeySecret(secret),
+		Scopes:     scopes,
+		CreatedBy:  createdBy,
+		CreatedAt:  s.now().Format(time.RFC3339),
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return key, APIKeyPrefix + id + "_" + secret, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]repository.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.ListAPIKeys")
+	defer span.End()
+
+	keys, err := s.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+
+	if err := s.repo.RevokeAPIKey(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+// Authenticate verifies a plaintext key and records its use.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*repository.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+
+	id, secret, ok := strings.Cut(strings.TrimPrefix(rawKey, APIKeyPrefix), "_")
+	if !IsAPIKey(rawKey) || !ok || id == "" || secret == "" {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	key, err := s.repo.GetAPIKeyByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, fmt.Errorf("failed to load api key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrAPIKeyInvalid
+	}
+	if key.RevokedAt != "" {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	now := s.now()
+	if last, err := time.Parse(time.RFC3339, key.LastUsedAt); err != nil || now.Sub(last) >= lastUsedResolution {
+		if err := s.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			authLog.WarnContext(ctx, "failed to record api key use", "api_key_id", key.ID, logger.Error(err))
+		} else {
+			key.LastUsedAt = now.Format(time.RFC3339)
+		}
+	}
+
+	return key, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(strings.ToLower(scope))
+		if !validScopes[scope] {
+			return nil, ErrAPIKeyInvalidScopes
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			out = append(out, scope)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrAPIKeyInvalidScopes
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return encode(b), nil
+}
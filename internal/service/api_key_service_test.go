@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+// Mock APIKeyRepository backed by a map so created keys can be authenticated.
+type MockAPIKeyRepository struct {
+	keys    map[string]*repository.APIKey
+	touched int
+}
+
+func NewMockAPIKeyRepository() *MockAPIKeyRepository {
+	return &MockAPIKeyRepository{keys: map[string]*repository.APIKey{}}
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *repository.APIKey) error {
+	cp := *key
+	m.keys[key.ID] = &cp
+	return nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByID(ctx context.Context, id string) (*repository.APIKey, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	cp := *key
+	return &cp, nil
+}
+
+func (m *MockAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]repository.APIKey, error) {
+	var out []repository.APIKey
+	for _, key := range m.keys {
+		out = append(out, *key)
+	}
+	return out, nil
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	key, ok := m.keys[id]
+	if !ok {
+		return repository.ErrAPIKeyNotFound
+	}
+	key.RevokedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	m.touched++
+	m.keys[id].LastUsedAt = usedAt.Format(time.RFC3339)
+	return nil
+}
+
+func TestAPIKeyService_CreateAPIKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		keyName       string
+		scopes        []string
+		wantScopes    []string
+		expectedError error
+	}{
+		{
+			name:       "Success - Scopes normalised",
+			keyName:    "onboarding-assistant",
+			scopes:     []string{"clients:write", "Clients:Read", "clients:write"},
+			wantScopes: []string{"clients:read", "clients:write"},
+		},
+		{name: "Failure - Missing name", keyName: " ", scopes: []string{"clients:read"}, expectedError: ErrAPIKeyMissingName},
+		{name: "Failure - No scopes", keyName: "nightly", scopes: nil, expectedError: ErrAPIKeyInvalidScopes},
+		{name: "Failure - Unknown scope", keyName: "nightly", scopes: []string{"admin:all"}, expectedError: ErrAPIKeyInvalidScopes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMockAPIKeyRepository()
+			svc := NewAPIKeyService(repo)
+
+			key, raw, err := svc.CreateAPIKey(context.Background(), "admin-1", tt.keyName, tt.scopes)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected %v, got: %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !strings.HasPrefix(raw, APIKeyPrefix+key.ID+"_") {
+				t.Errorf("Expected key to start with %s%s_, got %q", APIKeyPrefix, key.ID, raw)
+			}
+			if strings.Contains(repo.keys[key.ID].SecretHash, strings.TrimPrefix(raw, APIKeyPrefix+key.ID+"_")) {
+				t.Error("Expected only a hash of the secret to be stored")
+			}
+			if strings.Join(key.Scopes, ",") != strings.Join(tt.wantScopes, ",") {
+				t.Errorf("Expected scopes %v, got %v", tt.wantScopes, key.Scopes)
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	key, raw, err := svc.CreateAPIKey(ctx, "admin-1", "nightly", []string{"clients:read"})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	got, err := svc.Authenticate(ctx, raw)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if got.ID != key.ID || got.LastUsedAt != now.Format(time.RFC3339) {
+		t.Errorf("Unexpected key: %+v", got)
+	}
+
+	// A second use within the resolution window does not write again.
+	now = now.Add(10 * time.Second)
+	if _, err := svc.Authenticate(ctx, raw); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if repo.touched != 1 {
+		t.Errorf("Expected 1 last-used write, got %d", repo.touched)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := svc.Authenticate(ctx, raw); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if repo.touched != 2 {
+		t.Errorf("Expected 2 last-used writes, got %d", repo.touched)
+	}
+
+	for name, bad := range map[string]string{
+		"wrong secret": APIKeyPrefix + key.ID + "_not-the-secret",
+		"unknown id":   APIKeyPrefix + "ffffffffffff_secret",
+		"no prefix":    strings.TrimPrefix(raw, APIKeyPrefix),
+		"no secret":    APIKeyPrefix + key.ID,
+	} {
+		if _, err := svc.Authenticate(ctx, bad); !errors.Is(err, ErrAPIKeyInvalid) {
+			t.Errorf("%s: expected ErrAPIKeyInvalid, got: %v", name, err)
+		}
+	}
+
+	if err := svc.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, raw); !errors.Is(err, ErrAPIKeyRevoked) {
+		t.Errorf("Expected ErrAPIKeyRevoked, got: %v", err)
+	}
+	if err := svc.RevokeAPIKey(ctx, "missing"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected ErrAPIKeyNotFound, got: %v", err)
+	}
+}
@@ -4,28 +4,42 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/metrics"
 	"github.com/jmason/john_ai_project/internal/repository"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/jmason/john_ai_project/internal/tracing"
 )
 
 // Auth validation errors
 var (
-	ErrAuthMissingFields     = errors.New("username, email, password, first_name, and last_name are required")
-	ErrAuthInvalidPassword   = errors.New("password must be at least 8 characters long")
-	ErrAuthInvalidEmail      = errors.New("invalid email format")
-	ErrAuthLoginMissingFields = errors.New("login and password are required")
-	ErrUserExists            = errors.New("user with this email already exists")
-	ErrUsernameTaken          = errors.New("username is already taken")
-	ErrInvalidCredentials    = errors.New("invalid credentials")
-	ErrAccountDisabled       = errors.New("account is disabled")
+	ErrAuthMissingFields      = apperror.Validation("missing_fields", "username, email, password, first_name, and last_name are required")
+	ErrAuthInvalidPassword    = apperror.Validation("invalid_password", "password must be at least 8 characters long")
+	ErrAuthInvalidEmail       = apperror.Validation("invalid_email", "invalid email format")
+	ErrAuthLoginMissingFields = apperror.Validation("missing_credentials", "login and password are required")
+	ErrUserExists             = apperror.Conflict("user_exists", "user with this email already exists")
+	ErrUsernameTaken          = apperror.Conflict("username_taken", "username is already taken")
+	ErrInvalidCredentials     = apperror.Unauthorized("invalid_credentials", "invalid credentials")
+	ErrAccountDisabled        = apperror.Forbidden("account_disabled", "account is disabled")
+	ErrRegistrationClosed     = apperror.Forbidden("registration_closed", "registration requires an invitation")
+	ErrInvitationsDisabled    = apperror.NotImplemented("invitations_disabled", "invitations are not configured")
 )
 
+// Loggers for authentication events and for admin actions taken on another user's behalf.
+var (
+	authLog  = logger.Component("auth")
+	auditLog = logger.Component("audit")
+)
+
+// loginAttempts counts password and SSO logins; reason is one of the LoginFailure values.
+var loginAttempts = metrics.NewCounter("auth_logins_total",
+	"Login attempts by method and result; reason is set on failures.", "method", "result", "reason")
+
 var authEmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
 
 // UserRepository interface for dependency injection
@@ -34,44 +48,193 @@ type UserRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (*repository.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*repository.User, error)
 	GetUserByID(ctx context.Context, id string) (*repository.User, error)
+	UpdateUser(ctx context.Context, user *repository.User) error
 }
 
+// InvitationRedeemer claims invitations during registration. InvitationService implements it.
+type InvitationRedeemer interface {
+	Redeem(ctx context.Context, token, email, userID string) (*repository.Invitation, error)
+	Release(ctx context.Context, invitationID string) error
+}
+
+// DefaultHMACKeyID is the kid of the HS256 key derived from JWT_SECRET.
+const DefaultHMACKeyID = "hs256-default"
+
+// tokenTTL is how long an access token, and the session it belongs to, stays valid.
+const tokenTTL = 24 * time.Hour
+
 type AuthService struct {
-	userRepo  UserRepository
-	jwtSecret []byte
+	userRepo         UserRepository
+	signer           TokenSigner
+	invitations      InvitationRedeemer
+	openRegistration bool
+	sessions         SessionRepository
+	accountMailer    AccountMailer
+	verifyBaseURL    string
+	passwords        PasswordHasher
+	passwordPolicy   PasswordPolicy
+	securityEvents   SecurityEventRepository
+	// secret signs email verification tokens.
+	secret []byte
+}
+
+// AuthOption configures optional AuthService behaviour.
+type AuthOption func(*AuthService)
+
+// WithTokenSigner replaces the default HS256 signer built from the JWT secret, e.g. with a
+// KeySet holding RS256/ES256 keys.
+func WithTokenSigner(signer TokenSigner) AuthOption {
+	return func(s *AuthService) {
+		s.signer = signer
+	}
+}
+
+// WithInvitations enables invite-based registration through RegisterWithInvite.
+func WithInvitations(invitations InvitationRedeemer) AuthOption {
+	return func(s *AuthService) {
+		s.invitations = invitations
+	}
+}
+
+// WithOpenRegistration controls whether Register accepts sign-ups without an invitation.
+// Open registration is enabled by default.
+func WithOpenRegistration(enabled bool) AuthOption {
+	return func(s *AuthService) {
+		s.openRegistration = enabled
+	}
+}
+
+// WithSessions records a session for every issued token so users can list and revoke their
+// logins. Without it tokens are valid until they expire.
+func WithSessions(sessions SessionRepository) AuthOption {
+	return func(s *AuthService) {
+		s.sessions = sessions
+	}
+}
+
+// WithEmailVerification enables email changes through UpdateProfile. The confirmation link is
+// mailed to the new address; baseURL is the frontend page that accepts it (?token= is added).
+func WithEmailVerification(mailer AccountMailer, baseURL string) AuthOption {
+	return func(s *AuthService) {
+		s.accountMailer = mailer
+		s.verifyBaseURL = baseURL
+	}
+}
+
+// WithPasswordHasher replaces the default hasher (Argon2id, accepting legacy bcrypt hashes).
+// Hashes the hasher reports as outdated are upgraded when the user next logs in.
+func WithPasswordHasher(hasher PasswordHasher) AuthOption {
+	return func(s *AuthService) {
+		s.passwords = hasher
+	}
 }
 
-func NewAuthService(userRepo UserRepository, jwtSecret string) *AuthService {
-	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+// WithPasswordPolicy replaces BasicPasswordPolicy for registration and password changes.
+func WithPasswordPolicy(policy PasswordPolicy) AuthOption {
+	return func(s *AuthService) {
+		s.passwordPolicy = policy
 	}
 }
 
+func NewAuthService(userRepo UserRepository, jwtSecret string, opts ...AuthOption) *AuthService {
+	s := &AuthService{
+		userRepo:         userRepo,
+		openRegistration: true,
+		passwordPolicy:   BasicPasswordPolicy(),
+		secret:           []byte(jwtSecret),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.passwords == nil {
+		s.passwords = DefaultPasswordHasher()
+	}
+	if s.signer == nil {
+		// An HMAC key can always sign, so NewKeySet cannot fail here.
+		s.signer, _ = NewKeySet(NewHMACSigningKey(DefaultHMACKeyID, []byte(jwtSecret)))
+	}
+	return s
+}
+
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
+	// Actor is set on impersonation tokens: the admin acting as the user above.
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Register creates a user with the default "user" role. It fails with ErrRegistrationClosed
+// when open registration is disabled; invited staff use RegisterWithInvite instead.
 func (s *AuthService) Register(ctx context.Context, username, email, password, firstName, lastName string) (*repository.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	if !s.openRegistration {
+		return nil, ErrRegistrationClosed
+	}
+
+	user, err := s.newUser(ctx, username, email, password, firstName, lastName, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, uniquenessError(err)
+	}
+
+	return user, nil
+}
+
+// RegisterWithInvite consumes a single-use invitation token and creates the user with the role
+// the invitation was issued for. The invitation is reopened if the user cannot be created.
+func (s *AuthService) RegisterWithInvite(ctx context.Context, inviteToken, username, email, password, firstName, lastName string) (*repository.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RegisterWithInvite")
+	defer span.End()
+
+	if s.invitations == nil {
+		return nil, ErrInvitationsDisabled
+	}
+
+	user, err := s.newUser(ctx, username, email, password, firstName, lastName, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := s.invitations.Redeem(ctx, inviteToken, user.Email, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = inv.Role
+
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		if relErr := s.invitations.Release(ctx, inv.ID); relErr != nil {
+			authLog.ErrorContext(ctx, "failed to reopen invitation", "invitation_id", inv.ID, logger.Error(relErr))
+		}
+		return nil, uniquenessError(err)
+	}
+
+	return user, nil
+}
+
+// newUser validates registration input, checks for existing accounts and returns an unsaved user.
+func (s *AuthService) newUser(ctx context.Context, username, email, password, firstName, lastName, role string) (*repository.User, error) {
 	// Validate required fields
 	if username == "" || email == "" || password == "" || firstName == "" || lastName == "" {
 		return nil, ErrAuthMissingFields
 	}
 
-	// Validate password length
-	if len(password) < 8 {
-		return nil, ErrAuthInvalidPassword
-	}
-
 	// Validate email format
 	if !authEmailRegex.MatchString(email) {
 		return nil, ErrAuthInvalidEmail
 	}
 
+	if err := s.passwordPolicy.Check(password, username, email); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists by email
 	_, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err == nil {
@@ -85,96 +248,190 @@ func (s *AuthService) Register(ctx context.Context, username, email, password, f
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwords.Hash(password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// Create user
 	now := time.Now().Format(time.RFC3339)
-	user := &repository.User{
+	return &repository.User{
 		ID:           uuid.New().String(),
 		Username:     username,
 		Email:        email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 		FirstName:    firstName,
 		LastName:     lastName,
-		Role:         "user",
+		Role:         role,
 		IsActive:     true,
 		CreatedAt:    now,
 		UpdatedAt:    now,
-	}
+	}, nil
+}
 
-	if err := s.userRepo.CreateUser(ctx, user); err != nil {
-		return nil, err
+// uniquenessError translates a guard conflict from the repository into the matching service
+// error. The lookups in newUser catch most duplicates; the guard catches concurrent requests.
+func uniquenessError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrEmailTaken):
+		return ErrUserExists
+	case errors.Is(err, repository.ErrUsernameTaken):
+		return ErrUsernameTaken
 	}
-
-	return user, nil
+	return err
 }
 
 func (s *AuthService) Login(ctx context.Context, usernameOrEmail, password string) (string, *repository.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
 	// Validate required fields
 	if usernameOrEmail == "" || password == "" {
 		return "", nil, ErrAuthLoginMissingFields
 	}
 
-	log.Printf("[AUTH] Login attempt for: %s", usernameOrEmail)
+	authLog.DebugContext(ctx, "login attempt", "login", usernameOrEmail)
 
 	// Try to get user by email first
 	user, err := s.userRepo.GetUserByEmail(ctx, usernameOrEmail)
 	if err != nil {
-		log.Printf("[AUTH] User not found by email, trying username...")
 		// If not found by email, try username
 		user, err = s.userRepo.GetUserByUsername(ctx, usernameOrEmail)
 		if err != nil {
-			log.Printf("[AUTH] User not found by username either: %v", err)
+			authLog.InfoContext(ctx, "login failed", "login", usernameOrEmail, "reason", LoginFailureUnknownUser)
+			s.RecordSecurityEvent(ctx, repository.SecurityEvent{
+				Type:   SecurityEventLoginFailed,
+				Login:  usernameOrEmail,
+				Method: LoginMethodPassword,
+				Reason: LoginFailureUnknownUser,
+			})
 			return "", nil, ErrInvalidCredentials
 		}
-		log.Printf("[AUTH] User found by username: %s", user.Username)
-	} else {
-		log.Printf("[AUTH] User found by email: %s", user.Email)
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		log.Printf("[AUTH] Account is disabled for user: %s", user.Username)
+		authLog.InfoContext(ctx, "login failed", logger.KeyUserID, user.ID, "reason", LoginFailureAccountDisabled)
+		s.recordLoginFailed(ctx, user, LoginFailureAccountDisabled)
 		return "", nil, ErrAccountDisabled
 	}
 
-	log.Printf("[AUTH] Comparing password hash...")
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		log.Printf("[AUTH] Password verification failed: %v", err)
+	if ok, err := s.passwords.Verify(user.PasswordHash, password); !ok {
+		authLog.InfoContext(ctx, "login failed", logger.KeyUserID, user.ID, "reason", LoginFailureBadPassword, logger.Error(err))
+		s.recordLoginFailed(ctx, user, LoginFailureBadPassword)
 		return "", nil, ErrInvalidCredentials
 	}
 
-	log.Printf("[AUTH] Password verified successfully")
+	s.upgradePasswordHash(ctx, user, password)
 	// Generate JWT token
-	token, err := s.GenerateToken(user)
+	token, sessionID, err := s.issueToken(ctx, user)
 	if err != nil {
-		log.Printf("[AUTH] Failed to generate token: %v", err)
+		authLog.ErrorContext(ctx, "failed to issue token", logger.KeyUserID, user.ID, logger.Error(err))
 		return "", nil, err
 	}
 
-	log.Printf("[AUTH] Login successful for user: %s", user.Username)
+	s.RecordSecurityEvent(ctx, repository.SecurityEvent{
+		UserID:    user.ID,
+		Type:      SecurityEventLoginSucceeded,
+		Method:    LoginMethodPassword,
+		SessionID: sessionID,
+	})
+	authLog.InfoContext(ctx, "login succeeded", logger.KeyUserID, user.ID, "username", user.Username)
 	return token, user, nil
 }
 
+func (s *AuthService) recordLoginFailed(ctx context.Context, user *repository.User, reason string) {
+	s.RecordSecurityEvent(ctx, repository.SecurityEvent{
+		UserID: user.ID,
+		Type:   SecurityEventLoginFailed,
+		Method: LoginMethodPassword,
+		Reason: reason,
+	})
+}
+
+// upgradePasswordHash rehashes the password with the current hasher settings when the stored
+// hash is outdated. Failures are logged only; the old hash keeps working.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, user *repository.User, password string) {
+	if !s.passwords.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		authLog.WarnContext(ctx, "failed to rehash password", logger.KeyUserID, user.ID, logger.Error(err))
+		return
+	}
+	previous := user.PasswordHash
+	user.PasswordHash = hash
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		user.PasswordHash = previous
+		authLog.WarnContext(ctx, "failed to store upgraded password hash", logger.KeyUserID, user.ID, logger.Error(err))
+		return
+	}
+	authLog.InfoContext(ctx, "upgraded password hash", logger.KeyUserID, user.ID)
+}
+
+// GenerateToken signs an access token for user without recording a session.
 func (s *AuthService) GenerateToken(user *repository.User) (string, error) {
-	claims := &Claims{
+	return s.signToken(user, "", time.Now())
+}
+
+// IssueToken starts a session for user, when sessions are enabled, and returns an access token
+// whose jti is the session id. Request details are taken from WithSessionMetadata.
+func (s *AuthService) IssueToken(ctx context.Context, user *repository.User) (string, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.IssueToken")
+	defer span.End()
+
+	token, _, err := s.issueToken(ctx, user)
+	return token, err
+}
+
+// issueToken is IssueToken, also returning the new session id ("" when sessions are disabled).
+func (s *AuthService) issueToken(ctx context.Context, user *repository.User) (string, string, error) {
+	if s.sessions == nil {
+		token, err := s.GenerateToken(user)
+		return token, "", err
+	}
+
+	now := time.Now()
+	meta := sessionMetadataFromContext(ctx)
+	session := &repository.Session{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		UserAgent: meta.UserAgent,
+		IPAddress: meta.IPAddress,
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(tokenTTL).Format(time.RFC3339),
+		TTL:       now.Add(tokenTTL).Unix(),
+	}
+	if err := s.sessions.CreateSession(ctx, session); err != nil {
+		return "", "", fmt.Errorf("failed to start session: %w", err)
+	}
+
+	token, err := s.signToken(user, session.ID, now)
+	return token, session.ID, err
+}
+
+func (s *AuthService) signToken(user *repository.User, sessionID string, now time.Time) (string, error) {
+	return s.sign(userClaims(user, sessionID, now, tokenTTL))
+}
+
+func userClaims(user *repository.User, sessionID string, now time.Time, ttl time.Duration) *Claims {
+	return &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Email:    user.Email,
 		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "john-ai-project",
 		},
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(s.jwtSecret)
+func (s *AuthService) sign(claims *Claims) (string, error) {
+	tokenString, err := s.signer.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -183,25 +440,29 @@ func (s *AuthService) GenerateToken(user *repository.User) (string, error) {
 }
 
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.signer.Keyfunc)
 
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Invitation and SSO flow tokens may share the HMAC secret; they carry an audience and no
+	// user, so they are never accepted as access tokens.
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.UserID != "" && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token claims")
 }
 
+// JWKS returns the public keys that verify tokens issued by this service.
+func (s *AuthService) JWKS() JWKS {
+	return s.signer.JWKS()
+}
+
 func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*repository.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetUserByID")
+	defer span.End()
+
 	return s.userRepo.GetUserByID(ctx, userID)
 }
-
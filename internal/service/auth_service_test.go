@@ -5,7 +5,9 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/jmason/john_ai_project/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -16,6 +18,7 @@ type MockUserRepository struct {
 	GetUserByEmailFunc    func(ctx context.Context, email string) (*repository.User, error)
 	GetUserByUsernameFunc func(ctx context.Context, username string) (*repository.User, error)
 	GetUserByIDFunc       func(ctx context.Context, id string) (*repository.User, error)
+	UpdateUserFunc        func(ctx context.Context, user *repository.User) error
 }
 
 func (m *MockUserRepository) CreateUser(ctx context.Context, user *repository.User) error {
@@ -46,6 +49,13 @@ func (m *MockUserRepository) GetUserByID(ctx context.Context, id string) (*repos
 	return nil, errors.New("user not found")
 }
 
+func (m *MockUserRepository) UpdateUser(ctx context.Context, user *repository.User) error {
+	if m.UpdateUserFunc != nil {
+		return m.UpdateUserFunc(ctx, user)
+	}
+	return nil
+}
+
 func TestAuthService_Register(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -178,6 +188,46 @@ func TestAuthService_Register(t *testing.T) {
 			},
 			expectedError: "username is already taken",
 		},
+		{
+			name:      "Failure - Concurrent registration claims email",
+			username:  "johndoe",
+			email:     "john@example.com",
+			password:  "password123",
+			firstName: "John",
+			lastName:  "Doe",
+			mockSetup: func(m *MockUserRepository) {
+				m.GetUserByEmailFunc = func(ctx context.Context, email string) (*repository.User, error) {
+					return nil, errors.New("user not found")
+				}
+				m.GetUserByUsernameFunc = func(ctx context.Context, username string) (*repository.User, error) {
+					return nil, errors.New("user not found")
+				}
+				m.CreateUserFunc = func(ctx context.Context, user *repository.User) error {
+					return repository.ErrEmailTaken
+				}
+			},
+			expectedError: "user with this email already exists",
+		},
+		{
+			name:      "Failure - Concurrent registration claims username",
+			username:  "johndoe",
+			email:     "john@example.com",
+			password:  "password123",
+			firstName: "John",
+			lastName:  "Doe",
+			mockSetup: func(m *MockUserRepository) {
+				m.GetUserByEmailFunc = func(ctx context.Context, email string) (*repository.User, error) {
+					return nil, errors.New("user not found")
+				}
+				m.GetUserByUsernameFunc = func(ctx context.Context, username string) (*repository.User, error) {
+					return nil, errors.New("user not found")
+				}
+				m.CreateUserFunc = func(ctx context.Context, user *repository.User) error {
+					return repository.ErrUsernameTaken
+				}
+			},
+			expectedError: "username is already taken",
+		},
 		{
 			name:      "Failure - Repository error on create",
 			username:  "johndoe",
@@ -376,6 +426,82 @@ func TestAuthService_Login(t *testing.T) {
 	}
 }
 
+func TestAuthService_Login_CountsAttempts(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	repo := &MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*repository.User, error) {
+			return &repository.User{ID: "user-1", Username: "johndoe", Email: email, PasswordHash: string(hash), IsActive: true}, nil
+		},
+	}
+	svc := NewAuthService(repo, "test-jwt-secret")
+	successes := loginAttempts.Value(LoginMethodPassword, "success", "")
+	badPasswords := loginAttempts.Value(LoginMethodPassword, "failure", LoginFailureBadPassword)
+
+	svc.Login(context.Background(), "john@example.com", "password123")
+	svc.Login(context.Background(), "john@example.com", "wrong-password")
+
+	if got := loginAttempts.Value(LoginMethodPassword, "success", "") - successes; got != 1 {
+		t.Errorf("Expected 1 successful login counted, got %v", got)
+	}
+	if got := loginAttempts.Value(LoginMethodPassword, "failure", LoginFailureBadPassword) - badPasswords; got != 1 {
+		t.Errorf("Expected 1 bad password counted, got %v", got)
+	}
+}
+
+func TestAuthService_Login_UpgradesPasswordHash(t *testing.T) {
+	legacyHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	argon := NewArgon2idHasher(testArgon2idParams)
+	hashers := NewPasswordHashers(argon, NewBcryptHasher(bcrypt.DefaultCost))
+	currentHash, _ := argon.Hash("password123")
+
+	tests := []struct {
+		name         string
+		storedHash   string
+		updateErr    error
+		expectUpdate bool
+	}{
+		{name: "Legacy bcrypt hash is upgraded", storedHash: string(legacyHash), expectUpdate: true},
+		{name: "Upgrade failure does not block login", storedHash: string(legacyHash), updateErr: errors.New("db down"), expectUpdate: true},
+		{name: "Current hash is left alone", storedHash: currentHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updatedHash string
+			repo := &MockUserRepository{
+				GetUserByEmailFunc: func(ctx context.Context, email string) (*repository.User, error) {
+					return &repository.User{ID: "user-1", Username: "johndoe", Email: email, PasswordHash: tt.storedHash, IsActive: true}, nil
+				},
+				UpdateUserFunc: func(ctx context.Context, user *repository.User) error {
+					updatedHash = user.PasswordHash
+					return tt.updateErr
+				},
+			}
+			svc := NewAuthService(repo, "test-jwt-secret", WithPasswordHasher(hashers))
+
+			_, user, err := svc.Login(context.Background(), "john@example.com", "password123")
+			if err != nil {
+				t.Fatalf("Expected login to succeed, got: %v", err)
+			}
+			if !tt.expectUpdate {
+				if updatedHash != "" {
+					t.Error("Expected no hash upgrade")
+				}
+				return
+			}
+			if argon.NeedsRehash(updatedHash) {
+				t.Fatalf("Expected upgraded argon2id hash, got %q", updatedHash)
+			}
+			if ok, _ := hashers.Verify(updatedHash, "password123"); !ok {
+				t.Error("Upgraded hash does not verify")
+			}
+			if tt.updateErr != nil && user.PasswordHash != tt.storedHash {
+				t.Error("Expected stored hash to be kept when the upgrade fails")
+			}
+		})
+	}
+}
+
 func TestAuthService_GetUserByID(t *testing.T) {
 	mockRepo := &MockUserRepository{}
 	mockRepo.GetUserByIDFunc = func(ctx context.Context, id string) (*repository.User, error) {
@@ -436,4 +562,128 @@ func TestAuthService_GenerateTokenAndValidateToken(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for invalid token")
 	}
+
+	// Other tokens signed with the same secret (invitations, SSO flows) are not access tokens
+	flow, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{"oidc-flow"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}).SignedString([]byte("test-jwt-secret"))
+	if err != nil {
+		t.Fatalf("failed to sign flow token: %v", err)
+	}
+	if _, err := svc.ValidateToken(flow); err == nil {
+		t.Error("Expected error for token with an audience and no user")
+	}
+}
+
+// Mock InvitationRedeemer
+type MockInvitationRedeemer struct {
+	RedeemFunc  func(ctx context.Context, token, email, userID string) (*repository.Invitation, error)
+	ReleaseFunc func(ctx context.Context, invitationID string) error
+}
+
+func (m *MockInvitationRedeemer) Redeem(ctx context.Context, token, email, userID string) (*repository.Invitation, error) {
+	if m.RedeemFunc != nil {
+		return m.RedeemFunc(ctx, token, email, userID)
+	}
+	return nil, ErrInvitationInvalid
+}
+
+func (m *MockInvitationRedeemer) Release(ctx context.Context, invitationID string) error {
+	if m.ReleaseFunc != nil {
+		return m.ReleaseFunc(ctx, invitationID)
+	}
+	return nil
+}
+
+func TestAuthService_Register_OpenRegistrationDisabled(t *testing.T) {
+	svc := NewAuthService(&MockUserRepository{}, "test-jwt-secret", WithOpenRegistration(false))
+
+	_, err := svc.Register(context.Background(), "johndoe", "john@example.com", "password123", "John", "Doe")
+	if !errors.Is(err, ErrRegistrationClosed) {
+		t.Errorf("Expected ErrRegistrationClosed, got: %v", err)
+	}
+}
+
+func TestAuthService_RegisterWithInvite(t *testing.T) {
+	t.Run("Success - Role comes from invitation", func(t *testing.T) {
+		var created *repository.User
+		repo := &MockUserRepository{
+			CreateUserFunc: func(ctx context.Context, user *repository.User) error {
+				created = user
+				return nil
+			},
+		}
+		redeemer := &MockInvitationRedeemer{
+			RedeemFunc: func(ctx context.Context, token, email, userID string) (*repository.Invitation, error) {
+				if token != "good-token" || email != "alice@example.com" || userID == "" {
+					t.Errorf("Unexpected Redeem args: token=%q email=%q userID=%q", token, email, userID)
+				}
+				return &repository.Invitation{ID: "invite-1", Email: email, Role: "counsellor"}, nil
+			},
+		}
+		svc := NewAuthService(repo, "test-jwt-secret", WithInvitations(redeemer), WithOpenRegistration(false))
+
+		user, err := svc.RegisterWithInvite(context.Background(), "good-token", "alice", "alice@example.com", "password123", "Alice", "Counsellor")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if user.Role != "counsellor" {
+			t.Errorf("Expected role counsellor, got %q", user.Role)
+		}
+		if created == nil || created.ID != user.ID {
+			t.Error("Expected invited user to be persisted")
+		}
+	})
+
+	t.Run("Failure - Invalid token does not create user", func(t *testing.T) {
+		repo := &MockUserRepository{
+			CreateUserFunc: func(ctx context.Context, user *repository.User) error {
+				t.Error("CreateUser must not be called for an invalid invitation")
+				return nil
+			},
+		}
+		svc := NewAuthService(repo, "test-jwt-secret", WithInvitations(&MockInvitationRedeemer{}))
+
+		_, err := svc.RegisterWithInvite(context.Background(), "bad-token", "alice", "alice@example.com", "password123", "Alice", "Counsellor")
+		if !errors.Is(err, ErrInvitationInvalid) {
+			t.Errorf("Expected ErrInvitationInvalid, got: %v", err)
+		}
+	})
+
+	t.Run("Failure - Create error reopens invitation", func(t *testing.T) {
+		released := ""
+		repo := &MockUserRepository{
+			CreateUserFunc: func(ctx context.Context, user *repository.User) error {
+				return errors.New("dynamodb error")
+			},
+		}
+		redeemer := &MockInvitationRedeemer{
+			RedeemFunc: func(ctx context.Context, token, email, userID string) (*repository.Invitation, error) {
+				return &repository.Invitation{ID: "invite-1", Role: "staff"}, nil
+			},
+			ReleaseFunc: func(ctx context.Context, invitationID string) error {
+				released = invitationID
+				return nil
+			},
+		}
+		svc := NewAuthService(repo, "test-jwt-secret", WithInvitations(redeemer))
+
+		_, err := svc.RegisterWithInvite(context.Background(), "good-token", "alice", "alice@example.com", "password123", "Alice", "Staff")
+		if err == nil || !strings.Contains(err.Error(), "dynamodb error") {
+			t.Errorf("Expected dynamodb error, got: %v", err)
+		}
+		if released != "invite-1" {
+			t.Errorf("Expected invitation invite-1 to be released, got %q", released)
+		}
+	})
+
+	t.Run("Failure - Invitations not configured", func(t *testing.T) {
+		svc := NewAuthService(&MockUserRepository{}, "test-jwt-secret")
+
+		_, err := svc.RegisterWithInvite(context.Background(), "token", "alice", "alice@example.com", "password123", "Alice", "Staff")
+		if !errors.Is(err, ErrInvitationsDisabled) {
+			t.Errorf("Expected ErrInvitationsDisabled, got: %v", err)
+		}
+	})
 }
@@ -4,26 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmason/john_ai_project/internal/apperror"
 	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+	"github.com/jmason/john_ai_project/internal/validate"
 )
 
-// Validation errors for CreateClient
+// Validation errors for client requests
 var (
-	ErrMissingRequiredFields = errors.New("first_name, last_name, and email are required")
-	ErrInvalidEmail          = errors.New("invalid email format")
-	ErrMissingEmail          = errors.New("email is required")
-	ErrMissingClientID       = errors.New("client id is required")
-	ErrNoFieldsToUpdate      = errors.New("provide at least one field to update")
-	ErrEmailAlreadyExists    = errors.New("a client with this email already exists")
+	// ErrClientInvalid carries a message for every invalid field in its Fields.
+	ErrClientInvalid      = apperror.Validation("invalid_client_fields", "invalid client fields")
+	ErrInvalidEmail       = apperror.Validation("invalid_email", "invalid email format")
+	ErrMissingEmail       = apperror.Validation("missing_email", "email is required")
+	ErrMissingClientID    = apperror.Validation("missing_client_id", "client id is required")
+	ErrNoFieldsToUpdate   = apperror.Validation("no_fields_to_update", "provide at least one field to update")
+	ErrEmailAlreadyExists = apperror.Conflict("client_email_exists", "a client with this email already exists")
 )
 
-var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-
 // ClientRepository interface for dependency injection
 type ClientRepository interface {
 	GetClientList(ctx context.Context) ([]repository.Client, error)
@@ -49,15 +50,20 @@ type ClientUpdateInput struct {
 
 type ClientService struct {
 	repo ClientRepository
+	now  func() time.Time
 }
 
 func NewClientService(repo ClientRepository) *ClientService {
 	return &ClientService{
 		repo: repo,
+		now:  time.Now,
 	}
 }
 
 func (s *ClientService) GetClientList(ctx context.Context) ([]repository.Client, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.GetClientList")
+	defer span.End()
+
 	clients, err := s.repo.GetClientList(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client list: %w", err)
@@ -66,6 +72,9 @@ func (s *ClientService) GetClientList(ctx context.Context) ([]repository.Client,
 }
 
 func (s *ClientService) GetClientByID(ctx context.Context, id string) (*repository.Client, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.GetClientByID")
+	defer span.End()
+
 	client, err := s.repo.GetClientByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client by ID: %w", err)
@@ -74,17 +83,20 @@ func (s *ClientService) GetClientByID(ctx context.Context, id string) (*reposito
 }
 
 func (s *ClientService) GetClientByEmail(ctx context.Context, email string) (*repository.Client, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.GetClientByEmail")
+	defer span.End()
+
 	email = strings.TrimSpace(email)
 	if email == "" {
 		return nil, ErrMissingEmail
 	}
-	if !emailRegex.MatchString(email) {
+	if !validate.IsEmail(email) {
 		return nil, ErrInvalidEmail
 	}
 	// Prefer lowercase (matches normalized Create/Update); retry exact casing for legacy rows.
 	lower := strings.ToLower(email)
 	client, err := s.repo.GetClientByEmail(ctx, lower)
-	if err != nil && strings.Contains(err.Error(), "not found") && lower != email {
+	if errors.Is(err, repository.ErrClientNotFound) && lower != email {
 		client, err = s.repo.GetClientByEmail(ctx, email)
 	}
 	if err != nil {
@@ -94,6 +106,9 @@ func (s *ClientService) GetClientByEmail(ctx context.Context, email string) (*re
 }
 
 func (s *ClientService) GetActiveClients(ctx context.Context) ([]repository.Client, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.GetActiveClients")
+	defer span.End()
+
 	clients, err := s.repo.GetClientsByStatus(ctx, "active")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active clients: %w", err)
@@ -102,6 +117,9 @@ func (s *ClientService) GetActiveClients(ctx context.Context) ([]repository.Clie
 }
 
 func (s *ClientService) GetInactiveClients(ctx context.Context) ([]repository.Client, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.GetInactiveClients")
+	defer span.End()
+
 	clients, err := s.repo.GetClientsByStatus(ctx, "inactive")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inactive clients: %w", err)
@@ -109,24 +127,56 @@ func (s *ClientService) GetInactiveClients(ctx context.Context) ([]repository.Cl
 	return clients, nil
 }
 
-func (s *ClientService) CreateClient(ctx context.Context, client *repository.Client) error {
-	client.Email = strings.TrimSpace(strings.ToLower(client.Email))
+// ClientStats are the client counts reported as business metrics.
+type ClientStats struct {
+	// ByStatus counts clients per status; every ClientStatuses value is present.
+	ByStatus map[string]int
+	// AwaitingIntake counts active clients with no appointment booked, per urgency. Every
+	// ClientUrgencies value is present; clients without an urgency are counted under "".
+	AwaitingIntake map[string]int
+}
 
-	// Validate required fields
-	if client.FirstName == "" || client.LastName == "" || client.Email == "" {
-		return ErrMissingRequiredFields
+// Stats counts clients by status and the intake queue: active clients still waiting for an
+// appointment. It scans the clients table, so callers should cache the result.
+func (s *ClientService) Stats(ctx context.Context) (ClientStats, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.Stats")
+	defer span.End()
+
+	clients, err := s.repo.GetClientList(ctx)
+	if err != nil {
+		return ClientStats{}, fmt.Errorf("failed to count clients: %w", err)
+	}
+
+	stats := ClientStats{ByStatus: map[string]int{}, AwaitingIntake: map[string]int{"": 0}}
+	for _, status := range ClientStatuses {
+		stats.ByStatus[status] = 0
+	}
+	for _, urgency := range ClientUrgencies {
+		stats.AwaitingIntake[urgency] = 0
+	}
+	for _, c := range clients {
+		stats.ByStatus[c.Status]++
+		if c.Status == "active" && strings.TrimSpace(c.NextAppointment) == "" {
+			stats.AwaitingIntake[c.Urgency]++
+		}
 	}
+	return stats, nil
+}
+
+func (s *ClientService) CreateClient(ctx context.Context, client *repository.Client) error {
+	ctx, span := tracing.Start(ctx, "ClientService.CreateClient")
+	defer span.End()
 
-	// Validate email format
-	if !emailRegex.MatchString(client.Email) {
-		return ErrInvalidEmail
+	normalizeClient(client)
+	if err := validateNewClient(client, s.now()); err != nil {
+		return err
 	}
 
 	_, err := s.repo.GetClientByEmail(ctx, client.Email)
 	if err == nil {
 		return ErrEmailAlreadyExists
 	}
-	if !strings.Contains(err.Error(), "not found") {
+	if !errors.Is(err, repository.ErrClientNotFound) {
 		return fmt.Errorf("failed to check existing client by email: %w", err)
 	}
 
@@ -141,19 +191,26 @@ func (s *ClientService) CreateClient(ctx context.Context, client *repository.Cli
 	}
 
 	// Set timestamps
-	now := time.Now().Format(time.RFC3339)
+	now := s.now().Format(time.RFC3339)
 	if client.CreatedAt == "" {
 		client.CreatedAt = now
 	}
 	client.UpdatedAt = now
 
 	if err := s.repo.CreateClient(ctx, client); err != nil {
+		// The lookup above misses a concurrent create; the repository's email guard catches it.
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return ErrEmailAlreadyExists
+		}
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 	return nil
 }
 
 func (s *ClientService) UpdateClient(ctx context.Context, clientID string, in ClientUpdateInput) error {
+	ctx, span := tracing.Start(ctx, "ClientService.UpdateClient")
+	defer span.End()
+
 	if clientID == "" {
 		return ErrMissingClientID
 	}
@@ -164,34 +221,36 @@ func (s *ClientService) UpdateClient(ctx context.Context, clientID string, in Cl
 		in.RequestedCounsellor == nil && in.Urgency == nil && in.NextAppointment == nil {
 		return ErrNoFieldsToUpdate
 	}
+	normalizeClientUpdate(&in)
+	if err := validateClientUpdate(&in, s.now()); err != nil {
+		return err
+	}
 
 	existing, err := s.repo.GetClientByID(ctx, clientID)
 	if err != nil {
 		return fmt.Errorf("failed to load client: %w", err)
 	}
 
-	patch := repository.ClientPatch{}
-
-	if in.FirstName != nil {
-		v := strings.TrimSpace(*in.FirstName)
-		if v == "" {
-			return ErrMissingRequiredFields
-		}
-		patch.FirstName = &v
-	}
-	if in.LastName != nil {
-		v := strings.TrimSpace(*in.LastName)
-		if v == "" {
-			return ErrMissingRequiredFields
-		}
-		patch.LastName = &v
+	patch := repository.ClientPatch{
+		FirstName:           in.FirstName,
+		LastName:            in.LastName,
+		Email:               in.Email,
+		RequestedCounsellor: in.RequestedCounsellor,
+		Urgency:             in.Urgency,
+		NextAppointment:     in.NextAppointment,
 	}
 	if in.Email != nil {
-		em := strings.TrimSpace(strings.ToLower(*in.Email))
-		if !emailRegex.MatchString(em) {
-			return ErrInvalidEmail
+		em := *in.Email
+		// Clients created before email guards existed are only found through the index.
+		if em != strings.ToLower(existing.Email) {
+			other, err := s.repo.GetClientByEmail(ctx, em)
+			if err == nil && other.ID != clientID {
+				return ErrEmailAlreadyExists
+			}
+			if err != nil && !errors.Is(err, repository.ErrClientNotFound) {
+				return fmt.Errorf("failed to check existing client by email: %w", err)
+			}
 		}
-		patch.Email = &em
 	}
 	// Non-empty notes_list replaces the list. Empty slice is ignored so partial updates (e.g. only
 	// counsellor/urgency) do not clear notes when the UI sends notes_list: [].
@@ -203,7 +262,7 @@ func (s *ClientService) UpdateClient(ctx context.Context, clientID string, in Cl
 			note.ClientID = clientID
 		}
 		if note.Date == "" {
-			note.Date = time.Now().Format(time.RFC3339)
+			note.Date = s.now().Format(time.RFC3339)
 		}
 		notes := make([]repository.Note, len(existing.Notes))
 		copy(notes, existing.Notes)
@@ -214,20 +273,11 @@ func (s *ClientService) UpdateClient(ctx context.Context, clientID string, in Cl
 		}
 		patch.Notes = &notes
 	}
-	if in.RequestedCounsellor != nil {
-		v := strings.TrimSpace(*in.RequestedCounsellor)
-		patch.RequestedCounsellor = &v
-	}
-	if in.Urgency != nil {
-		v := strings.TrimSpace(*in.Urgency)
-		patch.Urgency = &v
-	}
-	if in.NextAppointment != nil {
-		v := strings.TrimSpace(*in.NextAppointment)
-		patch.NextAppointment = &v
-	}
 
 	if err := s.repo.UpdateClient(ctx, clientID, patch); err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return ErrEmailAlreadyExists
+		}
 		return fmt.Errorf("failed to update client: %w", err)
 	}
 	return nil
@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/jmason/john_ai_project/internal/apperror"
 	"github.com/jmason/john_ai_project/internal/repository"
 )
 
@@ -45,7 +48,7 @@ func (m *MockClientRepository) GetClientByEmail(ctx context.Context, email strin
 	if m.GetClientByEmailFunc != nil {
 		return m.GetClientByEmailFunc(ctx, email)
 	}
-	return nil, errors.New("client not found for email: " + email)
+	return nil, fmt.Errorf("%w for email: %s", repository.ErrClientNotFound, email)
 }
 
 func (m *MockClientRepository) GetClientsByStatus(ctx context.Context, status string) ([]repository.Client, error) {
@@ -111,7 +114,7 @@ func TestClientService_CreateClient(t *testing.T) {
 				DateOfBirth:           "1990-01-01",
 				Address:               "123 Street",
 				EmergencyContactName:  "Emergency Person",
-				EmergencyContactPhone: "+0987654321",
+				EmergencyContactPhone: "+14155550102",
 				Status:                "active",
 				CreatedAt:             "2024-01-01T00:00:00Z",
 				UpdatedAt:             "2024-01-01T00:00:00Z",
@@ -175,6 +178,20 @@ func TestClientService_CreateClient(t *testing.T) {
 			},
 			expectedError: "a client with this email already exists",
 		},
+		{
+			name: "Failure - Concurrent create claims email",
+			client: &repository.Client{
+				FirstName: "Dup",
+				LastName:  "User",
+				Email:     "dup@example.com",
+			},
+			mockSetup: func(m *MockClientRepository) {
+				m.CreateClientFunc = func(ctx context.Context, client *repository.Client) error {
+					return repository.ErrEmailTaken
+				}
+			},
+			expectedError: "a client with this email already exists",
+		},
 		{
 			name: "Failure - Missing first_name",
 			client: &repository.Client{
@@ -182,7 +199,7 @@ func TestClientService_CreateClient(t *testing.T) {
 				Email:    "test@example.com",
 			},
 			mockSetup:     func(m *MockClientRepository) {},
-			expectedError: "first_name, last_name, and email are required",
+			expectedError: "invalid client fields: first_name",
 		},
 		{
 			name: "Failure - Missing last_name",
@@ -191,7 +208,7 @@ func TestClientService_CreateClient(t *testing.T) {
 				Email:     "test@example.com",
 			},
 			mockSetup:     func(m *MockClientRepository) {},
-			expectedError: "first_name, last_name, and email are required",
+			expectedError: "invalid client fields: last_name",
 		},
 		{
 			name: "Failure - Missing email",
@@ -200,7 +217,7 @@ func TestClientService_CreateClient(t *testing.T) {
 				LastName:  "Doe",
 			},
 			mockSetup:     func(m *MockClientRepository) {},
-			expectedError: "first_name, last_name, and email are required",
+			expectedError: "invalid client fields: email",
 		},
 		{
 			name: "Failure - Invalid email format",
@@ -210,7 +227,7 @@ func TestClientService_CreateClient(t *testing.T) {
 				Email:     "not-an-email",
 			},
 			mockSetup:     func(m *MockClientRepository) {},
-			expectedError: "invalid email format",
+			expectedError: "invalid client fields: email",
 		},
 		{
 			name: "Success - Default status when empty",
@@ -452,8 +469,8 @@ func TestClientService_UpdateClient(t *testing.T) {
 		svc := NewClientService(&MockClientRepository{})
 		empty := "  "
 		err := svc.UpdateClient(context.Background(), "client-1", ClientUpdateInput{FirstName: &empty})
-		if err != ErrMissingRequiredFields {
-			t.Fatalf("err = %v, want ErrMissingRequiredFields", err)
+		if !errors.Is(err, ErrClientInvalid) {
+			t.Fatalf("err = %v, want ErrClientInvalid", err)
 		}
 	})
 
@@ -465,15 +482,16 @@ func TestClientService_UpdateClient(t *testing.T) {
 		})
 		bad := "bad"
 		err := svc.UpdateClient(context.Background(), "client-1", ClientUpdateInput{Email: &bad})
-		if err != ErrInvalidEmail {
-			t.Fatalf("err = %v, want ErrInvalidEmail", err)
+		appErr, ok := apperror.As(err)
+		if !ok || !errors.Is(err, ErrClientInvalid) || appErr.Fields["email"] == "" {
+			t.Fatalf("err = %v, want ErrClientInvalid with an email field", err)
 		}
 	})
 
 	t.Run("success requested counsellor urgency next appointment", func(t *testing.T) {
 		rc := "Sarah Johnson, MA, LPC"
-		urg := "soon"
-		next := "2025-04-01T10:00:00Z"
+		urg := "high"
+		next := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
 		mockRepo := &MockClientRepository{
 			GetClientByIDFunc: func(ctx context.Context, id string) (*repository.Client, error) {
 				return base, nil
@@ -517,4 +535,193 @@ func TestClientService_UpdateClient(t *testing.T) {
 			t.Fatalf("err = %v, want wrapped failure", err)
 		}
 	})
+
+	t.Run("email used by another client", func(t *testing.T) {
+		other := "other@example.com"
+		mockRepo := &MockClientRepository{
+			GetClientByIDFunc: func(ctx context.Context, id string) (*repository.Client, error) {
+				return base, nil
+			},
+			GetClientByEmailFunc: func(ctx context.Context, email string) (*repository.Client, error) {
+				return &repository.Client{ID: "client-2", Email: email}, nil
+			},
+			UpdateClientFunc: func(ctx context.Context, id string, patch repository.ClientPatch) error {
+				t.Fatal("UpdateClient should not be called")
+				return nil
+			},
+		}
+		svc := NewClientService(mockRepo)
+		err := svc.UpdateClient(context.Background(), "client-1", ClientUpdateInput{Email: &other})
+		if !errors.Is(err, ErrEmailAlreadyExists) {
+			t.Fatalf("err = %v, want ErrEmailAlreadyExists", err)
+		}
+	})
+
+	t.Run("email guard held by concurrent update", func(t *testing.T) {
+		other := "other@example.com"
+		mockRepo := &MockClientRepository{
+			GetClientByIDFunc: func(ctx context.Context, id string) (*repository.Client, error) {
+				return base, nil
+			},
+			UpdateClientFunc: func(ctx context.Context, id string, patch repository.ClientPatch) error {
+				return repository.ErrEmailTaken
+			},
+		}
+		svc := NewClientService(mockRepo)
+		err := svc.UpdateClient(context.Background(), "client-1", ClientUpdateInput{Email: &other})
+		if !errors.Is(err, ErrEmailAlreadyExists) {
+			t.Fatalf("err = %v, want ErrEmailAlreadyExists", err)
+		}
+	})
+}
+
+func TestClientService_ValidationReportsEveryField(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		client         *repository.Client
+		update         *ClientUpdateInput
+		expectedFields map[string]string
+	}{
+		{
+			name: "Create - every field invalid",
+			client: &repository.Client{
+				FirstName:             strings.Repeat("a", MaxClientNameLength+1),
+				Email:                 "not-an-email",
+				Phone:                 "555-0101",
+				DateOfBirth:           "1990-02-30",
+				EmergencyContactPhone: "+0123",
+				Status:                "archived",
+				Urgency:               "soon",
+				NextAppointment:       "2026-02-01T10:00:00Z",
+				Notes:                 []repository.Note{{Note: "ok"}, {Note: strings.Repeat("n", MaxClientNoteLength+1)}},
+			},
+			expectedFields: map[string]string{
+				"first_name":              "must be at most 100 characters",
+				"last_name":               "is required",
+				"email":                   "must be a valid email address",
+				"phone":                   "must be an E.164 phone number, e.g. +14155550123",
+				"date_of_birth":           "must be a date in YYYY-MM-DD format",
+				"emergency_contact_phone": "must be an E.164 phone number, e.g. +14155550123",
+				"status":                  "must be one of: active, inactive",
+				"urgency":                 "must be one of: low, medium, high, critical",
+				"next_appointment":        "must be in the future",
+				"notes[1].note":           "must be at most 10000 characters",
+			},
+		},
+		{
+			name: "Create - future birth date and malformed appointment",
+			client: &repository.Client{
+				FirstName:       "John",
+				LastName:        "Doe",
+				Email:           "john@example.com",
+				DateOfBirth:     "2026-03-01",
+				NextAppointment: "next tuesday",
+			},
+			expectedFields: map[string]string{
+				"date_of_birth":    "must be in the past",
+				"next_appointment": "must be an RFC 3339 timestamp, e.g. 2026-01-02T15:04:05Z",
+			},
+		},
+		{
+			name: "Create - punctuated phone and mixed case enums are accepted",
+			client: &repository.Client{
+				FirstName:       "John",
+				LastName:        "Doe",
+				Email:           "john@example.com",
+				Phone:           "+1 (415) 555-0123",
+				DateOfBirth:     "1990-01-01",
+				Urgency:         " High ",
+				NextAppointment: "2026-03-02T09:00:00-05:00",
+			},
+		},
+		{
+			name: "Update - only present fields are checked",
+			update: &ClientUpdateInput{
+				LastName:        stringPtr(""),
+				Urgency:         stringPtr("whenever"),
+				NextAppointment: stringPtr("2025-01-01T00:00:00Z"),
+			},
+			expectedFields: map[string]string{
+				"last_name":        "is required",
+				"urgency":          "must be one of: low, medium, high, critical",
+				"next_appointment": "must be in the future",
+			},
+		},
+		{
+			name:   "Update - clearing urgency is allowed",
+			update: &ClientUpdateInput{Urgency: stringPtr("")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewClientService(&MockClientRepository{
+				GetClientByIDFunc: func(ctx context.Context, id string) (*repository.Client, error) {
+					return &repository.Client{ID: id, Email: "john@example.com"}, nil
+				},
+			})
+			svc.now = func() time.Time { return now }
+
+			var err error
+			if tt.client != nil {
+				err = svc.CreateClient(context.Background(), tt.client)
+			} else {
+				err = svc.UpdateClient(context.Background(), "client-1", *tt.update)
+			}
+
+			if tt.expectedFields == nil {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			appErr, ok := apperror.As(err)
+			if !ok || !errors.Is(err, ErrClientInvalid) {
+				t.Fatalf("Expected ErrClientInvalid, got: %v", err)
+			}
+			if diff := cmp.Diff(tt.expectedFields, appErr.Fields); diff != "" {
+				t.Errorf("Fields mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestClientService_Stats(t *testing.T) {
+	repo := &MockClientRepository{
+		GetClientListFunc: func(ctx context.Context) ([]repository.Client, error) {
+			return []repository.Client{
+				{ID: "1", Status: "active", Urgency: "high"},
+				{ID: "2", Status: "active", Urgency: "high", NextAppointment: "2026-03-02T09:00:00Z"},
+				{ID: "3", Status: "active"},
+				{ID: "4", Status: "inactive", Urgency: "critical"},
+			}, nil
+		},
+	}
+	svc := NewClientService(repo)
+
+	stats, err := svc.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+
+	want := ClientStats{
+		ByStatus:       map[string]int{"active": 3, "inactive": 1},
+		AwaitingIntake: map[string]int{"": 1, "low": 0, "medium": 0, "high": 1, "critical": 0},
+	}
+	if diff := cmp.Diff(want, stats); diff != "" {
+		t.Errorf("Stats mismatch (-want +got):\n%s", diff)
+	}
+
+	repo.GetClientListFunc = func(ctx context.Context) ([]repository.Client, error) {
+		return nil, errors.New("scan failed")
+	}
+	if _, err := svc.Stats(context.Background()); err == nil {
+		t.Error("Expected the repository error")
+	}
 }
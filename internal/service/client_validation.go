@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/validate"
+)
+
+// Length limits for client fields, in characters.
+const (
+	MaxClientNameLength    = 100
+	MaxClientEmailLength   = 254
+	MaxClientAddressLength = 500
+	MaxContactNameLength   = 200
+	MaxCounsellorLength    = 200
+	MaxClientNoteLength    = 10000
+)
+
+// ClientUrgencies are the accepted urgency values, least to most urgent.
+var ClientUrgencies = []string{"low", "medium", "high", "critical"}
+
+// ClientStatuses are the accepted status values.
+var ClientStatuses = []string{"active", "inactive"}
+
+// normalizeClient trims fields and puts email, phones and enums in their stored form, so the
+// values that are validated are the values that are saved.
+func normalizeClient(c *repository.Client) {
+	c.FirstName = strings.TrimSpace(c.FirstName)
+	c.LastName = strings.TrimSpace(c.LastName)
+	c.Email = strings.ToLower(strings.TrimSpace(c.Email))
+	c.Phone = validate.NormalizePhone(c.Phone)
+	c.DateOfBirth = strings.TrimSpace(c.DateOfBirth)
+	c.Address = strings.TrimSpace(c.Address)
+	c.EmergencyContactName = strings.TrimSpace(c.EmergencyContactName)
+	c.EmergencyContactPhone = validate.NormalizePhone(c.EmergencyContactPhone)
+	c.Status = strings.ToLower(strings.TrimSpace(c.Status))
+	c.RequestedCounsellor = strings.TrimSpace(c.RequestedCounsellor)
+	c.Urgency = strings.ToLower(strings.TrimSpace(c.Urgency))
+	c.NextAppointment = strings.TrimSpace(c.NextAppointment)
+}
+
+// normalizeClientUpdate does the same for the fields present in a partial update.
+func normalizeClientUpdate(in *ClientUpdateInput) {
+	trim := func(p **string, lower bool) {
+		if *p == nil {
+			return
+		}
+		v := strings.TrimSpace(**p)
+		if lower {
+			v = strings.ToLower(v)
+		}
+		*p = &v
+	}
+	trim(&in.FirstName, false)
+	trim(&in.LastName, false)
+	trim(&in.Email, true)
+	trim(&in.RequestedCounsellor, false)
+	trim(&in.Urgency, true)
+	trim(&in.NextAppointment, false)
+}
+
+// validateNewClient checks every field of a client being created and reports all failures.
+func validateNewClient(c *repository.Client, now time.Time) error {
+	v := validate.New(now)
+	v.Required("first_name", c.FirstName)
+	v.MaxLength("first_name", c.FirstName, MaxClientNameLength)
+	v.Required("last_name", c.LastName)
+	v.MaxLength("last_name", c.LastName, MaxClientNameLength)
+	v.Required("email", c.Email)
+	v.MaxLength("email", c.Email, MaxClientEmailLength)
+	v.Email("email", c.Email)
+	v.Phone("phone", c.Phone)
+	v.PastDate("date_of_birth", c.DateOfBirth)
+	v.MaxLength("address", c.Address, MaxClientAddressLength)
+	v.MaxLength("emergency_contact_name", c.EmergencyContactName, MaxContactNameLength)
+	v.Phone("emergency_contact_phone", c.EmergencyContactPhone)
+	v.OneOf("status", c.Status, ClientStatuses)
+	v.MaxLength("requested_counsellor", c.RequestedCounsellor, MaxCounsellorLength)
+	v.OneOf("urgency", c.Urgency, ClientUrgencies)
+	v.FutureTime("next_appointment", c.NextAppointment)
+	validateNotes(v, "notes", c.Notes)
+	return v.Err(ErrClientInvalid)
+}
+
+// validateClientUpdate checks the fields present in a partial update. Names and email cannot be
+// cleared; urgency and next_appointment can be cleared with an empty string.
+func validateClientUpdate(in *ClientUpdateInput, now time.Time) error {
+	v := validate.New(now)
+	if in.FirstName != nil {
+		v.Required("first_name", *in.FirstName)
+		v.MaxLength("first_name", *in.FirstName, MaxClientNameLength)
+	}
+	if in.LastName != nil {
+		v.Required("last_name", *in.LastName)
+		v.MaxLength("last_name", *in.LastName, MaxClientNameLength)
+	}
+	if in.Email != nil {
+		v.Required("email", *in.Email)
+		v.MaxLength("email", *in.Email, MaxClientEmailLength)
+		v.Email("email", *in.Email)
+	}
+	if in.RequestedCounsellor != nil {
+		v.MaxLength("requested_counsellor", *in.RequestedCounsellor, MaxCounsellorLength)
+	}
+	if in.Urgency != nil {
+		v.OneOf("urgency", *in.Urgency, ClientUrgencies)
+	}
+	if in.NextAppointment != nil {
+		v.FutureTime("next_appointment", *in.NextAppointment)
+	}
+	if in.InitialNote != nil {
+		v.MaxLength("initial_note.note", in.InitialNote.Note, MaxClientNoteLength)
+	}
+	if in.NotesList != nil {
+		validateNotes(v, "notes_list", *in.NotesList)
+	}
+	return v.Err(ErrClientInvalid)
+}
+
+func validateNotes(v *validate.Validator, field string, notes []repository.Note) {
+	for i, n := range notes {
+		v.MaxLength(fmt.Sprintf("%s[%d].note", field, i), n.Note, MaxClientNoteLength)
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+)
+
+// Idempotency errors
+var (
+	ErrIdempotencyKeyInvalid = apperror.Validation("invalid_idempotency_key",
+		fmt.Sprintf("Idempotency-Key must be 1-%d printable ASCII characters", MaxIdempotencyKeyLength))
+	ErrIdempotencyKeyReused  = apperror.Unprocessable("idempotency_key_reused", "Idempotency-Key was already used for a different request")
+	ErrIdempotencyInProgress = apperror.Conflict("idempotency_request_in_progress", "a request with this Idempotency-Key is still being processed")
+)
+
+const (
+	// DefaultIdempotencyTTL is how long a stored response is replayed for.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// MaxIdempotencyKeyLength is the longest accepted Idempotency-Key.
+	MaxIdempotencyKeyLength = 255
+)
+
+// IdempotencyRepository interface for dependency injection
+type IdempotencyRepository interface {
+	ReserveIdempotencyKey(ctx context.Context, record *repository.IdempotencyRecord, now int64) error
+	GetIdempotencyRecord(ctx context.Context, id string) (*repository.IdempotencyRecord, error)
+	CompleteIdempotencyRecord(ctx context.Context, id string, status int, header map[string][]string, body []byte) error
+	DeleteIdempotencyRecord(ctx context.Context, id string) error
+}
+
+// IdempotentRequest identifies a write sent with an Idempotency-Key. Method, Path and Body are
+// fingerprinted so a key cannot be reused for a different request.
+type IdempotentRequest struct {
+	CallerID string
+	Key      string
+	Method   string
+	Path     string
+	Body     []byte
+}
+
+// IdempotencyService stores the first response to each keyed request so retries get the same
+// response instead of repeating the write.
+type IdempotencyService struct {
+	repo IdempotencyRepository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func NewIdempotencyService(repo IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		repo: repo,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// Begin claims the key for req. A nil record means the caller owns the key and must handle the
+// request, then call Complete or Release. A non-nil record is a stored response to replay.
+func (s *IdempotencyService) Begin(ctx context.Context, req IdempotentRequest) (*repository.IdempotencyRecord, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	if !validIdempotencyKey(req.Key) {
+		return nil, ErrIdempotencyKeyInvalid
+	}
+
+	now := s.now()
+	id := idempotencyID(req.CallerID, req.Key)
+	hash := requestFingerprint(req)
+	err := s.repo.ReserveIdempotencyKey(ctx, &repository.IdempotencyRecord{
+		ID:          id,
+		CallerID:    req.CallerID,
+		RequestHash: hash,
+		Method:      req.Method,
+		Path:        req.Path,
+		CreatedAt:   now.Format(time.RFC3339),
+		TTL:         now.Add(s.ttl).Unix(),
+	}, now.Unix())
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	existing, err := s.repo.GetIdempotencyRecord(ctx, id)
+	if errors.Is(err, repository.ErrIdempotencyRecordNotFound) {
+		// Released by a failed first attempt between our reserve and read; the client retries.
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load idempotency record: %w", err)
+	}
+	if existing.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.Status == 0 {
+		return nil, ErrIdempotencyInProgress
+	}
+	return existing, nil
+}
+
+// Complete stores the response to replay for the key: its status, the headers the handler set
+// and the body.
+func (s *IdempotencyService) Complete(ctx context.Context, callerID, key string, status int, header map[string][]string, body []byte) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	if err := s.repo.CompleteIdempotencyRecord(ctx, idempotencyID(callerID, key), status, header, body); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release forgets the key so a retry is handled afresh. It is used when the first attempt
+// failed on the server side.
+func (s *IdempotencyService) Release(ctx context.Context, callerID, key string) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Release")
+	defer span.End()
+
+	if err := s.repo.DeleteIdempotencyRecord(ctx, idempotencyID(callerID, key)); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// idempotencyID scopes a key to its caller.
+func idempotencyID(callerID, key string) string {
+	return callerID + "#" + key
+}
+
+func requestFingerprint(req IdempotentRequest) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.Path + "\n"))
+	h.Write(req.Body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+// Mock IdempotencyRepository backed by a map, honouring the reserve condition and TTL.
+type MockIdempotencyRepository struct {
+	records map[string]*repository.IdempotencyRecord
+}
+
+func NewMockIdempotencyRepository() *MockIdempotencyRepository {
+	return &MockIdempotencyRepository{records: map[string]*repository.IdempotencyRecord{}}
+}
+
+func (m *MockIdempotencyRepository) ReserveIdempotencyKey(ctx context.Context, record *repository.IdempotencyRecord, now int64) error {
+	if existing, ok := m.records[record.ID]; ok && existing.TTL >= now {
+		return repository.ErrIdempotencyKeyExists
+	}
+	cp := *record
+	m.records[record.ID] = &cp
+	return nil
+}
+
+func (m *MockIdempotencyRepository) GetIdempotencyRecord(ctx context.Context, id string) (*repository.IdempotencyRecord, error) {
+	record, ok := m.records[id]
+	if !ok {
+		return nil, repository.ErrIdempotencyRecordNotFound
+	}
+	cp := *record
+	return &cp, nil
+}
+
+func (m *MockIdempotencyRepository) CompleteIdempotencyRecord(ctx context.Context, id string, status int, header map[string][]string, body []byte) error {
+	record, ok := m.records[id]
+	if !ok {
+		return repository.ErrIdempotencyRecordNotFound
+	}
+	record.Status = status
+	record.Header = header
+	record.Body = append([]byte(nil), body...)
+	return nil
+}
+
+func (m *MockIdempotencyRepository) DeleteIdempotencyRecord(ctx context.Context, id string) error {
+	delete(m.records, id)
+	return nil
+}
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	ctx := context.Background()
+	first := IdempotentRequest{CallerID: "user:u-1", Key: "key-1", Method: "POST", Path: "/api/clients/add", Body: []byte(`{"first_name":"Ada"}`)}
+
+	t.Run("First request proceeds and a completed key replays", func(t *testing.T) {
+		svc := NewIdempotencyService(NewMockIdempotencyRepository(), time.Hour)
+
+		record, err := svc.Begin(ctx, first)
+		if err != nil || record != nil {
+			t.Fatalf("Expected the first request to proceed, got %+v, %v", record, err)
+		}
+		if err := svc.Complete(ctx, first.CallerID, first.Key, 201, map[string][]string{"Content-Type": {"application/json"}}, []byte(`{"id":"c-1"}`)); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+
+		record, err = svc.Begin(ctx, first)
+		if err != nil {
+			t.Fatalf("Expected a replay, got %v", err)
+		}
+		if record == nil || record.Status != 201 || string(record.Body) != `{"id":"c-1"}` || strings.Join(record.Header["Content-Type"], ",") != "application/json" {
+			t.Errorf("Expected the stored response, got %+v", record)
+		}
+	})
+
+	t.Run("Different payload with the same key is refused", func(t *testing.T) {
+		svc := NewIdempotencyService(NewMockIdempotencyRepository(), time.Hour)
+		svc.Begin(ctx, first)
+		svc.Complete(ctx, first.CallerID, first.Key, 201, map[string][]string{"Content-Type": {"application/json"}}, nil)
+
+		other := first
+		other.Body = []byte(`{"first_name":"Grace"}`)
+		if _, err := svc.Begin(ctx, other); !errors.Is(err, ErrIdempotencyKeyReused) {
+			t.Errorf("Expected ErrIdempotencyKeyReused, got %v", err)
+		}
+
+		other = first
+		other.Path = "/api/invitations"
+		if _, err := svc.Begin(ctx, other); !errors.Is(err, ErrIdempotencyKeyReused) {
+			t.Errorf("Expected ErrIdempotencyKeyReused for a different path, got %v", err)
+		}
+	})
+
+	t.Run("Keys are scoped to the caller", func(t *testing.T) {
+		svc := NewIdempotencyService(NewMockIdempotencyRepository(), time.Hour)
+		svc.Begin(ctx, first)
+
+		other := first
+		other.CallerID = "user:u-2"
+		other.Body = []byte(`{}`)
+		if record, err := svc.Begin(ctx, other); err != nil || record != nil {
+			t.Errorf("Expected another caller's key to be independent, got %+v, %v", record, err)
+		}
+	})
+
+	t.Run("A key still being handled is in progress", func(t *testing.T) {
+		svc := NewIdempotencyService(NewMockIdempotencyRepository(), time.Hour)
+		svc.Begin(ctx, first)
+
+		if _, err := svc.Begin(ctx, first); !errors.Is(err, ErrIdempotencyInProgress) {
+			t.Errorf("Expected ErrIdempotencyInProgress, got %v", err)
+		}
+	})
+
+	t.Run("A released key can be used again", func(t *testing.T) {
+		svc := NewIdempotencyService(NewMockIdempotencyRepository(), time.Hour)
+		svc.Begin(ctx, first)
+		if err := svc.Release(ctx, first.CallerID, first.Key); err != nil {
+			t.Fatalf("Release failed: %v", err)
+		}
+
+		if record, err := svc.Begin(ctx, first); err != nil || record != nil {
+			t.Errorf("Expected the released key to proceed, got %+v, %v", record, err)
+		}
+	})
+
+	t.Run("An expired key can be used again", func(t *testing.T) {
+		svc := NewIdempotencyService(NewMockIdempotencyRepository(), time.Hour)
+		now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+		svc.now = func() time.Time { return now }
+		svc.Begin(ctx, first)
+		svc.Complete(ctx, first.CallerID, first.Key, 201, map[string][]string{"Content-Type": {"application/json"}}, nil)
+
+		now = now.Add(2 * time.Hour)
+		other := first
+		other.Body = []byte(`{"first_name":"Grace"}`)
+		if record, err := svc.Begin(ctx, other); err != nil || record != nil {
+			t.Errorf("Expected the expired key to proceed, got %+v, %v", record, err)
+		}
+	})
+
+	t.Run("Malformed keys are rejected", func(t *testing.T) {
+		svc := NewIdempotencyService(NewMockIdempotencyRepository(), time.Hour)
+		for _, key := range []string{strings.Repeat("k", MaxIdempotencyKeyLength+1), "tab\tkey", "clé"} {
+			req := first
+			req.Key = key
+			if _, err := svc.Begin(ctx, req); !errors.Is(err, ErrIdempotencyKeyInvalid) {
+				t.Errorf("Expected ErrIdempotencyKeyInvalid for %q, got %v", key, err)
+			}
+		}
+	})
+}
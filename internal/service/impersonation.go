@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+)
+
+// Impersonation errors
+var (
+	ErrImpersonationForbidden = apperror.Forbidden("impersonation_forbidden", "only active admins can impersonate users")
+	ErrImpersonateSelf        = apperror.Validation("impersonate_self", "cannot impersonate yourself")
+	ErrImpersonateAdmin       = apperror.Forbidden("impersonate_admin", "admins cannot be impersonated")
+	ErrImpersonationNotFound  = apperror.NotFound("impersonation_target_not_found", "user to impersonate not found")
+)
+
+// ImpersonationTTL is how long an impersonation token stays valid. It is not tied to a session,
+// so it cannot be revoked and is kept short instead.
+const ImpersonationTTL = 15 * time.Minute
+
+// Actor is the admin behind an impersonation token, carried in the "act" claim (RFC 8693).
+type Actor struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// Impersonate issues a short-lived token for targetUserID whose claims also name the admin
+// actorID, so that every request made with it can be attributed to the real actor.
+func (s *AuthService) Impersonate(ctx context.Context, actorID, targetUserID string) (string, *repository.User, time.Time, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Impersonate")
+	defer span.End()
+
+	actor, err := s.userRepo.GetUserByID(ctx, actorID)
+	if err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to load impersonating user: %w", err)
+	}
+	if actor.Role != "admin" || !actor.IsActive {
+		return "", nil, time.Time{}, ErrImpersonationForbidden
+	}
+	if actorID == targetUserID {
+		return "", nil, time.Time{}, ErrImpersonateSelf
+	}
+
+	target, err := s.userRepo.GetUserByID(ctx, targetUserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return "", nil, time.Time{}, ErrImpersonationNotFound
+		}
+		return "", nil, time.Time{}, fmt.Errorf("failed to load user to impersonate: %w", err)
+	}
+	if target.Role == "admin" {
+		return "", nil, time.Time{}, ErrImpersonateAdmin
+	}
+	if !target.IsActive {
+		return "", nil, time.Time{}, ErrAccountDisabled
+	}
+
+	now := time.Now()
+	claims := userClaims(target, "", now, ImpersonationTTL)
+	claims.Actor = &Actor{UserID: actor.ID, Username: actor.Username}
+	token, err := s.sign(claims)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+
+	expiresAt := claims.ExpiresAt.Time
+	s.RecordSecurityEvent(ctx, repository.SecurityEvent{UserID: target.ID, Type: SecurityEventImpersonationStarted, ActorID: actor.ID})
+	auditLog.InfoContext(ctx, "impersonation started",
+		"actor_id", actor.ID, "actor", actor.Username, "target_id", target.ID, "target", target.Username,
+		"expires_at", expiresAt.Format(time.RFC3339))
+	return token, target, expiresAt, nil
+}
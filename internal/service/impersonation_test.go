@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+func TestAuthService_Impersonate(t *testing.T) {
+	users := map[string]*repository.User{
+		"admin-1":    {ID: "admin-1", Username: "root", Role: "admin", IsActive: true},
+		"admin-2":    {ID: "admin-2", Username: "other-admin", Role: "admin", IsActive: true},
+		"admin-off":  {ID: "admin-off", Username: "retired", Role: "admin", IsActive: false},
+		"user-1":     {ID: "user-1", Username: "jane", Email: "jane@example.com", Role: "user", IsActive: true},
+		"user-off":   {ID: "user-off", Username: "gone", Role: "user", IsActive: false},
+		"readonly-1": {ID: "readonly-1", Username: "viewer", Role: "readonly", IsActive: true},
+	}
+
+	tests := []struct {
+		name          string
+		actorID       string
+		targetID      string
+		expectedError error
+	}{
+		{name: "Success", actorID: "admin-1", targetID: "user-1"},
+		{name: "Failure - Actor not admin", actorID: "readonly-1", targetID: "user-1", expectedError: ErrImpersonationForbidden},
+		{name: "Failure - Actor disabled", actorID: "admin-off", targetID: "user-1", expectedError: ErrImpersonationForbidden},
+		{name: "Failure - Self", actorID: "admin-1", targetID: "admin-1", expectedError: ErrImpersonateSelf},
+		{name: "Failure - Admin target", actorID: "admin-1", targetID: "admin-2", expectedError: ErrImpersonateAdmin},
+		{name: "Failure - Target not found", actorID: "admin-1", targetID: "missing", expectedError: ErrImpersonationNotFound},
+		{name: "Failure - Target disabled", actorID: "admin-1", targetID: "user-off", expectedError: ErrAccountDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockUserRepository{
+				GetUserByIDFunc: func(ctx context.Context, id string) (*repository.User, error) {
+					if u, ok := users[id]; ok {
+						return u, nil
+					}
+					return nil, repository.ErrUserNotFound
+				},
+			}
+			svc := NewAuthService(repo, "test-jwt-secret")
+
+			token, user, expiresAt, err := svc.Impersonate(context.Background(), tt.actorID, tt.targetID)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if user.ID != tt.targetID {
+				t.Errorf("Expected user %s, got %s", tt.targetID, user.ID)
+			}
+			if d := time.Until(expiresAt); d <= ImpersonationTTL-time.Minute || d > ImpersonationTTL {
+				t.Errorf("Expected expiry about %v away, got %v", ImpersonationTTL, d)
+			}
+
+			claims, err := svc.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("Expected token to validate, got: %v", err)
+			}
+			if claims.UserID != tt.targetID || claims.Role != "user" {
+				t.Errorf("Expected claims for %s as user, got %s as %s", tt.targetID, claims.UserID, claims.Role)
+			}
+			if claims.Actor == nil || claims.Actor.UserID != tt.actorID || claims.Actor.Username != "root" {
+				t.Errorf("Expected actor %s in claims, got %+v", tt.actorID, claims.Actor)
+			}
+			if claims.ID != "" {
+				t.Errorf("Expected no session id, got %q", claims.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+)
+
+// Invitation errors
+var (
+	ErrInvitationMissingFields = apperror.Validation("invitation_missing_fields", "email and role are required")
+	ErrInvalidRole             = apperror.Validation("invalid_role", "role must be one of: admin, counsellor, staff, user")
+	ErrInvitationInvalid       = apperror.Validation("invitation_invalid", "invitation is invalid or has expired")
+	ErrInvitationUsed          = apperror.Conflict("invitation_used", "invitation has already been used or revoked")
+	ErrInvitationEmailMismatch = apperror.Validation("invitation_email_mismatch", "email does not match the invitation")
+)
+
+const inviteTokenAudience = "invite"
+
+// Roles that can be assigned to users, either through invitations or seeded data.
+var validRoles = map[string]bool{
+	"admin":      true,
+	"counsellor": true,
+	"staff":      true,
+	"user":       true,
+}
+
+// InvitationRepository interface for dependency injection
+type InvitationRepository interface {
+	CreateInvitation(ctx context.Context, inv *repository.Invitation) error
+	GetInvitationByID(ctx context.Context, id string) (*repository.Invitation, error)
+	ListInvitations(ctx context.Context) ([]repository.Invitation, error)
+	TransitionInvitation(ctx context.Context, id, status, acceptedBy string) error
+	ReopenInvitation(ctx context.Context, id string) error
+}
+
+// InvitationMailer delivers invitation links to invitees.
+type InvitationMailer interface {
+	SendInvitation(ctx context.Context, email, role, link string) error
+}
+
+type InvitationService struct {
+	repo     InvitationRepository
+	userRepo UserRepository
+	mailer   InvitationMailer
+	secret   []byte
+	ttl      time.Duration
+	baseURL  string
+}
+
+// NewInvitationService creates an invitation service. Tokens are signed with secret and expire
+// after ttl; baseURL is the frontend page that accepts them (the token is added as ?token=).
+func NewInvitationService(repo InvitationRepository, userRepo UserRepository, mailer InvitationMailer, secret string, ttl time.Duration, baseURL string) *InvitationService {
+	return &InvitationService{
+		repo:     repo,
+		userRepo: userRepo,
+		mailer:   mailer,
+		secret:   []byte(secret),
+		ttl:      ttl,
+		baseURL:  baseURL,
+	}
+}
+
+// InviteClaims are carried in a signed invitation token.
+type InviteClaims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// CreateInvitation records a pending invitation for email with the given role and mails the
+// signed token to the invitee. The token is returned so admins can share it manually.
+func (s *InvitationService) CreateInvitation(ctx context.Context, invitedBy, email, role string) (*repository.Invitation, string, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.CreateInvitation")
+	defer span.End()
+
+	email = strings.TrimSpace(strings.ToLower(email))
+	role = strings.TrimSpace(strings.ToLower(role))
+
+	if email == "" || role == "" {
+		return nil, "", ErrInvitationMissingFields
+	}
+	if !authEmailRegex.MatchString(email) {
+		return nil, "", ErrAuthInvalidEmail
+	}
+	if !validRoles[role] {
+		return nil, "", ErrInvalidRole
+	}
+
+	if _, err := s.userRepo.GetUserByEmail(ctx, email); err == nil {
+		return nil, "", ErrUserExists
+	}
+
+	now := time.Now()
+	inv := &repository.Invitation{
+		ID:        "invite-" + uuid.New().String(),
+		Email:     email,
+		Role:      role,
+		Status:    repository.InvitationStatusPending,
+		InvitedBy: invitedBy,
+		ExpiresAt: now.Add(s.ttl).Format(time.RFC3339),
+		CreatedAt: now.Format(time.RFC3339),
+		UpdatedAt: now.Format(time.RFC3339),
+	}
+
+	token, err := s.signToken(inv, now)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.repo.CreateInvitation(ctx, inv); err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := s.mailer.SendInvitation(ctx, inv.Email, inv.Role, s.inviteLink(token)); err != nil {
+		return nil, "", fmt.Errorf("failed to send invitation: %w", err)
+	}
+
+	return inv, token, nil
+}
+
+func (s *InvitationService) ListInvitations(ctx context.Context) ([]repository.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.ListInvitations")
+	defer span.End()
+
+	invitations, err := s.repo.ListInvitations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (s *InvitationService) RevokeInvitation(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "InvitationService.RevokeInvitation")
+	defer span.End()
+
+	if err := s.repo.TransitionInvitation(ctx, id, repository.InvitationStatusRevoked, ""); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotPending) {
+			return ErrInvitationUsed
+		}
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	return nil
+}
+
+// Redeem verifies token, checks it was issued for email and atomically marks the invitation
+// accepted by userID. The returned invitation carries the role to assign.
+func (s *InvitationService) Redeem(ctx context.Context, token, email, userID string) (*repository.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.Redeem")
+	defer span.End()
+
+	claims := &InviteClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return s.secret, nil
+	})
+	if err != nil || !parsed.Valid || !claims.VerifyAudience(inviteTokenAudience, true) {
+		return nil, ErrInvitationInvalid
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(email), claims.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	inv, err := s.repo.GetInvitationByID(ctx, claims.ID)
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	if inv.Status != repository.InvitationStatusPending {
+		return nil, ErrInvitationUsed
+	}
+
+	if err := s.repo.TransitionInvitation(ctx, inv.ID, repository.InvitationStatusAccepted, userID); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotPending) {
+			return nil, ErrInvitationUsed
+		}
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	inv.Status = repository.InvitationStatusAccepted
+	inv.AcceptedBy = userID
+	return inv, nil
+}
+
+// Release reopens an invitation claimed by Redeem when the registration that claimed it failed.
+func (s *InvitationService) Release(ctx context.Context, invitationID string) error {
+	ctx, span := tracing.Start(ctx, "InvitationService.Release")
+	defer span.End()
+
+	return s.repo.ReopenInvitation(ctx, invitationID)
+}
+
+func (s *InvitationService) signToken(inv *repository.Invitation, now time.Time) (string, error) {
+	claims := &InviteClaims{
+		Email: inv.Email,
+		Role:  inv.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        inv.ID,
+			Audience:  jwt.ClaimStrings{inviteTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "john-ai-project",
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign invitation: %w", err)
+	}
+	return token, nil
+}
+
+func (s *InvitationService) inviteLink(token string) string {
+	return tokenLink(s.baseURL, token)
+}
+
+// tokenLink adds token to a frontend URL as ?token=. Without a base URL the bare token is used.
+func tokenLink(baseURL, token string) string {
+	if baseURL == "" {
+		return token
+	}
+	sep := "?"
+	if strings.Contains(baseURL, "?") {
+		sep = "&"
+	}
+	return baseURL + sep + "token=" + url.QueryEscape(token)
+}
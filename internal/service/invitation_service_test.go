@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+// Mock InvitationRepository backed by a map so tokens can round-trip through Redeem.
+type MockInvitationRepository struct {
+	invitations map[string]*repository.Invitation
+}
+
+func NewMockInvitationRepository() *MockInvitationRepository {
+	return &MockInvitationRepository{invitations: map[string]*repository.Invitation{}}
+}
+
+func (m *MockInvitationRepository) CreateInvitation(ctx context.Context, inv *repository.Invitation) error {
+	cp := *inv
+	m.invitations[inv.ID] = &cp
+	return nil
+}
+
+func (m *MockInvitationRepository) GetInvitationByID(ctx context.Context, id string) (*repository.Invitation, error) {
+	inv, ok := m.invitations[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", repository.ErrInvitationNotFound, id)
+	}
+	cp := *inv
+	return &cp, nil
+}
+
+func (m *MockInvitationRepository) ListInvitations(ctx context.Context) ([]repository.Invitation, error) {
+	var out []repository.Invitation
+	for _, inv := range m.invitations {
+		out = append(out, *inv)
+	}
+	return out, nil
+}
+
+func (m *MockInvitationRepository) TransitionInvitation(ctx context.Context, id, status, acceptedBy string) error {
+	inv, ok := m.invitations[id]
+	if !ok || inv.Status != repository.InvitationStatusPending {
+		return repository.ErrInvitationNotPending
+	}
+	inv.Status = status
+	inv.AcceptedBy = acceptedBy
+	return nil
+}
+
+func (m *MockInvitationRepository) ReopenInvitation(ctx context.Context, id string) error {
+	inv, ok := m.invitations[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", repository.ErrInvitationNotFound, id)
+	}
+	inv.Status = repository.InvitationStatusPending
+	inv.AcceptedBy = ""
+	return nil
+}
+
+// Mock InvitationMailer
+type MockInvitationMailer struct {
+	sent []string
+}
+
+func (m *MockInvitationMailer) SendInvitation(ctx context.Context, email, role, link string) error {
+	m.sent = append(m.sent, link)
+	return nil
+}
+
+func TestInvitationService_CreateInvitation(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		role          string
+		mockSetup     func(*MockUserRepository)
+		expectedError string
+	}{
+		{
+			name:  "Success - Pending invitation",
+			email: "Alice@Example.com",
+			role:  "counsellor",
+		},
+		{
+			name:          "Failure - Missing role",
+			email:         "alice@example.com",
+			role:          "",
+			expectedError: "email and role are required",
+		},
+		{
+			name:          "Failure - Unknown role",
+			email:         "alice@example.com",
+			role:          "superuser",
+			expectedError: "role must be one of",
+		},
+		{
+			name:          "Failure - Invalid email",
+			email:         "not-an-email",
+			role:          "staff",
+			expectedError: "invalid email format",
+		},
+		{
+			name:  "Failure - User already exists",
+			email: "existing@example.com",
+			role:  "staff",
+			mockSetup: func(m *MockUserRepository) {
+				m.GetUserByEmailFunc = func(ctx context.Context, email string) (*repository.User, error) {
+					return &repository.User{Email: email}, nil
+				}
+			},
+			expectedError: "user with this email already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &MockUserRepository{}
+			if tt.mockSetup != nil {
+				tt.mockSetup(userRepo)
+			}
+			mailer := &MockInvitationMailer{}
+			svc := NewInvitationService(NewMockInvitationRepository(), userRepo, mailer, "invite-secret", time.Hour, "https://app.example.com/accept")
+
+			inv, token, err := svc.CreateInvitation(context.Background(), "admin-1", tt.email, tt.role)
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("Expected error containing '%s', got: %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if inv.Email != "alice@example.com" || inv.Status != repository.InvitationStatusPending || inv.InvitedBy != "admin-1" {
+				t.Errorf("Unexpected invitation: %+v", inv)
+			}
+			if token == "" {
+				t.Error("Expected token, got empty")
+			}
+			if len(mailer.sent) != 1 || !strings.HasPrefix(mailer.sent[0], "https://app.example.com/accept?token=") {
+				t.Errorf("Expected one invitation link to be mailed, got %v", mailer.sent)
+			}
+		})
+	}
+}
+
+func TestInvitationService_Redeem(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockInvitationRepository()
+	svc := NewInvitationService(repo, &MockUserRepository{}, &MockInvitationMailer{}, "invite-secret", time.Hour, "")
+
+	inv, token, err := svc.CreateInvitation(ctx, "admin-1", "alice@example.com", "staff")
+	if err != nil {
+		t.Fatalf("CreateInvitation failed: %v", err)
+	}
+
+	if _, err := svc.Redeem(ctx, token, "mallory@example.com", "user-1"); !errors.Is(err, ErrInvitationEmailMismatch) {
+		t.Errorf("Expected ErrInvitationEmailMismatch, got: %v", err)
+	}
+
+	other := NewInvitationService(repo, &MockUserRepository{}, &MockInvitationMailer{}, "other-secret", time.Hour, "")
+	if _, err := other.Redeem(ctx, token, "alice@example.com", "user-1"); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Expected ErrInvitationInvalid for token signed with another secret, got: %v", err)
+	}
+
+	redeemed, err := svc.Redeem(ctx, token, "ALICE@example.com", "user-1")
+	if err != nil {
+		t.Fatalf("Redeem failed: %v", err)
+	}
+	if redeemed.ID != inv.ID || redeemed.Role != "staff" || redeemed.AcceptedBy != "user-1" {
+		t.Errorf("Unexpected redeemed invitation: %+v", redeemed)
+	}
+
+	if _, err := svc.Redeem(ctx, token, "alice@example.com", "user-2"); !errors.Is(err, ErrInvitationUsed) {
+		t.Errorf("Expected ErrInvitationUsed on second redeem, got: %v", err)
+	}
+
+	if err := svc.Release(ctx, inv.ID); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := svc.Redeem(ctx, token, "alice@example.com", "user-2"); err != nil {
+		t.Errorf("Expected released invitation to be redeemable, got: %v", err)
+	}
+}
+
+func TestInvitationService_Redeem_Expired(t *testing.T) {
+	ctx := context.Background()
+	svc := NewInvitationService(NewMockInvitationRepository(), &MockUserRepository{}, &MockInvitationMailer{}, "invite-secret", -time.Minute, "")
+
+	_, token, err := svc.CreateInvitation(ctx, "admin-1", "alice@example.com", "staff")
+	if err != nil {
+		t.Fatalf("CreateInvitation failed: %v", err)
+	}
+
+	if _, err := svc.Redeem(ctx, token, "alice@example.com", "user-1"); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Expected ErrInvitationInvalid for expired token, got: %v", err)
+	}
+}
+
+func TestInvitationService_RevokeInvitation(t *testing.T) {
+	ctx := context.Background()
+	svc := NewInvitationService(NewMockInvitationRepository(), &MockUserRepository{}, &MockInvitationMailer{}, "invite-secret", time.Hour, "")
+
+	inv, token, err := svc.CreateInvitation(ctx, "admin-1", "alice@example.com", "staff")
+	if err != nil {
+		t.Fatalf("CreateInvitation failed: %v", err)
+	}
+
+	if err := svc.RevokeInvitation(ctx, inv.ID); err != nil {
+		t.Fatalf("RevokeInvitation failed: %v", err)
+	}
+	if err := svc.RevokeInvitation(ctx, inv.ID); !errors.Is(err, ErrInvitationUsed) {
+		t.Errorf("Expected ErrInvitationUsed on second revoke, got: %v", err)
+	}
+	if _, err := svc.Redeem(ctx, token, "alice@example.com", "user-1"); !errors.Is(err, ErrInvitationUsed) {
+		t.Errorf("Expected revoked invitation to be rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,590 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+)
+
+// OIDC errors
+var (
+	ErrOIDCLoginExpired    = apperror.Validation("sso_login_expired", "sso login expired or was started in another browser; please sign in again")
+	ErrOIDCStateMismatch   = apperror.Validation("sso_state_mismatch", "sso state does not match the login that was started")
+	ErrOIDCExchangeFailed  = apperror.Unauthorized("sso_exchange_failed", "identity provider rejected the authorization code")
+	ErrOIDCInvalidIDToken  = apperror.Unauthorized("sso_invalid_id_token", "invalid id token")
+	ErrOIDCEmailUnverified = apperror.Forbidden("sso_email_unverified", "identity provider has not verified this email address")
+	ErrOIDCNoRole          = apperror.Forbidden("sso_no_role", "none of your identity provider groups grant access to this application")
+	ErrOIDCAccountConflict = apperror.Forbidden("sso_account_conflict", "email is already linked to a different identity provider account")
+	ErrOIDCInvalidGroupMap = errors.New("invalid group to role mapping")
+)
+
+const (
+	// OIDCFlowTTL bounds how long a user has to finish signing in at the identity provider.
+	OIDCFlowTTL      = 10 * time.Minute
+	oidcFlowAudience = "oidc-flow"
+	// jwksRefreshInterval limits how often an unknown kid triggers a JWKS refetch.
+	jwksRefreshInterval = time.Minute
+)
+
+// rolePriority decides which role wins when a user's groups map to several roles.
+var rolePriority = map[string]int{
+	"admin":      4,
+	"counsellor": 3,
+	"staff":      2,
+	"user":       1,
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9._\-]`)
+
+// OIDCConfig configures SSO against an OpenID Connect identity provider.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // optional; public clients rely on PKCE alone
+	RedirectURL  string
+	Scopes       []string // defaults to openid, email, profile
+	GroupsClaim  string   // defaults to "groups"
+	// GroupRoles maps IdP group names to application roles.
+	GroupRoles map[string]string
+	// DefaultRole is given to users whose groups match no mapping. Empty denies them access.
+	DefaultRole string
+}
+
+// ParseGroupRoles parses "group=role,group=role" as used by OIDC_GROUP_ROLES.
+func ParseGroupRoles(spec string) (map[string]string, error) {
+	roles := map[string]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || !validRoles[role] {
+			return nil, fmt.Errorf("%w: %q", ErrOIDCInvalidGroupMap, pair)
+		}
+		roles[group] = role
+	}
+	return roles, nil
+}
+
+// TokenIssuer issues application tokens. AuthService implements it.
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, user *repository.User) (string, error)
+}
+
+// OIDCService signs staff in through an OpenID Connect provider using the authorization-code
+// flow with PKCE, then issues the same application JWT as a password login.
+type OIDCService struct {
+	cfg        OIDCConfig
+	users      UserRepository
+	tokens     TokenIssuer
+	flowSecret []byte
+	httpClient *http.Client
+	events     SecurityEventRecorder
+
+	mu          sync.Mutex
+	metadata    *oidcMetadata
+	keys        map[string]*SigningKey
+	keysFetched time.Time
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcFlowClaims carries the per-login secrets between BeginLogin and CompleteLogin. It is
+// signed, not encrypted, and only ever stored in an HttpOnly cookie on the user's browser.
+type oidcFlowClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// OIDCOption configures optional OIDCService behaviour.
+type OIDCOption func(*OIDCService)
+
+// WithOIDCSecurityEvents records SSO logins, including whether the provider reported MFA.
+func WithOIDCSecurityEvents(events SecurityEventRecorder) OIDCOption {
+	return func(s *OIDCService) {
+		s.events = events
+	}
+}
+
+func NewOIDCService(cfg OIDCConfig, users UserRepository, tokens TokenIssuer, flowSecret string, opts ...OIDCOption) *OIDCService {
+	cfg.IssuerURL = strings.TrimSuffix(cfg.IssuerURL, "/")
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	s := &OIDCService{
+		cfg:        cfg,
+		users:      users,
+		tokens:     tokens,
+		flowSecret: []byte(flowSecret),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       map[string]*SigningKey{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// BeginLogin returns the identity provider URL to redirect the browser to, and a signed flow
+// token holding the state, nonce and PKCE verifier that CompleteLogin needs.
+func (s *OIDCService) BeginLogin(ctx context.Context) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.BeginLogin")
+	defer span.End()
+
+	meta, err := s.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		if secrets[i], err = randomToken(32, base64.RawURLEncoding.EncodeToString); err != nil {
+			return "", "", err
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	now := time.Now()
+	flow, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcFlowClaims{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcFlowAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(OIDCFlowTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}).SignedString(s.flowSecret)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign sso flow: %w", err)
+	}
+
+	authURL, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", s.cfg.ClientID)
+	q.Set("redirect_uri", s.cfg.RedirectURL)
+	q.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	return authURL.String(), flow, nil
+}
+
+// CompleteLogin handles the identity provider callback: it checks state, exchanges the code,
+// verifies the ID token and provisions or updates the user before issuing an application token.
+func (s *OIDCService) CompleteLogin(ctx context.Context, flow, state, code string) (string, *repository.User, error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.CompleteLogin")
+	defer span.End()
+
+	var fc oidcFlowClaims
+	_, err := jwt.ParseWithClaims(flow, &fc, func(t *jwt.Token) (interface{}, error) {
+		return s.flowSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !fc.VerifyAudience(oidcFlowAudience, true) {
+		return "", nil, ErrOIDCLoginExpired
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(fc.State)) != 1 {
+		return "", nil, ErrOIDCStateMismatch
+	}
+
+	meta, err := s.discover(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	rawIDToken, err := s.exchangeCode(ctx, meta, code, fc.Verifier)
+	if err != nil {
+		return "", nil, err
+	}
+	claims, err := s.verifyIDToken(ctx, meta, rawIDToken, fc.Nonce)
+	if err != nil {
+		return "", nil, err
+	}
+
+	role, err := s.mapRole(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	user, err := s.provisionUser(ctx, claims, role)
+	if err != nil {
+		return "", nil, err
+	}
+	if !user.IsActive {
+		s.recordLogin(ctx, user, claims, SecurityEventLoginFailed, LoginFailureAccountDisabled)
+		return "", nil, ErrAccountDisabled
+	}
+
+	token, err := s.tokens.IssueToken(ctx, user)
+	if err != nil {
+		return "", nil, err
+	}
+	s.recordLogin(ctx, user, claims, SecurityEventLoginSucceeded, "")
+	authLog.InfoContext(ctx, "sso login succeeded", logger.KeyUserID, user.ID, "username", user.Username, "role", user.Role)
+	return token, user, nil
+}
+
+func (s *OIDCService) recordLogin(ctx context.Context, user *repository.User, claims jwt.MapClaims, eventType, reason string) {
+	if s.events == nil {
+		return
+	}
+	s.events.RecordSecurityEvent(ctx, repository.SecurityEvent{
+		UserID: user.ID,
+		Type:   eventType,
+		Method: LoginMethodOIDC,
+		MFA:    usedMFA(claims),
+		Reason: reason,
+	})
+}
+
+// mfaMethods are the RFC 8176 authentication method references that mean a second factor was
+// used.
+var mfaMethods = map[string]bool{"mfa": true, "otp": true, "hwk": true, "sms": true, "swk": true}
+
+// usedMFA reports whether the ID token's amr claim shows the provider used a second factor.
+func usedMFA(claims jwt.MapClaims) bool {
+	for _, method := range stringsClaim(claims, "amr") {
+		if mfaMethods[method] {
+			return true
+		}
+	}
+	return false
+}
+
+// discover fetches and caches the provider's OpenID configuration.
+func (s *OIDCService) discover(ctx context.Context) (*oidcMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metadata != nil {
+		return s.metadata, nil
+	}
+
+	var meta oidcMetadata
+	if err := s.getJSON(ctx, s.cfg.IssuerURL+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != s.cfg.IssuerURL {
+		return nil, fmt.Errorf("identity provider issuer %q does not match configured %q", meta.Issuer, s.cfg.IssuerURL)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("identity provider discovery document is missing endpoints")
+	}
+	s.metadata = &meta
+	return s.metadata, nil
+}
+
+func (s *OIDCService) exchangeCode(ctx context.Context, meta *oidcMetadata, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.cfg.RedirectURL},
+		"client_id":     {s.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if s.cfg.ClientSecret != "" {
+		form.Set("client_secret", s.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		authLog.WarnContext(ctx, "sso token exchange failed", logger.KeyStatus, resp.StatusCode, "oauth_error", body.Error, "oauth_error_description", body.ErrorDescription)
+		return "", fmt.Errorf("%w: %s", ErrOIDCExchangeFailed, body.Error)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("%w: token response has no id_token", ErrOIDCInvalidIDToken)
+	}
+	return body.IDToken, nil
+}
+
+func (s *OIDCService) verifyIDToken(ctx context.Context, meta *oidcMetadata, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := s.providerKey(ctx, meta, kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.verifyKey, nil
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidIDToken, err)
+	}
+
+	if !claims.VerifyIssuer(meta.Issuer, true) {
+		return nil, fmt.Errorf("%w: wrong issuer", ErrOIDCInvalidIDToken)
+	}
+	if !claims.VerifyAudience(s.cfg.ClientID, true) {
+		return nil, fmt.Errorf("%w: wrong audience", ErrOIDCInvalidIDToken)
+	}
+	if got, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCInvalidIDToken)
+	}
+	if stringClaim(claims, "sub") == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrOIDCInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// providerKey returns the provider's key for kid, refetching the JWKS when the kid is unknown
+// so the provider can rotate keys. A token without kid is accepted only if there is one key.
+func (s *OIDCService) providerKey(ctx context.Context, meta *oidcMetadata, kid string) (*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lookup := func() *SigningKey {
+		if kid == "" && len(s.keys) == 1 {
+			for _, k := range s.keys {
+				return k
+			}
+		}
+		return s.keys[kid]
+	}
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	if time.Since(s.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+
+	var set JWKS
+	if err := s.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch identity provider keys: %w", err)
+	}
+	s.keysFetched = time.Now()
+	s.keys = map[string]*SigningKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := verifyKeyFromJWK(jwk)
+		if err != nil {
+			authLog.WarnContext(ctx, "skipping identity provider key", "kid", jwk.Kid, logger.Error(err))
+			continue
+		}
+		s.keys[key.ID] = key
+	}
+
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+}
+
+func (s *OIDCService) mapRole(claims jwt.MapClaims) (string, error) {
+	role := s.cfg.DefaultRole
+	for _, group := range stringsClaim(claims, s.cfg.GroupsClaim) {
+		if mapped, ok := s.cfg.GroupRoles[group]; ok && rolePriority[mapped] > rolePriority[role] {
+			role = mapped
+		}
+	}
+	if role == "" {
+		return "", ErrOIDCNoRole
+	}
+	return role, nil
+}
+
+// provisionUser links the identity to the user with the same email, creating the user on first
+// login. A password account is linked only when the provider has verified the email, since
+// otherwise anyone who can set that email at the provider could take it over, and it keeps its
+// role. Accounts without a password belong to the provider, and their role follows its groups.
+func (s *OIDCService) provisionUser(ctx context.Context, claims jwt.MapClaims, role string) (*repository.User, error) {
+	subject := stringClaim(claims, "sub")
+	email := stringClaim(claims, "email")
+	if email == "" {
+		return nil, fmt.Errorf("%w: missing email", ErrOIDCInvalidIDToken)
+	}
+	// Corporate providers often omit email_verified; only an explicit false is rejected.
+	if _, ok := claims["email_verified"]; ok && !emailVerified(claims) {
+		return nil, ErrOIDCEmailUnverified
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err == nil {
+		if user.ExternalID != "" && user.ExternalID != subject {
+			return nil, ErrOIDCAccountConflict
+		}
+		if user.ExternalID == "" && user.PasswordHash != "" && !emailVerified(claims) {
+			return nil, ErrOIDCAccountConflict
+		}
+		if user.PasswordHash != "" {
+			role = user.Role
+		}
+		if user.ExternalID == subject && user.Role == role {
+			return user, nil
+		}
+		user.AuthProvider = repository.AuthProviderOIDC
+		user.ExternalID = subject
+		user.Role = role
+		user.UpdatedAt = now
+		if err := s.users.UpdateUser(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to link sso user: %w", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to look up sso user: %w", err)
+	}
+
+	username, err := s.availableUsername(ctx, claims, email)
+	if err != nil {
+		return nil, err
+	}
+	firstName, lastName := stringClaim(claims, "given_name"), stringClaim(claims, "family_name")
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(stringClaim(claims, "name"), " ")
+	}
+
+	// No password hash: SSO users cannot use password login.
+	user = &repository.User{
+		ID:           uuid.New().String(),
+		Username:     username,
+		Email:        email,
+		FirstName:    firstName,
+		LastName:     lastName,
+		Role:         role,
+		IsActive:     true,
+		AuthProvider: repository.AuthProviderOIDC,
+		ExternalID:   subject,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.users.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to provision sso user: %w", err)
+	}
+	authLog.InfoContext(ctx, "provisioned sso user", logger.KeyUserID, user.ID, "username", user.Username, "role", role)
+	return user, nil
+}
+
+// emailVerified reports whether the provider explicitly vouches for the email claim.
+func emailVerified(claims jwt.MapClaims) bool {
+	verified := claims["email_verified"]
+	return verified == true || verified == "true"
+}
+
+// availableUsername derives a username from preferred_username or the email, adding a short
+// suffix when it is already taken.
+func (s *OIDCService) availableUsername(ctx context.Context, claims jwt.MapClaims, email string) (string, error) {
+	base := stringClaim(claims, "preferred_username")
+	if base == "" {
+		base = email
+	}
+	base, _, _ = strings.Cut(base, "@")
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		if _, err := s.users.GetUserByUsername(ctx, candidate); errors.Is(err, repository.ErrUserNotFound) {
+			return candidate, nil
+		} else if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		suffix, err := randomToken(2, hex.EncodeToString)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + suffix
+	}
+	return "", ErrUsernameTaken
+}
+
+func (s *OIDCService) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	v, _ := claims[name].(string)
+	return strings.TrimSpace(v)
+}
+
+// stringsClaim reads a claim that may be a single string or a list of strings.
+func stringsClaim(claims jwt.MapClaims, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
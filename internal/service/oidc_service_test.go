@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmason/john_ai_project/internal/oidctest"
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+// MemoryUserRepository is a map-backed UserRepository keyed by email
+type MemoryUserRepository struct {
+	users map[string]*repository.User
+}
+
+func (m *MemoryUserRepository) CreateUser(ctx context.Context, user *repository.User) error {
+	cp := *user
+	m.users[user.Email] = &cp
+	return nil
+}
+
+func (m *MemoryUserRepository) UpdateUser(ctx context.Context, user *repository.User) error {
+	// Drop the entry under the old email when the address changes
+	for email, u := range m.users {
+		if u.ID == user.ID {
+			delete(m.users, email)
+		}
+	}
+	return m.CreateUser(ctx, user)
+}
+
+func (m *MemoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*repository.User, error) {
+	if u, ok := m.users[email]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *MemoryUserRepository) GetUserByUsername(ctx context.Context, username string) (*repository.User, error) {
+	for _, u := range m.users {
+		if u.Username == username {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *MemoryUserRepository) GetUserByID(ctx context.Context, id string) (*repository.User, error) {
+	for _, u := range m.users {
+		if u.ID == id {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+type stubTokenIssuer struct{}
+
+func (stubTokenIssuer) IssueToken(ctx context.Context, user *repository.User) (string, error) {
+	return "app-token-" + user.ID, nil
+}
+
+// oidcLogin runs the browser side of the flow against the stand-in provider: follow the
+// authorization redirect and hand the callback parameters to CompleteLogin.
+func oidcLogin(t *testing.T, svc *OIDCService) (string, *repository.User, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authURL, flow, err := svc.BeginLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect from authorize, got %d", resp.StatusCode)
+	}
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid callback location: %v", err)
+	}
+
+	return svc.CompleteLogin(ctx, flow, callback.Query().Get("state"), callback.Query().Get("code"))
+}
+
+func newTestIdP(t *testing.T, identity oidctest.Identity) *oidctest.Provider {
+	t.Helper()
+	idp, err := oidctest.NewProvider("john-ai", identity)
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+	server := httptest.NewServer(idp)
+	t.Cleanup(server.Close)
+	idp.Issuer = server.URL
+	return idp
+}
+
+func TestOIDCService_Login(t *testing.T) {
+	staff := oidctest.Identity{
+		Subject:       "idp-123",
+		Email:         "casey@example.com",
+		EmailVerified: true,
+		Username:      "casey@example.com",
+		GivenName:     "Casey",
+		FamilyName:    "Jones",
+		Groups:        []string{"everyone", "counsellors"},
+	}
+
+	tests := []struct {
+		name          string
+		identity      oidctest.Identity
+		existing      *repository.User
+		modifyClaims  func(jwt.MapClaims)
+		defaultRole   string
+		wantRole      string
+		wantUsername  string
+		expectedError error
+	}{
+		{
+			name:         "Success - First login provisions user",
+			identity:     staff,
+			wantRole:     "counsellor",
+			wantUsername: "casey",
+		},
+		{
+			name:         "Success - Highest mapped role wins",
+			identity:     func() oidctest.Identity { i := staff; i.Groups = []string{"counsellors", "it-admins"}; return i }(),
+			wantRole:     "admin",
+			wantUsername: "casey",
+		},
+		{
+			name:         "Success - Existing password user is linked and keeps role",
+			identity:     staff,
+			existing:     &repository.User{ID: "user-1", Username: "cjones", Email: "casey@example.com", PasswordHash: "hash", Role: "admin", IsActive: true},
+			wantRole:     "admin",
+			wantUsername: "cjones",
+		},
+		{
+			name:         "Success - SSO user role follows groups",
+			identity:     staff,
+			existing:     &repository.User{ID: "user-1", Username: "cjones", Email: "casey@example.com", AuthProvider: repository.AuthProviderOIDC, ExternalID: "idp-123", Role: "user", IsActive: true},
+			wantRole:     "counsellor",
+			wantUsername: "cjones",
+		},
+		{
+			name:          "Failure - Password user not linked without email_verified",
+			identity:      staff,
+			existing:      &repository.User{ID: "user-1", Username: "cjones", Email: "casey@example.com", PasswordHash: "hash", Role: "admin", IsActive: true},
+			modifyClaims:  func(c jwt.MapClaims) { delete(c, "email_verified") },
+			expectedError: ErrOIDCAccountConflict,
+		},
+		{
+			name:         "Success - Missing email_verified still provisions a new user",
+			identity:     staff,
+			modifyClaims: func(c jwt.MapClaims) { delete(c, "email_verified") },
+			wantRole:     "counsellor",
+			wantUsername: "casey",
+		},
+		{
+			name:         "Success - Taken username gets a suffix",
+			identity:     staff,
+			existing:     &repository.User{ID: "user-2", Username: "casey", Email: "other@example.com", Role: "user", IsActive: true},
+			wantRole:     "counsellor",
+			wantUsername: "casey-",
+		},
+		{
+			name:          "Failure - No mapped group and no default role",
+			identity:      func() oidctest.Identity { i := staff; i.Groups = []string{"everyone"}; return i }(),
+			expectedError: ErrOIDCNoRole,
+		},
+		{
+			name:         "Success - Default role for unmapped groups",
+			identity:     func() oidctest.Identity { i := staff; i.Groups = nil; return i }(),
+			defaultRole:  "staff",
+			wantRole:     "staff",
+			wantUsername: "casey",
+		},
+		{
+			name:          "Failure - Email linked to another subject",
+			identity:      staff,
+			existing:      &repository.User{ID: "user-1", Username: "cjones", Email: "casey@example.com", ExternalID: "idp-999", IsActive: true},
+			expectedError: ErrOIDCAccountConflict,
+		},
+		{
+			name:          "Failure - Disabled account",
+			identity:      staff,
+			existing:      &repository.User{ID: "user-1", Username: "cjones", Email: "casey@example.com", ExternalID: "idp-123", Role: "counsellor"},
+			expectedError: ErrAccountDisabled,
+		},
+		{
+			name:          "Failure - Unverified email",
+			identity:      func() oidctest.Identity { i := staff; i.EmailVerified = false; return i }(),
+			expectedError: ErrOIDCEmailUnverified,
+		},
+		{
+			name:          "Failure - Nonce mismatch",
+			identity:      staff,
+			modifyClaims:  func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+			expectedError: ErrOIDCInvalidIDToken,
+		},
+		{
+			name:          "Failure - Wrong audience",
+			identity:      staff,
+			modifyClaims:  func(c jwt.MapClaims) { c["aud"] = "another-app" },
+			expectedError: ErrOIDCInvalidIDToken,
+		},
+		{
+			name:          "Failure - Wrong issuer",
+			identity:      staff,
+			modifyClaims:  func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			expectedError: ErrOIDCInvalidIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t, tt.identity)
+			idp.ModifyClaims = tt.modifyClaims
+
+			repo := &MemoryUserRepository{users: map[string]*repository.User{}}
+			if tt.existing != nil {
+				repo.users[tt.existing.Email] = tt.existing
+			}
+			svc := NewOIDCService(OIDCConfig{
+				IssuerURL:   idp.Issuer,
+				ClientID:    "john-ai",
+				RedirectURL: "http://localhost:8081/api/auth/oidc/callback",
+				GroupRoles:  map[string]string{"counsellors": "counsellor", "it-admins": "admin"},
+				DefaultRole: tt.defaultRole,
+			}, repo, stubTokenIssuer{}, "flow-secret")
+
+			token, user, err := oidcLogin(t, svc)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected %v, got: %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if token != "app-token-"+user.ID {
+				t.Errorf("Unexpected token %q", token)
+			}
+			stored := repo.users["casey@example.com"]
+			if stored == nil || stored.Role != tt.wantRole || stored.ExternalID != "idp-123" || stored.AuthProvider != repository.AuthProviderOIDC {
+				t.Errorf("Unexpected stored user: %+v", stored)
+			}
+			if len(stored.Username) < len(tt.wantUsername) || stored.Username[:len(tt.wantUsername)] != tt.wantUsername {
+				t.Errorf("Expected username starting %q, got %q", tt.wantUsername, stored.Username)
+			}
+			if tt.existing == nil && stored.PasswordHash != "" {
+				t.Error("SSO users must not get a password")
+			}
+		})
+	}
+}
+
+func TestOIDCService_CompleteLogin_RejectsBadFlow(t *testing.T) {
+	idp := newTestIdP(t, oidctest.Identity{Subject: "s", Email: "a@example.com"})
+	svc := NewOIDCService(OIDCConfig{IssuerURL: idp.Issuer, ClientID: "john-ai", RedirectURL: "http://localhost/cb"},
+		&MemoryUserRepository{users: map[string]*repository.User{}}, stubTokenIssuer{}, "flow-secret")
+
+	_, flow, err := svc.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+
+	if _, _, err := svc.CompleteLogin(context.Background(), flow, "forged-state", "code"); !errors.Is(err, ErrOIDCStateMismatch) {
+		t.Errorf("Expected ErrOIDCStateMismatch, got: %v", err)
+	}
+	if _, _, err := svc.CompleteLogin(context.Background(), "not-a-flow", "state", "code"); !errors.Is(err, ErrOIDCLoginExpired) {
+		t.Errorf("Expected ErrOIDCLoginExpired, got: %v", err)
+	}
+
+	other := NewOIDCService(OIDCConfig{IssuerURL: idp.Issuer, ClientID: "john-ai"}, nil, stubTokenIssuer{}, "another-secret")
+	if _, _, err := other.CompleteLogin(context.Background(), flow, "state", "code"); !errors.Is(err, ErrOIDCLoginExpired) {
+		t.Errorf("Expected ErrOIDCLoginExpired for flow signed with another secret, got: %v", err)
+	}
+}
+
+func TestParseGroupRoles(t *testing.T) {
+	roles, err := ParseGroupRoles("it-admins=admin, counsellors = counsellor,")
+	if err != nil {
+		t.Fatalf("ParseGroupRoles failed: %v", err)
+	}
+	if roles["it-admins"] != "admin" || roles["counsellors"] != "counsellor" || len(roles) != 2 {
+		t.Errorf("Unexpected roles: %v", roles)
+	}
+
+	for _, spec := range []string{"admins", "admins=superuser", "=admin"} {
+		if _, err := ParseGroupRoles(spec); !errors.Is(err, ErrOIDCInvalidGroupMap) {
+			t.Errorf("%q: expected ErrOIDCInvalidGroupMap, got: %v", spec, err)
+		}
+	}
+}
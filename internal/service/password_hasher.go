@@ -0,0 +1,182 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownPasswordHash is returned by Verify for a stored hash the hasher cannot read.
+var ErrUnknownPasswordHash = errors.New("unrecognised password hash format")
+
+// PasswordHasher hashes passwords for storage. The encoded hash names its algorithm and
+// parameters, so hashes written by other hashers or older settings can still be verified.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded, or returns ErrUnknownPasswordHash.
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash reports whether encoded was not produced with this hasher's current settings.
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idParams are the Argon2id cost parameters. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the OWASP minimum recommendation (19 MiB, 2 passes).
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher stores hashes in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params != h.params
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// BcryptHasher stores standard $2a$/$2b$ bcrypt hashes, which carry their own cost.
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	if _, err := bcrypt.Cost([]byte(encoded)); err != nil {
+		return false, ErrUnknownPasswordHash
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+// PasswordHashers hashes new passwords with the preferred hasher and verifies hashes written
+// by any of the configured hashers. Anything the preferred hasher did not produce with its
+// current settings needs a rehash.
+type PasswordHashers struct {
+	preferred PasswordHasher
+	accepted  []PasswordHasher
+}
+
+func NewPasswordHashers(preferred PasswordHasher, accepted ...PasswordHasher) *PasswordHashers {
+	return &PasswordHashers{
+		preferred: preferred,
+		accepted:  append([]PasswordHasher{preferred}, accepted...),
+	}
+}
+
+// DefaultPasswordHasher hashes with Argon2id and still accepts bcrypt hashes from before the
+// switch, upgrading them at the next login.
+func DefaultPasswordHasher() *PasswordHashers {
+	return NewPasswordHashers(NewArgon2idHasher(DefaultArgon2idParams), NewBcryptHasher(bcrypt.DefaultCost))
+}
+
+func (p *PasswordHashers) Hash(password string) (string, error) {
+	return p.preferred.Hash(password)
+}
+
+func (p *PasswordHashers) Verify(encoded, password string) (bool, error) {
+	for _, h := range p.accepted {
+		ok, err := h.Verify(encoded, password)
+		if errors.Is(err, ErrUnknownPasswordHash) {
+			continue
+		}
+		return ok, err
+	}
+	return false, ErrUnknownPasswordHash
+}
+
+func (p *PasswordHashers) NeedsRehash(encoded string) bool {
+	return p.preferred.NeedsRehash(encoded)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams keeps Argon2id cheap in tests
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHashers(t *testing.T) {
+	argon := NewArgon2idHasher(testArgon2idParams)
+	legacy := NewBcryptHasher(bcrypt.MinCost)
+	hashers := NewPasswordHashers(argon, legacy)
+
+	bcryptHash, err := legacy.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("bcrypt Hash failed: %v", err)
+	}
+	argonHash, err := hashers.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Expected PHC-encoded argon2id hash, got %q", argonHash)
+	}
+
+	tests := []struct {
+		name        string
+		hasher      PasswordHasher
+		encoded     string
+		password    string
+		expectMatch bool
+		expectErr   error
+		needsRehash bool
+	}{
+		{name: "Argon2id match", hasher: hashers, encoded: argonHash, password: "correct horse", expectMatch: true},
+		{name: "Argon2id mismatch", hasher: hashers, encoded: argonHash, password: "wrong horse"},
+		{name: "Legacy bcrypt match needs rehash", hasher: hashers, encoded: bcryptHash, password: "correct horse", expectMatch: true, needsRehash: true},
+		{name: "Legacy bcrypt mismatch", hasher: hashers, encoded: bcryptHash, password: "wrong horse", needsRehash: true},
+		{name: "Argon2id with stronger parameters needs rehash", hasher: NewArgon2idHasher(DefaultArgon2idParams), encoded: argonHash, password: "correct horse", expectMatch: true, needsRehash: true},
+		{name: "Bcrypt with higher cost needs rehash", hasher: NewBcryptHasher(bcrypt.MinCost + 1), encoded: bcryptHash, password: "correct horse", expectMatch: true, needsRehash: true},
+		{name: "Bcrypt hasher rejects argon2id hash", hasher: legacy, encoded: argonHash, password: "correct horse", expectErr: ErrUnknownPasswordHash, needsRehash: true},
+		{name: "Empty hash", hasher: hashers, encoded: "", password: "correct horse", expectErr: ErrUnknownPasswordHash, needsRehash: true},
+		{name: "Malformed argon2id hash", hasher: hashers, encoded: "$argon2id$v=19$m=x$salt$key", password: "correct horse", expectErr: ErrUnknownPasswordHash, needsRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.hasher.Verify(tt.encoded, tt.password)
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("Expected error %v, got: %v", tt.expectErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if ok != tt.expectMatch {
+				t.Errorf("Expected match=%v, got %v", tt.expectMatch, ok)
+			}
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.needsRehash {
+				t.Errorf("Expected NeedsRehash=%v, got %v", tt.needsRehash, got)
+			}
+		})
+	}
+}
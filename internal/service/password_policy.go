@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/jmason/john_ai_project/internal/bloom"
+	"github.com/jmason/john_ai_project/internal/breached"
+)
+
+// Password rules reported in PasswordViolation.Rule
+const (
+	PasswordRuleMinLength        = "min_length"
+	PasswordRuleStrength         = "strength"
+	PasswordRuleContainsUsername = "contains_username"
+	PasswordRuleContainsEmail    = "contains_email"
+	PasswordRuleBreached         = "breached"
+)
+
+// personalInfoMinLength keeps very short usernames (e.g. "al") from rejecting most passwords.
+const personalInfoMinLength = 3
+
+// PasswordViolation is one password rule a password failed.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password failed. It wraps ErrAuthInvalidPassword, so
+// callers that only need to know the password was rejected can keep using errors.Is.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrAuthInvalidPassword.WithFields(map[string]string{"password": e.Error()})
+}
+
+// BreachedPasswords reports whether a password appears in a list of known-compromised passwords.
+type BreachedPasswords interface {
+	Contains(password string) bool
+}
+
+// PasswordPolicy decides which new passwords are accepted. Zero values disable a rule.
+type PasswordPolicy struct {
+	MinLength int
+	// MinEntropyBits is the minimum strength estimated by PasswordEntropy.
+	MinEntropyBits float64
+	// RejectPersonalInfo refuses passwords containing the username or the email address.
+	RejectPersonalInfo bool
+	Breached           BreachedPasswords
+}
+
+// BasicPasswordPolicy is the original rule: at least 8 characters.
+func BasicPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: 8}
+}
+
+// DefaultPasswordPolicy applies every rule, checking the bundled breached-password list.
+func DefaultPasswordPolicy() (PasswordPolicy, error) {
+	list, err := BundledBreachedPasswords()
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+	return PasswordPolicy{
+		MinLength:          8,
+		MinEntropyBits:     40,
+		RejectPersonalInfo: true,
+		Breached:           list,
+	}, nil
+}
+
+type bloomBreachedPasswords struct {
+	filter *bloom.Filter
+}
+
+func (b bloomBreachedPasswords) Contains(password string) bool {
+	return b.filter.Contains(breached.Normalize(password))
+}
+
+// BundledBreachedPasswords checks passwords against the Bloom filter shipped in
+// internal/breached. A false positive rejects a safe password; a breached one is never missed.
+func BundledBreachedPasswords() (BreachedPasswords, error) {
+	filter, err := breached.Filter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load breached password list: %w", err)
+	}
+	return bloomBreachedPasswords{filter: filter}, nil
+}
+
+// Check returns nil if password satisfies the policy, or a *PasswordPolicyError listing every
+// failed rule.
+func (p PasswordPolicy) Check(password, username, email string) error {
+	var violations []PasswordViolation
+	add := func(rule, message string) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: message})
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		add(PasswordRuleMinLength, fmt.Sprintf("password must be at least %d characters long", p.MinLength))
+	}
+	if p.MinEntropyBits > 0 && PasswordEntropy(password) < p.MinEntropyBits {
+		add(PasswordRuleStrength, "password is too easy to guess: use a longer password or mix letters, digits and symbols")
+	}
+	if p.RejectPersonalInfo {
+		lower := strings.ToLower(password)
+		if containsPersonal(lower, username) {
+			add(PasswordRuleContainsUsername, "password must not contain your username")
+		}
+		local, _, _ := strings.Cut(email, "@")
+		if containsPersonal(lower, email) || containsPersonal(lower, local) {
+			add(PasswordRuleContainsEmail, "password must not contain your email address")
+		}
+	}
+	if p.Breached != nil && password != "" && p.Breached.Contains(password) {
+		add(PasswordRuleBreached, "password appears in a list of common or breached passwords")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsPersonal(lowerPassword, value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return len(value) >= personalInfoMinLength && strings.Contains(lowerPassword, value)
+}
+
+// PasswordEntropy estimates the strength of a password in bits: each character is worth
+// log2 of the size of the character classes used, except that repeats and sequential steps
+// ("aa", "123", "cba") are worth a single bit.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	perChar := math.Log2(float64(pool))
+	bits := 0.0
+	var prev rune
+	for i, r := range password {
+		if i > 0 && (r == prev || r == prev+1 || r == prev-1) {
+			bits++
+		} else {
+			bits += perChar
+		}
+		prev = r
+	}
+	return bits
+}
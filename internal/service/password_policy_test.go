@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy, err := DefaultPasswordPolicy()
+	if err != nil {
+		t.Fatalf("DefaultPasswordPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		policy        PasswordPolicy
+		password      string
+		expectedRules []string
+	}{
+		{name: "Success - Strong passphrase", policy: policy, password: "violet lantern orbit"},
+		{name: "Success - Mixed classes", policy: policy, password: "Tq8#vLm2!zR"},
+		{name: "Failure - Too short and weak", policy: policy, password: "ab1", expectedRules: []string{PasswordRuleMinLength, PasswordRuleStrength}},
+		{name: "Failure - Repeated characters", policy: policy, password: "aaaaaaaaaaaa", expectedRules: []string{PasswordRuleStrength}},
+		{name: "Failure - Breached password", policy: policy, password: "password123", expectedRules: []string{PasswordRuleBreached}},
+		{name: "Failure - Breached password in other case", policy: policy, password: "PASSWORD123", expectedRules: []string{PasswordRuleBreached}},
+		{name: "Failure - Contains username", policy: policy, password: "xx-JohnDoe-4817!", expectedRules: []string{PasswordRuleContainsUsername}},
+		{name: "Failure - Contains email local part", policy: policy, password: "jd.smith@9152-Q", expectedRules: []string{PasswordRuleContainsEmail}},
+		{name: "Failure - Every rule reported", policy: policy, password: "johndoe", expectedRules: []string{PasswordRuleMinLength, PasswordRuleStrength, PasswordRuleContainsUsername}},
+		{name: "Success - Basic policy only checks length", policy: BasicPasswordPolicy(), password: "password123"},
+		{name: "Failure - Basic policy length", policy: BasicPasswordPolicy(), password: "short", expectedRules: []string{PasswordRuleMinLength}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password, "johndoe", "jd.smith@example.com")
+
+			if len(tt.expectedRules) == 0 {
+				if err != nil {
+					t.Fatalf("Expected password to pass, got: %v", err)
+				}
+				return
+			}
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Expected *PasswordPolicyError, got: %v", err)
+			}
+			if !errors.Is(err, ErrAuthInvalidPassword) {
+				t.Error("Expected policy error to match ErrAuthInvalidPassword")
+			}
+			var rules []string
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.expectedRules) {
+				t.Errorf("Expected rules %v, got %v", tt.expectedRules, rules)
+			}
+		})
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	if weak, strong := PasswordEntropy("abcdefgh"), PasswordEntropy("qmzvtrkw"); weak >= strong {
+		t.Errorf("Expected sequential run to score lower: %f >= %f", weak, strong)
+	}
+	if lower, mixed := PasswordEntropy("qmzvtrkw"), PasswordEntropy("qMz7t!kw"); lower >= mixed {
+		t.Errorf("Expected mixed character classes to score higher: %f >= %f", lower, mixed)
+	}
+	if PasswordEntropy("") != 0 {
+		t.Error("Expected empty password to score 0")
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/metrics"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+)
+
+// rateLimitAttempts is how many times Allow retries when other requests keep changing the
+// bucket between its read and write.
+const rateLimitAttempts = 3
+
+// rateLimitRejections counts requests refused with 429.
+var rateLimitRejections = metrics.NewCounter("rate_limit_rejections_total",
+	"Requests refused by the rate limiter, by route group.", "group")
+
+// RateLimitStore interface for dependency injection: repository.NewMemoryRateLimitStore for a
+// single server, or repository.NewRateLimitRepository to share buckets across a fleet.
+type RateLimitStore interface {
+	GetRateLimitBucket(ctx context.Context, key string) (*repository.RateLimitBucket, error)
+	SaveRateLimitBucket(ctx context.Context, bucket *repository.RateLimitBucket, version int64) error
+}
+
+// RateLimit is a token bucket holding up to Requests tokens, refilled evenly over Period. Each
+// request takes one token, so a caller may burst up to Requests and then sustain Requests per
+// Period. The zero RateLimit is unlimited.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Unlimited reports whether l allows every request.
+func (l RateLimit) Unlimited() bool {
+	return l.Requests <= 0
+}
+
+func (l RateLimit) String() string {
+	if l.Unlimited() {
+		return ""
+	}
+	return strconv.Itoa(l.Requests) + "/" + l.Period.String()
+}
+
+// ParseRateLimit parses "<requests>/<period>", e.g. "100/1m". An empty string is unlimited.
+func ParseRateLimit(s string) (RateLimit, error) {
+	if s == "" {
+		return RateLimit{}, nil
+	}
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q must look like 100/1m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q must allow a positive number of requests", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d < time.Second {
+		return RateLimit{}, fmt.Errorf("rate limit %q must have a period of at least 1s, such as 1m", s)
+	}
+	return RateLimit{Requests: n, Period: d}, nil
+}
+
+// RateLimitDecision is the outcome of one request against a bucket.
+type RateLimitDecision struct {
+	Allowed bool
+	Limit   RateLimit
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed; zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimiter applies token-bucket limits per caller and route group.
+type RateLimiter struct {
+	store RateLimitStore
+	now   func() time.Time
+}
+
+func NewRateLimiter(store RateLimitStore) *RateLimiter {
+	return &RateLimiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of caller in group. Refused requests do not touch the
+// store beyond the read, so a caller hammering the API cannot turn it into a write storm.
+func (l *RateLimiter) Allow(ctx context.Context, group, caller string, limit RateLimit) (RateLimitDecision, error) {
+	ctx, span := tracing.Start(ctx, "RateLimiter.Allow")
+	defer span.End()
+
+	if limit.Unlimited() {
+		return RateLimitDecision{Allowed: true, Limit: limit}, nil
+	}
+
+	key := group + "#" + caller
+	for attempt := 0; attempt < rateLimitAttempts; attempt++ {
+		bucket, err := l.store.GetRateLimitBucket(ctx, key)
+		if errors.Is(err, repository.ErrRateLimitBucketNotFound) {
+			bucket = &repository.RateLimitBucket{Key: key, Tokens: float64(limit.Requests)}
+		} else if err != nil {
+			return RateLimitDecision{}, fmt.Errorf("failed to load rate limit bucket: %w", err)
+		}
+
+		now := l.now()
+		version := bucket.Version
+		decision := takeToken(bucket, limit, now)
+		if !decision.Allowed {
+			rateLimitRejections.Inc(group)
+			return decision, nil
+		}
+
+		bucket.Version = version + 1
+		bucket.TTL = now.Add(decision.Reset).Unix() + 1
+		err = l.store.SaveRateLimitBucket(ctx, bucket, version)
+		if err == nil {
+			return decision, nil
+		}
+		if !errors.Is(err, repository.ErrRateLimitBucketConflict) {
+			return RateLimitDecision{}, fmt.Errorf("failed to save rate limit bucket: %w", err)
+		}
+	}
+	return RateLimitDecision{}, fmt.Errorf("rate limit bucket %s kept changing after %d attempts", group, rateLimitAttempts)
+}
+
+// takeToken refills bucket for the time since it was last updated and takes one token if
+// there is one.
+func takeToken(bucket *repository.RateLimitBucket, limit RateLimit, now time.Time) RateLimitDecision {
+	capacity := float64(limit.Requests)
+	perMilli := capacity / float64(limit.Period.Milliseconds())
+	nowMilli := now.UnixMilli()
+
+	if elapsed := nowMilli - bucket.UpdatedAt; elapsed > 0 {
+		bucket.Tokens = math.Min(capacity, bucket.Tokens+float64(elapsed)*perMilli)
+	}
+	bucket.UpdatedAt = nowMilli
+
+	decision := RateLimitDecision{Limit: limit}
+	if bucket.Tokens >= 1 {
+		bucket.Tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = millis((1 - bucket.Tokens) / perMilli)
+	}
+	decision.Remaining = int(bucket.Tokens)
+	decision.Reset = millis((capacity - bucket.Tokens) / perMilli)
+	return decision
+}
+
+func millis(ms float64) time.Duration {
+	return time.Duration(math.Ceil(ms)) * time.Millisecond
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    RateLimit
+		wantErr bool
+	}{
+		{in: "", want: RateLimit{}},
+		{in: "100/1m", want: RateLimit{Requests: 100, Period: time.Minute}},
+		{in: " 5 / 10s ", want: RateLimit{Requests: 5, Period: 10 * time.Second}},
+		{in: "100", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "10/minute", wantErr: true},
+		{in: "10/1ms", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRateLimit(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRateLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRateLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	limit := RateLimit{Requests: 3, Period: 3 * time.Second}
+	// The memory store sweeps expired buckets by the wall clock, so the test clock starts there.
+	now := time.Now().Truncate(time.Second)
+	limiter := NewRateLimiter(repository.NewMemoryRateLimitStore())
+	limiter.now = func() time.Time { return now }
+
+	for i := 2; i >= 0; i-- {
+		d, err := limiter.Allow(ctx, "clients", "user:u-1", limit)
+		if err != nil || !d.Allowed {
+			t.Fatalf("Expected request %d of the burst to be allowed, got %+v, %v", 3-i, d, err)
+		}
+		if d.Remaining != i {
+			t.Errorf("Expected %d remaining, got %d", i, d.Remaining)
+		}
+	}
+
+	d, err := limiter.Allow(ctx, "clients", "user:u-1", limit)
+	if err != nil || d.Allowed {
+		t.Fatalf("Expected the fourth request to be refused, got %+v, %v", d, err)
+	}
+	if d.RetryAfter != time.Second || d.Reset != 3*time.Second {
+		t.Errorf("Expected retry after 1s and reset after 3s, got %v and %v", d.RetryAfter, d.Reset)
+	}
+
+	if d, _ := limiter.Allow(ctx, "clients", "user:u-2", limit); !d.Allowed {
+		t.Error("Expected another caller to have its own bucket")
+	}
+	if d, _ := limiter.Allow(ctx, "admin", "user:u-1", limit); !d.Allowed {
+		t.Error("Expected another group to have its own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if d, _ := limiter.Allow(ctx, "clients", "user:u-1", limit); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("Expected one token to refill after a second, got %+v", d)
+	}
+
+	if d, err := limiter.Allow(ctx, "clients", "user:u-1", RateLimit{}); err != nil || !d.Allowed {
+		t.Errorf("Expected an unlimited group to allow every request, got %+v, %v", d, err)
+	}
+}
+
+// conflictingRateLimitStore fails the first saves as if other servers changed the bucket.
+type conflictingRateLimitStore struct {
+	*repository.MemoryRateLimitStore
+	conflicts int
+}
+
+func (s *conflictingRateLimitStore) SaveRateLimitBucket(ctx context.Context, bucket *repository.RateLimitBucket, version int64) error {
+	if s.conflicts > 0 {
+		s.conflicts--
+		return repository.ErrRateLimitBucketConflict
+	}
+	return s.MemoryRateLimitStore.SaveRateLimitBucket(ctx, bucket, version)
+}
+
+func TestRateLimiter_RetriesConflicts(t *testing.T) {
+	ctx := context.Background()
+	limit := RateLimit{Requests: 10, Period: time.Minute}
+
+	store := &conflictingRateLimitStore{MemoryRateLimitStore: repository.NewMemoryRateLimitStore(), conflicts: 2}
+	if d, err := NewRateLimiter(store).Allow(ctx, "clients", "ip:203.0.113.7", limit); err != nil || !d.Allowed {
+		t.Errorf("Expected the request to be allowed after retrying, got %+v, %v", d, err)
+	}
+
+	store = &conflictingRateLimitStore{MemoryRateLimitStore: repository.NewMemoryRateLimitStore(), conflicts: rateLimitAttempts}
+	if _, err := NewRateLimiter(store).Allow(ctx, "clients", "ip:203.0.113.7", limit); err == nil || errors.Is(err, repository.ErrRateLimitBucketConflict) {
+		t.Errorf("Expected an error after %d conflicts, got %v", rateLimitAttempts, err)
+	}
+}
+
+func TestMemoryRateLimitStore_Versions(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRateLimitStore()
+	bucket := &repository.RateLimitBucket{Key: "clients#user:u-1", Tokens: 1, Version: 1, TTL: time.Now().Add(time.Hour).Unix()}
+
+	if err := store.SaveRateLimitBucket(ctx, bucket, 0); err != nil {
+		t.Fatalf("Expected the first save to succeed, got %v", err)
+	}
+	if err := store.SaveRateLimitBucket(ctx, bucket, 0); !errors.Is(err, repository.ErrRateLimitBucketConflict) {
+		t.Errorf("Expected creating an existing bucket to conflict, got %v", err)
+	}
+	next := *bucket
+	next.Version = 2
+	if err := store.SaveRateLimitBucket(ctx, &next, 1); err != nil {
+		t.Errorf("Expected a save at the current version to succeed, got %v", err)
+	}
+	if err := store.SaveRateLimitBucket(ctx, &next, 1); !errors.Is(err, repository.ErrRateLimitBucketConflict) {
+		t.Errorf("Expected a save at a stale version to conflict, got %v", err)
+	}
+}
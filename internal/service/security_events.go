@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmason/john_ai_project/internal/apperror"
+	"github.com/jmason/john_ai_project/internal/logger"
+	"github.com/jmason/john_ai_project/internal/repository"
+	"github.com/jmason/john_ai_project/internal/tracing"
+)
+
+// Security event errors
+var (
+	ErrSecurityEventsDisabled = apperror.NotImplemented("security_events_disabled", "security events are not enabled")
+	ErrSecurityEventFilter    = apperror.Validation("invalid_security_event_filter", "invalid security event filter")
+)
+
+// Security event types
+const (
+	SecurityEventLoginSucceeded       = "login_succeeded"
+	SecurityEventLoginFailed          = "login_failed"
+	SecurityEventPasswordChanged      = "password_changed"
+	SecurityEventEmailChanged         = "email_changed"
+	SecurityEventSessionRevoked       = "session_revoked"
+	SecurityEventImpersonationStarted = "impersonation_started"
+)
+
+var securityEventTypes = map[string]bool{
+	SecurityEventLoginSucceeded:       true,
+	SecurityEventLoginFailed:          true,
+	SecurityEventPasswordChanged:      true,
+	SecurityEventEmailChanged:         true,
+	SecurityEventSessionRevoked:       true,
+	SecurityEventImpersonationStarted: true,
+}
+
+// Reasons recorded on failed logins
+const (
+	LoginFailureUnknownUser     = "unknown_user"
+	LoginFailureBadPassword     = "bad_password"
+	LoginFailureAccountDisabled = "account_disabled"
+)
+
+// Login methods
+const (
+	LoginMethodPassword = "password"
+	LoginMethodOIDC     = "oidc"
+)
+
+const (
+	// securityEventRetention is how long DynamoDB keeps an event before its TTL removes it.
+	securityEventRetention    = 365 * 24 * time.Hour
+	defaultSecurityEventLimit = 50
+	maxSecurityEventLimit     = 500
+)
+
+// SecurityEventRepository interface for dependency injection
+type SecurityEventRepository interface {
+	CreateSecurityEvent(ctx context.Context, event *repository.SecurityEvent) error
+	ListSecurityEvents(ctx context.Context, filter repository.SecurityEventFilter) ([]repository.SecurityEvent, error)
+}
+
+// SecurityEventRecorder records security events. AuthService implements it.
+type SecurityEventRecorder interface {
+	RecordSecurityEvent(ctx context.Context, event repository.SecurityEvent)
+}
+
+// SecurityEventQuery is an admin search over security events. Since and Until are RFC 3339.
+type SecurityEventQuery struct {
+	UserID string
+	Type   string
+	Since  string
+	Until  string
+	Limit  int
+}
+
+// WithSecurityEvents stores logins, password changes and session revocations so users and
+// admins can review them.
+func WithSecurityEvents(events SecurityEventRepository) AuthOption {
+	return func(s *AuthService) {
+		s.securityEvents = events
+	}
+}
+
+// RecordSecurityEvent stores event, filling in its id, time and the request details from
+// WithSessionMetadata. Failures are logged only, so recording never breaks the operation.
+// Logins are also counted in auth_logins_total, whether or not events are stored.
+func (s *AuthService) RecordSecurityEvent(ctx context.Context, event repository.SecurityEvent) {
+	ctx, span := tracing.Start(ctx, "AuthService.RecordSecurityEvent")
+	defer span.End()
+
+	switch event.Type {
+	case SecurityEventLoginSucceeded:
+		loginAttempts.Inc(event.Method, "success", "")
+	case SecurityEventLoginFailed:
+		loginAttempts.Inc(event.Method, "failure", event.Reason)
+	}
+	if s.securityEvents == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	meta := sessionMetadataFromContext(ctx)
+	event.ID = uuid.New().String()
+	event.CreatedAt = now.Format(time.RFC3339)
+	event.TTL = now.Add(securityEventRetention).Unix()
+	if event.IPAddress == "" {
+		event.IPAddress = meta.IPAddress
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = meta.UserAgent
+	}
+
+	if err := s.securityEvents.CreateSecurityEvent(ctx, &event); err != nil {
+		authLog.ErrorContext(ctx, "failed to record security event", "event_type", event.Type, "subject_user_id", event.UserID, logger.Error(err))
+	}
+}
+
+// ListUserSecurityEvents returns the user's own security events, newest first.
+func (s *AuthService) ListUserSecurityEvents(ctx context.Context, userID string, limit int) ([]repository.SecurityEvent, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ListUserSecurityEvents")
+	defer span.End()
+
+	return s.ListSecurityEvents(ctx, SecurityEventQuery{UserID: userID, Limit: limit})
+}
+
+// ListSecurityEvents returns the events matching query across all users, newest first.
+func (s *AuthService) ListSecurityEvents(ctx context.Context, query SecurityEventQuery) ([]repository.SecurityEvent, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ListSecurityEvents")
+	defer span.End()
+
+	if s.securityEvents == nil {
+		return nil, ErrSecurityEventsDisabled
+	}
+
+	filter, err := query.filter()
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.securityEvents.ListSecurityEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+	if events == nil {
+		events = []repository.SecurityEvent{}
+	}
+	return events, nil
+}
+
+// filter validates the query and converts its times to UTC, the zone events are stored in, so
+// they compare correctly.
+func (q SecurityEventQuery) filter() (repository.SecurityEventFilter, error) {
+	filter := repository.SecurityEventFilter{UserID: q.UserID, Type: q.Type, Limit: q.Limit}
+	if q.Type != "" && !securityEventTypes[q.Type] {
+		return filter, fmt.Errorf("%w: unknown type %q", ErrSecurityEventFilter, q.Type)
+	}
+	switch {
+	case q.Limit < 0 || q.Limit > maxSecurityEventLimit:
+		return filter, fmt.Errorf("%w: limit must be between 1 and %d", ErrSecurityEventFilter, maxSecurityEventLimit)
+	case q.Limit == 0:
+		filter.Limit = defaultSecurityEventLimit
+	}
+
+	var err error
+	if filter.Since, err = utcTimestamp(q.Since); err != nil {
+		return filter, fmt.Errorf("%w: since must be an RFC 3339 time", ErrSecurityEventFilter)
+	}
+	if filter.Until, err = utcTimestamp(q.Until); err != nil {
+		return filter, fmt.Errorf("%w: until must be an RFC 3339 time", ErrSecurityEventFilter)
+	}
+	if filter.Since != "" && filter.Until != "" && filter.Since > filter.Until {
+		return filter, fmt.Errorf("%w: since must not be after until", ErrSecurityEventFilter)
+	}
+	return filter, nil
+}
+
+func utcTimestamp(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
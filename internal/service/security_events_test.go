@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmason/john_ai_project/internal/oidctest"
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+// MemorySecurityEventRepository keeps events in insertion order and records the last filter
+type MemorySecurityEventRepository struct {
+	events     []repository.SecurityEvent
+	lastFilter repository.SecurityEventFilter
+	err        error
+}
+
+func (m *MemorySecurityEventRepository) CreateSecurityEvent(ctx context.Context, event *repository.SecurityEvent) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, *event)
+	return nil
+}
+
+func (m *MemorySecurityEventRepository) ListSecurityEvents(ctx context.Context, filter repository.SecurityEventFilter) ([]repository.SecurityEvent, error) {
+	m.lastFilter = filter
+	var out []repository.SecurityEvent
+	for i := len(m.events) - 1; i >= 0; i-- {
+		event := m.events[i]
+		if (filter.UserID == "" || event.UserID == filter.UserID) && (filter.Type == "" || event.Type == filter.Type) {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemorySecurityEventRepository) types() []string {
+	out := make([]string, len(m.events))
+	for i, event := range m.events {
+		out[i] = event.Type
+	}
+	return out
+}
+
+func TestAuthService_Login_RecordsSecurityEvents(t *testing.T) {
+	tests := []struct {
+		name       string
+		login      string
+		password   string
+		wantType   string
+		wantUserID string
+		wantReason string
+	}{
+		{name: "Success", login: "casey", password: "old-password", wantType: SecurityEventLoginSucceeded, wantUserID: "user-1"},
+		{name: "Failure - Wrong password", login: "casey@example.com", password: "wrong-password", wantType: SecurityEventLoginFailed, wantUserID: "user-1", wantReason: LoginFailureBadPassword},
+		{name: "Failure - Unknown user", login: "nobody", password: "whatever", wantType: SecurityEventLoginFailed, wantReason: LoginFailureUnknownUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, _, _ := newAccountTestService(t)
+			events := &MemorySecurityEventRepository{}
+			WithSecurityEvents(events)(svc)
+
+			ctx := WithSessionMetadata(context.Background(), SessionMetadata{UserAgent: "test-agent", IPAddress: "203.0.113.7"})
+			svc.Login(ctx, tt.login, tt.password)
+
+			if len(events.events) != 1 {
+				t.Fatalf("Expected 1 event, got %v", events.types())
+			}
+			got := events.events[0]
+			if got.Type != tt.wantType || got.UserID != tt.wantUserID || got.Reason != tt.wantReason {
+				t.Errorf("Unexpected event: %+v", got)
+			}
+			if got.Method != LoginMethodPassword || got.IPAddress != "203.0.113.7" || got.UserAgent != "test-agent" {
+				t.Errorf("Expected request details on event, got %+v", got)
+			}
+			if got.ID == "" || got.CreatedAt == "" || got.TTL == 0 {
+				t.Errorf("Expected id, time and ttl to be set, got %+v", got)
+			}
+			if tt.wantType == SecurityEventLoginSucceeded && got.SessionID == "" {
+				t.Error("Expected session id on successful login")
+			}
+			if tt.wantReason == LoginFailureUnknownUser && got.Login != tt.login {
+				t.Errorf("Expected attempted login %q, got %q", tt.login, got.Login)
+			}
+		})
+	}
+}
+
+func TestAuthService_RecordSecurityEvent_FailureDoesNotBreakLogin(t *testing.T) {
+	svc, _, _, _ := newAccountTestService(t)
+	WithSecurityEvents(&MemorySecurityEventRepository{err: errors.New("table missing")})(svc)
+
+	if _, _, err := svc.Login(context.Background(), "casey", "old-password"); err != nil {
+		t.Fatalf("Expected login to succeed, got: %v", err)
+	}
+}
+
+func TestAuthService_ChangePassword_RecordsSecurityEvents(t *testing.T) {
+	svc, users, _, _ := newAccountTestService(t)
+	events := &MemorySecurityEventRepository{}
+	WithSecurityEvents(events)(svc)
+
+	current := issueSession(t, svc, users, "casey@example.com")
+	issueSession(t, svc, users, "casey@example.com")
+
+	if err := svc.ChangePassword(context.Background(), "user-1", current.ID, "old-password", "new-password-1"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+
+	got := events.types()
+	want := []string{SecurityEventPasswordChanged, SecurityEventSessionRevoked}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected events %v, got %v", want, got)
+	}
+	if events.events[1].SessionID == current.ID {
+		t.Error("The current session must not be revoked")
+	}
+}
+
+func TestAuthService_ListSecurityEvents(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         SecurityEventQuery
+		wantFilter    repository.SecurityEventFilter
+		expectedError error
+	}{
+		{
+			name:       "Success - Default limit",
+			query:      SecurityEventQuery{UserID: "user-1"},
+			wantFilter: repository.SecurityEventFilter{UserID: "user-1", Limit: defaultSecurityEventLimit},
+		},
+		{
+			name:       "Success - Times converted to UTC",
+			query:      SecurityEventQuery{Type: SecurityEventLoginFailed, Since: "2026-01-01T09:00:00+02:00", Until: "2026-01-02T00:00:00Z", Limit: 10},
+			wantFilter: repository.SecurityEventFilter{Type: SecurityEventLoginFailed, Since: "2026-01-01T07:00:00Z", Until: "2026-01-02T00:00:00Z", Limit: 10},
+		},
+		{name: "Failure - Unknown type", query: SecurityEventQuery{Type: "logout"}, expectedError: ErrSecurityEventFilter},
+		{name: "Failure - Bad since", query: SecurityEventQuery{Since: "yesterday"}, expectedError: ErrSecurityEventFilter},
+		{name: "Failure - Since after until", query: SecurityEventQuery{Since: "2026-02-01T00:00:00Z", Until: "2026-01-01T00:00:00Z"}, expectedError: ErrSecurityEventFilter},
+		{name: "Failure - Limit too large", query: SecurityEventQuery{Limit: maxSecurityEventLimit + 1}, expectedError: ErrSecurityEventFilter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &MemorySecurityEventRepository{}
+			svc := NewAuthService(&MockUserRepository{}, "test-jwt-secret", WithSecurityEvents(events))
+
+			got, err := svc.ListSecurityEvents(context.Background(), tt.query)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got == nil {
+				t.Error("Expected an empty list, not nil")
+			}
+			if events.lastFilter != tt.wantFilter {
+				t.Errorf("Expected filter %+v, got %+v", tt.wantFilter, events.lastFilter)
+			}
+		})
+	}
+
+	t.Run("Failure - Disabled", func(t *testing.T) {
+		svc := NewAuthService(&MockUserRepository{}, "test-jwt-secret")
+		if _, err := svc.ListUserSecurityEvents(context.Background(), "user-1", 0); !errors.Is(err, ErrSecurityEventsDisabled) {
+			t.Errorf("Expected ErrSecurityEventsDisabled, got %v", err)
+		}
+	})
+}
+
+func TestOIDCService_Login_RecordsMFA(t *testing.T) {
+	tests := []struct {
+		name    string
+		amr     []string
+		wantMFA bool
+	}{
+		{name: "Password only", amr: []string{"pwd"}, wantMFA: false},
+		{name: "One-time password", amr: []string{"pwd", "otp"}, wantMFA: true},
+		{name: "No amr claim", amr: nil, wantMFA: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t, oidctest.Identity{Subject: "idp-123", Email: "casey@example.com", EmailVerified: true, Groups: []string{"counsellors"}})
+			idp.ModifyClaims = func(c jwt.MapClaims) {
+				if tt.amr != nil {
+					c["amr"] = tt.amr
+				}
+			}
+			events := &MemorySecurityEventRepository{}
+			recorder := NewAuthService(&MockUserRepository{}, "test-jwt-secret", WithSecurityEvents(events))
+			svc := NewOIDCService(OIDCConfig{
+				IssuerURL:   idp.Issuer,
+				ClientID:    "john-ai",
+				RedirectURL: "http://localhost/cb",
+				GroupRoles:  map[string]string{"counsellors": "counsellor"},
+			}, &MemoryUserRepository{users: map[string]*repository.User{}}, stubTokenIssuer{}, "flow-secret", WithOIDCSecurityEvents(recorder))
+
+			if _, _, err := oidcLogin(t, svc); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(events.events) != 1 {
+				t.Fatalf("Expected 1 event, got %v", events.types())
+			}
+			got := events.events[0]
+			if got.Type != SecurityEventLoginSucceeded || got.Method != LoginMethodOIDC || got.MFA != tt.wantMFA {
+				t.Errorf("Unexpected event: %+v", got)
+			}
+		})
+	}
+}
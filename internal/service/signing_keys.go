@@ -0,0 +1,398 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Signing key errors
+var (
+	ErrUnknownKeyID     = errors.New("unknown signing key id")
+	ErrKeyCannotSign    = errors.New("signing key has no private key")
+	ErrRetireActiveKey  = errors.New("cannot retire the active signing key")
+	ErrUnsupportedKey   = errors.New("unsupported key type: use RSA or ECDSA P-256/P-384")
+	ErrSigningKeyExists = errors.New("signing key id already registered")
+)
+
+// TokenSigner signs and verifies access tokens. KeySet is the implementation used by the
+// server; tests and future KMS-backed signers can supply their own.
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	JWKS() JWKS
+}
+
+// SigningKey is a single JWT key identified by kid. A key without a private half can only
+// verify, which is how retired keys are kept around until the tokens they signed expire.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACSigningKey creates an HS256 key. HMAC keys are never published in the JWKS.
+func NewHMACSigningKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        kid,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// NewRSASigningKey creates an RS256 key from an RSA private key.
+func NewRSASigningKey(kid string, key *rsa.PrivateKey) *SigningKey {
+	return &SigningKey{
+		ID:        kid,
+		Method:    jwt.SigningMethodRS256,
+		signKey:   key,
+		verifyKey: &key.PublicKey,
+	}
+}
+
+// NewECDSASigningKey creates an ES256 (P-256) or ES384 (P-384) key from an ECDSA private key.
+func NewECDSASigningKey(kid string, key *ecdsa.PrivateKey) (*SigningKey, error) {
+	method, err := ecdsaMethod(key.Curve)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:        kid,
+		Method:    method,
+		signKey:   key,
+		verifyKey: &key.PublicKey,
+	}, nil
+}
+
+// ParseSigningKeyPEM reads an RSA or ECDSA key from PEM. Private keys (PKCS#1, PKCS#8 or SEC 1)
+// produce a key that can sign; a PKIX public key produces a verify-only key.
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM block found", kid)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %s: unsupported PEM block %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", kid, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return NewRSASigningKey(kid, k), nil
+	case *ecdsa.PrivateKey:
+		return NewECDSASigningKey(kid, k)
+	case *rsa.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case *ecdsa.PublicKey:
+		method, err := ecdsaMethod(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: kid, Method: method, verifyKey: k}, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// CanSign reports whether the key has a private half.
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+func ecdsaMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// KeySet signs with one active key and verifies with every registered key. Rotating to a new
+// key keeps the old one for verification so tokens issued before the rotation stay valid.
+type KeySet struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+	// legacy verifies tokens issued before tokens carried a kid header.
+	legacy *SigningKey
+}
+
+// NewKeySet creates a key set that signs with active. Additional keys are accepted for
+// verification only. The first HMAC key also verifies tokens that have no kid.
+func NewKeySet(active *SigningKey, verifyOnly ...*SigningKey) (*KeySet, error) {
+	if active == nil || !active.CanSign() {
+		return nil, ErrKeyCannotSign
+	}
+	ks := &KeySet{
+		active: active,
+		keys:   map[string]*SigningKey{},
+	}
+	for _, k := range append([]*SigningKey{active}, verifyOnly...) {
+		if _, exists := ks.keys[k.ID]; exists {
+			return nil, fmt.Errorf("%w: %s", ErrSigningKeyExists, k.ID)
+		}
+		ks.keys[k.ID] = k
+		if ks.legacy == nil && k.Method == jwt.SigningMethodHS256 {
+			ks.legacy = k
+		}
+	}
+	return ks, nil
+}
+
+// ActiveKeyID returns the kid that new tokens are signed with.
+func (ks *KeySet) ActiveKeyID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active.ID
+}
+
+// Rotate makes next the active signing key. The previous active key remains valid for
+// verification until it is retired.
+func (ks *KeySet) Rotate(next *SigningKey) error {
+	if next == nil || !next.CanSign() {
+		return ErrKeyCannotSign
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if existing, ok := ks.keys[next.ID]; ok && existing != next {
+		return fmt.Errorf("%w: %s", ErrSigningKeyExists, next.ID)
+	}
+	ks.keys[next.ID] = next
+	ks.active = next
+	return nil
+}
+
+// Retire stops accepting tokens signed by kid.
+func (ks *KeySet) Retire(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.active.ID == kid {
+		return ErrRetireActiveKey
+	}
+	k, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+	delete(ks.keys, kid)
+	if ks.legacy == k {
+		ks.legacy = nil
+	}
+	return nil
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.active
+	ks.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc resolves the verification key for token by its kid header. The token's alg must
+// match the key's algorithm, so an RSA public key can never be used as an HMAC secret.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var key *SigningKey
+	if kid, _ := token.Header["kid"].(string); kid != "" {
+		key = ks.keys[kid]
+		if key == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+		}
+	} else {
+		key = ks.legacy
+		if key == nil {
+			return nil, fmt.Errorf("token has no kid header")
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// ECDSA
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every asymmetric key, sorted by kid. HMAC secrets are
+// never published, so a key set with only HMAC keys returns an empty list.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: k.ID,
+				Use: "sig",
+				Alg: k.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			set.Keys = append(set.Keys, JWK{
+				Kty: "EC",
+				Kid: k.ID,
+				Use: "sig",
+				Alg: k.Method.Alg(),
+				Crv: pub.Curve.Params().Name,
+				X:   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+				Y:   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// verifyKeyFromJWK converts a published RSA or EC key into a verify-only SigningKey. A key
+// without an alg is assumed to be RS256 (RSA) or chosen from its curve (EC).
+func verifyKeyFromJWK(k JWK) (*SigningKey, error) {
+	decode := func(field, v string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("key %s: invalid %s", k.Kid, field)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	var key *SigningKey
+	switch k.Kty {
+	case "RSA":
+		n, err := decode("n", k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode("e", k.E)
+		if err != nil {
+			return nil, err
+		}
+		key = &SigningKey{ID: k.Kid, Method: jwt.SigningMethodRS256, verifyKey: &rsa.PublicKey{N: n, E: int(e.Int64())}}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode("y", k.Y)
+		if err != nil {
+			return nil, err
+		}
+		method, _ := ecdsaMethod(curve)
+		key = &SigningKey{ID: k.Kid, Method: method, verifyKey: &ecdsa.PublicKey{Curve: curve, X: x, Y: y}}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	if k.Alg != "" {
+		method := jwt.GetSigningMethod(k.Alg)
+		if method == nil {
+			return nil, fmt.Errorf("key %s: unsupported alg %q", k.Kid, k.Alg)
+		}
+		key.Method = method
+	}
+	return key, nil
+}
+
+// LoadSigningKeysDir reads every *.pem file in dir as a signing key named after the file
+// (alice-2026.pem has kid "alice-2026"). activeKID selects the signing key; all other keys,
+// including public-only *.pem files for retired keys, are verify-only.
+func LoadSigningKeysDir(dir, activeKID string) (*SigningKey, []*SigningKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+
+	var active *SigningKey
+	var others []*SigningKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := ParseSigningKeyPEM(kid, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if kid == activeKID {
+			active = key
+		} else {
+			others = append(others, key)
+		}
+	}
+
+	if active == nil {
+		return nil, nil, fmt.Errorf("%w: %s not found in %s", ErrUnknownKeyID, activeKID, dir)
+	}
+	if !active.CanSign() {
+		return nil, nil, fmt.Errorf("key %s: %w", activeKID, ErrKeyCannotSign)
+	}
+	return active, others, nil
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmason/john_ai_project/internal/repository"
+)
+
+func newTestRSAKey(t *testing.T, kid string) *SigningKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return NewRSASigningKey(kid, key)
+}
+
+func newTestECKey(t *testing.T, kid string) *SigningKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	sk, err := NewECDSASigningKey(kid, key)
+	if err != nil {
+		t.Fatalf("NewECDSASigningKey failed: %v", err)
+	}
+	return sk
+}
+
+func testUser() *repository.User {
+	return &repository.User{ID: "user-123", Username: "johndoe", Email: "john@example.com", Role: "user"}
+}
+
+func TestKeySet_SignAndValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     func(t *testing.T) *SigningKey
+		wantAlg string
+	}{
+		{name: "HS256", key: func(t *testing.T) *SigningKey { return NewHMACSigningKey("hs", []byte("secret")) }, wantAlg: "HS256"},
+		{name: "RS256", key: func(t *testing.T) *SigningKey { return newTestRSAKey(t, "rs") }, wantAlg: "RS256"},
+		{name: "ES256", key: func(t *testing.T) *SigningKey { return newTestECKey(t, "es") }, wantAlg: "ES256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key(t)
+			ks, err := NewKeySet(key)
+			if err != nil {
+				t.Fatalf("NewKeySet failed: %v", err)
+			}
+			svc := NewAuthService(&MockUserRepository{}, "unused", WithTokenSigner(ks))
+
+			token, err := svc.GenerateToken(testUser())
+			if err != nil {
+				t.Fatalf("GenerateToken failed: %v", err)
+			}
+
+			parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified failed: %v", err)
+			}
+			if parsed.Header["kid"] != key.ID || parsed.Header["alg"] != tt.wantAlg {
+				t.Errorf("Expected kid=%s alg=%s, got header %v", key.ID, tt.wantAlg, parsed.Header)
+			}
+
+			claims, err := svc.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken failed: %v", err)
+			}
+			if claims.UserID != "user-123" {
+				t.Errorf("Expected user-123, got %s", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestKeySet_RotationKeepsInFlightTokensValid(t *testing.T) {
+	oldKey := newTestRSAKey(t, "2026-01")
+	ks, err := NewKeySet(oldKey)
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+	svc := NewAuthService(&MockUserRepository{}, "unused", WithTokenSigner(ks))
+
+	oldToken, err := svc.GenerateToken(testUser())
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+
+	if err := ks.Rotate(newTestECKey(t, "2026-02")); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if ks.ActiveKeyID() != "2026-02" {
+		t.Errorf("Expected active key 2026-02, got %s", ks.ActiveKeyID())
+	}
+
+	newToken, err := svc.GenerateToken(testUser())
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := svc.ValidateToken(token); err != nil {
+			t.Errorf("Expected %s token to validate after rotation, got: %v", name, err)
+		}
+	}
+
+	if err := ks.Retire("2026-02"); !errors.Is(err, ErrRetireActiveKey) {
+		t.Errorf("Expected ErrRetireActiveKey, got: %v", err)
+	}
+	if err := ks.Retire("2026-01"); err != nil {
+		t.Fatalf("Retire failed: %v", err)
+	}
+	if _, err := svc.ValidateToken(oldToken); err == nil {
+		t.Error("Expected token signed by retired key to be rejected")
+	}
+}
+
+func TestKeySet_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey := newTestRSAKey(t, "rs")
+	ks, err := NewKeySet(rsaKey)
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+	svc := NewAuthService(&MockUserRepository{}, "unused", WithTokenSigner(ks))
+
+	// Sign an HS256 token using the RSA public key bytes as the HMAC secret.
+	pubDER, err := x509.MarshalPKIXPublicKey(rsaKey.verifyKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed: %v", err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "attacker"})
+	forged.Header["kid"] = "rs"
+	forgedString, err := forged.SignedString(pubDER)
+	if err != nil {
+		t.Fatalf("SignedString failed: %v", err)
+	}
+
+	if _, err := svc.ValidateToken(forgedString); err == nil {
+		t.Error("Expected HS256 token with RSA kid to be rejected")
+	}
+}
+
+func TestKeySet_LegacyTokensWithoutKid(t *testing.T) {
+	secret := []byte("legacy-secret")
+	ks, err := NewKeySet(newTestRSAKey(t, "rs"), NewHMACSigningKey(DefaultHMACKeyID, secret))
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+	svc := NewAuthService(&MockUserRepository{}, "unused", WithTokenSigner(ks))
+
+	// Tokens issued before key ids were introduced carry no kid header.
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID: "user-123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	legacyString, err := legacy.SignedString(secret)
+	if err != nil {
+		t.Fatalf("SignedString failed: %v", err)
+	}
+
+	claims, err := svc.ValidateToken(legacyString)
+	if err != nil {
+		t.Fatalf("Expected legacy token to validate, got: %v", err)
+	}
+	if claims.UserID != "user-123" {
+		t.Errorf("Expected user-123, got %s", claims.UserID)
+	}
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	ks, err := NewKeySet(newTestRSAKey(t, "b-rsa"), newTestECKey(t, "a-ec"), NewHMACSigningKey("hs", []byte("secret")))
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("Expected 2 public keys (HMAC excluded), got %d", len(jwks.Keys))
+	}
+	ec, rs := jwks.Keys[0], jwks.Keys[1]
+	if ec.Kid != "a-ec" || ec.Kty != "EC" || ec.Crv != "P-256" || ec.Alg != "ES256" || len(ec.X) != 43 || len(ec.Y) != 43 {
+		t.Errorf("Unexpected EC JWK: %+v", ec)
+	}
+	if rs.Kid != "b-rsa" || rs.Kty != "RSA" || rs.Alg != "RS256" || rs.E != "AQAB" || rs.N == "" {
+		t.Errorf("Unexpected RSA JWK: %+v", rs)
+	}
+}
+
+func TestLoadSigningKeysDir(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, "old.pem"), "PUBLIC KEY", mustMarshalPKIX(t, &rsaKey.PublicKey))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, "current.pem"), "PRIVATE KEY", ecDER)
+
+	active, others, err := LoadSigningKeysDir(dir, "current")
+	if err != nil {
+		t.Fatalf("LoadSigningKeysDir failed: %v", err)
+	}
+	if active.ID != "current" || active.Method.Alg() != "ES256" || !active.CanSign() {
+		t.Errorf("Unexpected active key: %+v", active)
+	}
+	if len(others) != 1 || others[0].ID != "old" || others[0].CanSign() {
+		t.Errorf("Expected one verify-only key 'old', got %+v", others)
+	}
+
+	if _, _, err := LoadSigningKeysDir(dir, "old"); !errors.Is(err, ErrKeyCannotSign) {
+		t.Errorf("Expected ErrKeyCannotSign for public-only active key, got: %v", err)
+	}
+	if _, _, err := LoadSigningKeysDir(dir, "missing"); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("Expected ErrUnknownKeyID, got: %v", err)
+	}
+}
+
+func mustMarshalPKIX(t *testing.T, pub interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed: %v", err)
+	}
+	return der
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
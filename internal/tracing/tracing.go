@@ -0,0 +1,94 @@
+// Package tracing sets up OpenTelemetry tracing: the tracer provider and exporter, W3C trace
+// context propagation, and a server span per inbound HTTP request. Code that wants its own span,
+// such as a service method, calls Start and ends the span when it returns.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans this module creates.
+const instrumentationName = "github.com/jmason/john_ai_project"
+
+// Exporters accepted by Options.Exporter.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Options configures Setup.
+type Options struct {
+	// Exporter is ExporterOTLP, ExporterStdout or ExporterNone. The OTLP exporter sends over
+	// HTTP and reads its endpoint and headers from the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string
+	// ServiceName names the service in exported spans. OTEL_SERVICE_NAME overrides it.
+	ServiceName string
+	// Stdout is where ExporterStdout writes; nil means os.Stdout.
+	Stdout io.Writer
+}
+
+// Setup installs the W3C trace context propagator and, unless the exporter is ExporterNone, a
+// tracer provider exporting in batches. Sampling follows OTEL_TRACES_SAMPLER, by default
+// sampling every trace unless the caller's traceparent says otherwise. The returned function
+// flushes buffered spans and stops the provider.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		w := opts.Stdout
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (want %s, %s or %s)", opts.Exporter, ExporterOTLP, ExporterStdout, ExporterNone)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	// Environment attributes (OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES) take precedence.
+	env, err := resource.New(ctx, resource.WithFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trace resource from environment: %w", err)
+	}
+	if res, err = resource.Merge(res, env); err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name, e.g. "ClientService.UpdateClient", as a child of the span in
+// ctx. The caller must end it.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
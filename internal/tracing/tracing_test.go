@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a provider that records ended spans for the rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return rec
+}
+
+func TestMiddleware_ContinuesTraceparent(t *testing.T) {
+	rec := recordSpans(t)
+
+	var child trace.SpanContext
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r, "/api/clients/{id}")
+		_, span := Start(r.Context(), "ClientService.UpdateClient")
+		child = span.SpanContext()
+		span.End()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/clients/c-123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	server := spans[1]
+	if server.Name() != "PUT /api/clients/{id}" {
+		t.Errorf("Expected span named after the route, got %q", server.Name())
+	}
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Expected a server span, got %v", server.SpanKind())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the caller's trace id, got %s", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" || !server.Parent().IsRemote() {
+		t.Errorf("Expected the remote caller span as parent, got %s", got)
+	}
+	if spans[0].Parent().SpanID() != server.SpanContext().SpanID() || child.TraceID() != server.SpanContext().TraceID() {
+		t.Error("Expected the service span to be a child of the server span")
+	}
+	for _, attr := range server.Attributes() {
+		if attr.Key == semconv.HTTPResponseStatusCodeKey && attr.Value.AsInt64() != http.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", attr.Value.AsInt64())
+		}
+		if strings.Contains(attr.Value.Emit(), "c-123") {
+			t.Errorf("Expected the path not to be recorded, got %s=%s", attr.Key, attr.Value.Emit())
+		}
+	}
+}
+
+func TestMiddleware_ServerErrorStatus(t *testing.T) {
+	rec := recordSpans(t)
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != http.MethodGet {
+		t.Errorf("Expected an unmatched request to be named after its method, got %q", spans[0].Name())
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected an error status, got %v", spans[0].Status().Code)
+	}
+	if spans[0].Parent().IsValid() {
+		t.Error("Expected a new trace without a traceparent header")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Error("Expected an unknown exporter to be rejected")
+	}
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterStdout, ServiceName: "test-service", Stdout: &out})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	_, span := Start(context.Background(), "ClientService.GetClientByID")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if !strings.Contains(out.String(), `"Name":"ClientService.GetClientByID"`) {
+		t.Errorf("Expected the span on stdout: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "test-service") {
+		t.Errorf("Expected the service name in the resource: %s", out.String())
+	}
+}
@@ -0,0 +1,154 @@
+// Package validate checks request fields and collects every failure, so an API can report all
+// invalid inputs at once as a field -> message map instead of stopping at the first.
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmason/john_ai_project/internal/apperror"
+)
+
+// DateLayout is the format of calendar dates such as date_of_birth.
+const DateLayout = "2006-01-02"
+
+var (
+	emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+	// E.164: a plus, a country code that does not start with 0, and at most 15 digits in total.
+	e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+)
+
+// Validator accumulates field errors. Only the first failure for each field is kept, so check
+// presence before format.
+type Validator struct {
+	now    time.Time
+	fields map[string]string
+}
+
+// New returns a validator that judges past and future values against now.
+func New(now time.Time) *Validator {
+	return &Validator{now: now, fields: map[string]string{}}
+}
+
+// Add records message for field unless the field already failed.
+func (v *Validator) Add(field, message string) {
+	if _, ok := v.fields[field]; !ok {
+		v.fields[field] = message
+	}
+}
+
+// Valid reports whether no field has failed yet.
+func (v *Validator) Valid() bool {
+	return len(v.fields) == 0
+}
+
+// Err returns nil when every check passed. Otherwise it returns a copy of base carrying the
+// field messages, with the failed field names appended to its message.
+func (v *Validator) Err(base *apperror.Error) error {
+	if v.Valid() {
+		return nil
+	}
+	names := make([]string, 0, len(v.fields))
+	for name := range v.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := base.WithFields(v.fields)
+	out.Message = base.Message + ": " + strings.Join(names, ", ")
+	return out
+}
+
+// Required fails when value is empty or only whitespace.
+func (v *Validator) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "is required")
+	}
+}
+
+// MaxLength fails when value has more than max characters.
+func (v *Validator) MaxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// IsEmail reports whether value looks like an email address.
+func IsEmail(value string) bool {
+	return emailPattern.MatchString(value)
+}
+
+// Email fails when a non-empty value is not an email address.
+func (v *Validator) Email(field, value string) {
+	if value != "" && !IsEmail(value) {
+		v.Add(field, "must be a valid email address")
+	}
+}
+
+// Phone fails when a non-empty value is not an E.164 number such as +14155550123. Call
+// NormalizePhone first to accept common punctuation.
+func (v *Validator) Phone(field, value string) {
+	if value != "" && !e164Pattern.MatchString(value) {
+		v.Add(field, "must be an E.164 phone number, e.g. +14155550123")
+	}
+}
+
+// PastDate fails when a non-empty value is not a real YYYY-MM-DD date before today.
+func (v *Validator) PastDate(field, value string) {
+	if value == "" {
+		return
+	}
+	d, err := time.Parse(DateLayout, value)
+	if err != nil {
+		v.Add(field, "must be a date in YYYY-MM-DD format")
+		return
+	}
+	today := time.Date(v.now.Year(), v.now.Month(), v.now.Day(), 0, 0, 0, 0, time.UTC)
+	if !d.Before(today) {
+		v.Add(field, "must be in the past")
+	}
+}
+
+// FutureTime fails when a non-empty value is not an RFC 3339 timestamp after now.
+func (v *Validator) FutureTime(field, value string) {
+	if value == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		v.Add(field, "must be an RFC 3339 timestamp, e.g. 2026-01-02T15:04:05Z")
+		return
+	}
+	if !t.After(v.now) {
+		v.Add(field, "must be in the future")
+	}
+}
+
+// OneOf fails when a non-empty value is not one of allowed.
+func (v *Validator) OneOf(field, value string, allowed []string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Add(field, "must be one of: "+strings.Join(allowed, ", "))
+}
+
+// NormalizePhone strips spaces, dots, dashes and parentheses so "+1 (415) 555-0123" validates
+// as E.164.
+func NormalizePhone(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+}
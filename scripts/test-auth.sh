@@ -105,7 +105,7 @@ CLIENTS_RESPONSE=$(curl -s "${BASE_URL}/api/clients" \
 
 echo "$CLIENTS_RESPONSE" | jq '.'
 
-if echo "$CLIENTS_RESPONSE" | jq -e '.error' > /dev/null 2>&1; then
+if echo "$CLIENTS_RESPONSE" | jq -e '.code' > /dev/null 2>&1; then
   echo "❌ Protected endpoint failed"
   exit 1
 fi
@@ -118,7 +118,7 @@ UNAUTH_RESPONSE=$(curl -s "${BASE_URL}/api/clients")
 
 echo "$UNAUTH_RESPONSE" | jq '.'
 
-if echo "$UNAUTH_RESPONSE" | jq -e '.error' > /dev/null 2>&1; then
+if echo "$UNAUTH_RESPONSE" | jq -e '.code' > /dev/null 2>&1; then
   echo "✅ Unauthorized request correctly blocked"
 else
   echo "❌ Unauthorized request was not blocked (security issue!)"